@@ -0,0 +1,332 @@
+// Package client is a Go client of the REST API of the Agora token service.
+//
+// Example usage:
+//
+//	tokens, err := client.New("https://tokens.example.com", client.WithAPIKey(os.Getenv("TOKEN_SERVICE_KEY")))
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	token, err := tokens.GenRtcToken(ctx, client.TokenRequest{Channel: "room", Uid: "42", RtcRole: "publisher"})
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultTimeout bounds each attempt of a request, unless a timeout or HTTP client is given.
+	defaultTimeout = 10 * time.Second
+
+	// defaultRetries is the number of times a failed request is retried by default.
+	defaultRetries = 2
+
+	// defaultBackoff is the wait before the first retry, doubled for each following retry.
+	defaultBackoff = 100 * time.Millisecond
+
+	// defaultMaxBackoff is the longest wait before a retry. Rate limited requests asking to wait longer are not retried.
+	defaultMaxBackoff = 5 * time.Second
+)
+
+// Client calls the token service. It is safe for concurrent use.
+type Client struct {
+	// baseURL is the URL the service is served at, without a trailing slash.
+	baseURL string
+
+	// httpClient sends the requests.
+	httpClient *http.Client
+
+	// authenticate adds the credentials to a request with the given body, if any.
+	authenticate func(req *http.Request, body []byte)
+
+	// project is the project of the token requests that do not select one.
+	project string
+
+	// retries is the number of times a failed request is retried, waiting backoff, then twice as long up to maxBackoff.
+	retries    int
+	backoff    time.Duration
+	maxBackoff time.Duration
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sends the requests with the given HTTP client, such as one with a custom transport or TLS
+// configuration. Its timeout applies to each attempt.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTimeout bounds each attempt of a request, 10 seconds by default. Use the context of the calls to bound
+// them as a whole, retries included.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		httpClient := *c.httpClient
+		httpClient.Timeout = timeout
+		c.httpClient = &httpClient
+	}
+}
+
+// WithAPIKey authenticates the requests with an API key, sent in the X-API-Key header.
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.authenticate = func(req *http.Request, _ []byte) {
+			req.Header.Set("X-API-Key", key)
+		}
+	}
+}
+
+// WithBearerToken authenticates the requests with a JWT, sent in the Authorization header.
+func WithBearerToken(token string) Option {
+	return func(c *Client) {
+		c.authenticate = func(req *http.Request, _ []byte) {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+}
+
+// WithHMACKey authenticates the requests by signing them with a shared secret, identified by its key ID.
+func WithHMACKey(keyID, secret string) Option {
+	return func(c *Client) {
+		c.authenticate = func(req *http.Request, body []byte) {
+			timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+			req.Header.Set("X-Auth-Key-Id", keyID)
+			req.Header.Set("X-Auth-Timestamp", timestamp)
+			req.Header.Set("X-Auth-Signature", signRequest(secret, req.Method, req.URL.RequestURI(), timestamp, body))
+		}
+	}
+}
+
+// WithProject signs the tokens for the registered project, unless a token request selects another one.
+func WithProject(project string) Option {
+	return func(c *Client) {
+		c.project = project
+	}
+}
+
+// WithRetries retries the requests that fail with a network error, 429 Too Many Requests, 502 Bad Gateway,
+// 503 Service Unavailable or 504 Gateway Timeout up to retries times, 2 by default. The first retry waits
+// backoff, 100ms by default, and each following retry twice as long, up to 5 seconds. Rate limited requests
+// wait as long as the service asks, and are not retried if that is longer than 5 seconds.
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+// New returns a Client of the token service served at baseURL.
+//
+// Parameters:
+//   - baseURL: string - The URL of the service, such as "https://tokens.example.com".
+//   - options: ...Option - The authentication, timeout, retries and project of the client.
+//
+// Returns:
+//   - *Client: The client.
+//   - error: An error if baseURL is not an absolute HTTP or HTTPS URL.
+//
+// Example usage:
+//
+//	tokens, err := client.New("http://localhost:8080", client.WithHMACKey("backend", secret), client.WithRetries(3, time.Second))
+func New(baseURL string, options ...Option) (*Client, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q: expected http(s)://host[:port][/path]", baseURL)
+	}
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		retries:    defaultRetries,
+		backoff:    defaultBackoff,
+		maxBackoff: defaultMaxBackoff,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c, nil
+}
+
+// GetToken requests a token, or several tokens if separate tokens of multiple services are requested.
+//
+// Parameters:
+//   - ctx: context.Context - Bounds the request, retries included.
+//   - tokenRequest: TokenRequest - The token to generate.
+//
+// Returns:
+//   - *TokenResponse: The generated token or tokens.
+//   - error: An *Error if the service rejected the request, or the error that prevented reaching it.
+func (c *Client) GetToken(ctx context.Context, tokenRequest TokenRequest) (*TokenResponse, error) {
+	var response TokenResponse
+	if err := c.post(ctx, "/getToken", c.withProject(tokenRequest), &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GenRtcToken requests an RTC token, mirroring Service.GenRtcToken. The token type is set to "rtc".
+//
+// Example usage:
+//
+//	token, err := tokens.GenRtcToken(ctx, client.TokenRequest{Channel: "room", Uid: "42", RtcRole: "publisher", ExpirationSeconds: 600})
+func (c *Client) GenRtcToken(ctx context.Context, tokenRequest TokenRequest) (string, error) {
+	tokenRequest.TokenType = "rtc"
+	return c.genToken(ctx, tokenRequest)
+}
+
+// GenRtmToken requests an RTM token, mirroring Service.GenRtmToken. The token type is set to "rtm".
+func (c *Client) GenRtmToken(ctx context.Context, tokenRequest TokenRequest) (string, error) {
+	tokenRequest.TokenType = "rtm"
+	return c.genToken(ctx, tokenRequest)
+}
+
+// GenChatToken requests a chat token, mirroring Service.GenChatToken. The token type is set to "chat": the token
+// grants user privileges when a uid is set, and app privileges otherwise.
+func (c *Client) GenChatToken(ctx context.Context, tokenRequest TokenRequest) (string, error) {
+	tokenRequest.TokenType = "chat"
+	return c.genToken(ctx, tokenRequest)
+}
+
+// genToken requests a single token.
+func (c *Client) genToken(ctx context.Context, tokenRequest TokenRequest) (string, error) {
+	response, err := c.GetToken(ctx, tokenRequest)
+	if err != nil {
+		return "", err
+	}
+	return response.Token, nil
+}
+
+// GetTokens requests a batch of tokens in a single round-trip. The results are in the order of the requests,
+// and the items that failed carry their error.
+//
+// Returns:
+//   - []TokenResult: The result of each token request.
+//   - error: An *Error if the service rejected the whole batch, or the error that prevented reaching it.
+func (c *Client) GetTokens(ctx context.Context, tokenRequests []TokenRequest) ([]TokenResult, error) {
+	requests := make([]TokenRequest, len(tokenRequests))
+	for i, tokenRequest := range tokenRequests {
+		requests[i] = c.withProject(tokenRequest)
+	}
+	var items []struct {
+		Token  string            `json:"token"`
+		Tokens map[string]string `json:"tokens"`
+		errorBody
+	}
+	if err := c.post(ctx, "/getTokens", requests, &items); err != nil {
+		return nil, err
+	}
+	results := make([]TokenResult, len(items))
+	for i, item := range items {
+		results[i] = TokenResult{Status: item.Status, Token: item.Token, Tokens: item.Tokens}
+		if item.Code != "" {
+			results[i].Err = item.errorBody.toError()
+		}
+	}
+	return results, nil
+}
+
+// withProject selects the project of the client, unless the token request selects one.
+func (c *Client) withProject(tokenRequest TokenRequest) TokenRequest {
+	if tokenRequest.Project == "" {
+		tokenRequest.Project = c.project
+	}
+	return tokenRequest
+}
+
+// post sends the payload as JSON to the path, retrying temporary failures, and decodes the response into result.
+func (c *Client) post(ctx context.Context, path string, payload, result any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		err = c.do(ctx, path, body, result)
+		if err == nil || attempt >= c.retries {
+			return err
+		}
+
+		wait := backoff
+		var apiErr *Error
+		if errors.As(err, &apiErr) {
+			if !apiErr.temporary() || apiErr.RetryAfter > c.maxBackoff {
+				return err
+			}
+			wait = max(wait, apiErr.RetryAfter)
+		} else if ctx.Err() != nil {
+			return err
+		}
+		// Jitter spreads the retries of concurrent requests
+		wait += time.Duration(rand.Int63n(int64(wait)/4 + 1))
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return err
+		}
+		backoff = min(2*backoff, c.maxBackoff)
+	}
+}
+
+// do sends a single attempt of a request.
+func (c *Client) do(ctx context.Context, path string, body []byte, result any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if c.authenticate != nil {
+		c.authenticate(req, body)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var response errorBody
+		if json.Unmarshal(content, &response) != nil || response.Code == "" {
+			// Not an error of the service, such as a proxy error
+			return &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(http.StatusText(resp.StatusCode) + " " + string(content))}
+		}
+		response.Status = resp.StatusCode
+		return response.toError()
+	}
+	if err := json.Unmarshal(content, result); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
+
+// signRequest returns the HMAC signature of a request, as verified by the HMACAuthenticator of the service.
+func signRequest(secret, method, requestURI, timestamp string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(method + "\n" + requestURI + "\n" + timestamp + "\n" + hex.EncodeToString(bodyHash[:])))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,254 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AgoraIO-Community/agora-token-service/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testAppID       = "6ce46dd303d54056a52f9a34c13c547e"
+	testCertificate = "77be7e16f7482cef9fe796205b85831e"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// newTestServer serves a token service configured with the environment variables.
+func newTestServer(t *testing.T, env map[string]string) *service.Service {
+	t.Setenv("APP_ID", testAppID)
+	t.Setenv("APP_CERTIFICATE", testCertificate)
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+	return service.NewService()
+}
+
+// newTestClient returns a client of an httptest server of the handler.
+func newTestClient(t *testing.T, handler http.Handler, options ...Option) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	tokens, err := New(server.URL, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokens
+}
+
+func TestGenTokens(t *testing.T) {
+	s := newTestServer(t, nil)
+	tokens := newTestClient(t, s.Server.Handler)
+	ctx := context.Background()
+
+	rtcToken, err := tokens.GenRtcToken(ctx, TokenRequest{Channel: "room", Uid: "42", RtcRole: "publisher", ExpirationSeconds: 600})
+	if assert.NoError(t, err) {
+		info, err := s.InspectToken(rtcToken)
+		if assert.NoError(t, err) && assert.Len(t, info.Services, 1) {
+			assert.True(t, info.SignatureValid)
+			assert.Equal(t, uint32(600), info.Expire)
+			assert.Equal(t, "rtc", info.Services[0].Type)
+			assert.Equal(t, "room", info.Services[0].Channel)
+		}
+	}
+
+	rtmToken, err := tokens.GenRtmToken(ctx, TokenRequest{Uid: "user"})
+	if assert.NoError(t, err) {
+		info, err := s.InspectToken(rtmToken)
+		if assert.NoError(t, err) && assert.Len(t, info.Services, 1) {
+			assert.Equal(t, "rtm", info.Services[0].Type)
+		}
+	}
+
+	chatToken, err := tokens.GenChatToken(ctx, TokenRequest{})
+	if assert.NoError(t, err) {
+		info, err := s.InspectToken(chatToken)
+		if assert.NoError(t, err) && assert.Len(t, info.Services, 1) {
+			assert.Equal(t, "chat", info.Services[0].Type)
+		}
+	}
+
+	response, err := tokens.GetToken(ctx, TokenRequest{Services: []string{"rtc", "rtm"}, Channel: "room", Uid: "42", SeparateTokens: true})
+	if assert.NoError(t, err) {
+		assert.Len(t, response.Tokens, 2)
+	}
+
+	results, err := tokens.GetTokens(ctx, []TokenRequest{
+		{TokenType: "rtm", Uid: "user"},
+		{TokenType: "rtc", Uid: "42"},
+	})
+	if assert.NoError(t, err) && assert.Len(t, results, 2) {
+		assert.Equal(t, http.StatusOK, results[0].Status)
+		assert.NotEmpty(t, results[0].Token)
+		assert.Nil(t, results[0].Err)
+		assert.Equal(t, http.StatusBadRequest, results[1].Status)
+		if assert.NotNil(t, results[1].Err) {
+			assert.Equal(t, CodeMissingChannel, results[1].Err.Code)
+			assert.Equal(t, "channel", results[1].Err.Field)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tokens := newTestClient(t, newTestServer(t, nil).Server.Handler)
+
+	_, err := tokens.GenRtcToken(context.Background(), TokenRequest{Channel: "room", Uid: "4294967296"})
+	var apiErr *Error
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+		assert.Equal(t, CodeInvalidUid, apiErr.Code)
+		assert.Equal(t, "uid", apiErr.Field)
+		assert.NotEmpty(t, apiErr.Message)
+	}
+	assert.ErrorIs(t, err, &Error{Code: CodeInvalidUid})
+	assert.False(t, errors.Is(err, &Error{Code: CodeInvalidChannel}))
+
+	_, err = tokens.GenRtmToken(context.Background(), TokenRequest{Uid: "user", Project: "unknown"})
+	assert.ErrorIs(t, err, &Error{Code: CodeUnknownProject})
+}
+
+func TestAuthentication(t *testing.T) {
+	handler := newTestServer(t, map[string]string{
+		"AUTH_API_KEYS":  "backend:backend-key",
+		"AUTH_HMAC_KEYS": "signer:signer-secret",
+	}).Server.Handler
+	tokens := TokenRequest{Uid: "user"}
+
+	_, err := newTestClient(t, handler).GenRtmToken(context.Background(), tokens)
+	assert.ErrorIs(t, err, &Error{Code: CodeUnauthenticated})
+
+	_, err = newTestClient(t, handler, WithAPIKey("backend-key")).GenRtmToken(context.Background(), tokens)
+	assert.NoError(t, err)
+
+	_, err = newTestClient(t, handler, WithHMACKey("signer", "signer-secret")).GenRtmToken(context.Background(), tokens)
+	assert.NoError(t, err)
+
+	_, err = newTestClient(t, handler, WithHMACKey("signer", "other-secret")).GenRtmToken(context.Background(), tokens)
+	assert.ErrorIs(t, err, &Error{Code: CodeUnauthenticated})
+
+	// The signature matches the one of the service
+	assert.Equal(t, service.SignRequest("secret", "POST", "/getToken", "1700000000", []byte("{}")),
+		signRequest("secret", "POST", "/getToken", "1700000000", []byte("{}")))
+}
+
+func TestRetries(t *testing.T) {
+	handler := newTestServer(t, nil).Server.Handler
+	var attempts atomic.Int32
+	failing := func(failures int32) http.Handler {
+		attempts.Store(0)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if attempts.Add(1) <= failures {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			handler.ServeHTTP(w, r)
+		})
+	}
+
+	tokens := newTestClient(t, failing(2), WithRetries(2, time.Millisecond))
+	_, err := tokens.GenRtmToken(context.Background(), TokenRequest{Uid: "user"})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), attempts.Load())
+
+	tokens = newTestClient(t, failing(3), WithRetries(2, time.Millisecond))
+	_, err = tokens.GenRtmToken(context.Background(), TokenRequest{Uid: "user"})
+	var apiErr *Error
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+		assert.Empty(t, apiErr.Code)
+	}
+	assert.Equal(t, int32(3), attempts.Load())
+
+	// Errors of the request are not retried
+	tokens = newTestClient(t, failing(0), WithRetries(2, time.Millisecond))
+	_, err = tokens.GenRtmToken(context.Background(), TokenRequest{})
+	assert.ErrorIs(t, err, &Error{Code: CodeMissingUid})
+	assert.Equal(t, int32(1), attempts.Load())
+}
+
+func TestRateLimited(t *testing.T) {
+	tokens := newTestClient(t, newTestServer(t, map[string]string{"RATE_LIMIT_UID": "1/m"}).Server.Handler,
+		WithRetries(2, time.Millisecond))
+
+	_, err := tokens.GenRtmToken(context.Background(), TokenRequest{Uid: "user"})
+	assert.NoError(t, err)
+
+	// The service asks to wait longer than the longest backoff, so the request is not retried
+	start := time.Now()
+	_, err = tokens.GenRtmToken(context.Background(), TokenRequest{Uid: "user"})
+	var apiErr *Error
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, CodeRateLimited, apiErr.Code)
+		assert.Equal(t, "uid", apiErr.Scope)
+		assert.Greater(t, apiErr.RetryAfter, defaultMaxBackoff)
+	}
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestNew(t *testing.T) {
+	for _, baseURL := range []string{"", "localhost:8080", "ftp://tokens.example.com", "http://"} {
+		_, err := New(baseURL)
+		assert.Error(t, err, baseURL)
+	}
+
+	tokens, err := New("https://tokens.example.com/", WithTimeout(time.Second), WithProject("acme"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "https://tokens.example.com", tokens.baseURL)
+		assert.Equal(t, time.Second, tokens.httpClient.Timeout)
+		assert.Equal(t, "acme", tokens.withProject(TokenRequest{}).Project)
+		assert.Equal(t, "other", tokens.withProject(TokenRequest{Project: "other"}).Project)
+	}
+}
+
+// TestTokenRequestFields checks that TokenRequest has the JSON fields of the service TokenRequest.
+func TestTokenRequestFields(t *testing.T) {
+	jsonTags := func(v any) map[string]string {
+		tags := map[string]string{}
+		typ := reflect.TypeOf(v)
+		for i := 0; i < typ.NumField(); i++ {
+			tags[typ.Field(i).Name] = typ.Field(i).Tag.Get("json")
+		}
+		return tags
+	}
+	assert.Equal(t, jsonTags(service.TokenRequest{}), jsonTags(TokenRequest{}))
+	assert.Equal(t, jsonTags(service.TokenResponse{}), jsonTags(TokenResponse{}))
+}
+
+// TestErrorCodes checks that the ErrorCode constants are the ones of the service.
+func TestErrorCodes(t *testing.T) {
+	errorCodes := func(path string) map[string]string {
+		file, err := parser.ParseFile(token.NewFileSet(), path, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		codes := map[string]string{}
+		for _, decl := range file.Decls {
+			if decl, ok := decl.(*ast.GenDecl); ok && decl.Tok == token.CONST {
+				for _, spec := range decl.Specs {
+					spec := spec.(*ast.ValueSpec)
+					if typ, ok := spec.Type.(*ast.Ident); ok && typ.Name == "ErrorCode" {
+						codes[spec.Names[0].Name] = spec.Values[0].(*ast.BasicLit).Value
+					}
+				}
+			}
+		}
+		return codes
+	}
+	serviceCodes := errorCodes("../service/errors.go")
+	assert.NotEmpty(t, serviceCodes)
+	assert.Equal(t, serviceCodes, errorCodes("errors.go"))
+}
@@ -0,0 +1,111 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrorCode is the stable, machine-readable identifier of why the service rejected a request.
+type ErrorCode string
+
+// The error codes returned by the service. See the Errors section of the README for the HTTP status of each.
+const (
+	CodeInvalidJSON          ErrorCode = "INVALID_JSON"
+	CodeInvalidRequest       ErrorCode = "INVALID_REQUEST"
+	CodeUnsupportedTokenType ErrorCode = "UNSUPPORTED_TOKEN_TYPE"
+	CodeMissingServices      ErrorCode = "MISSING_SERVICES"
+	CodeUnsupportedService   ErrorCode = "UNSUPPORTED_SERVICE"
+	CodeDuplicateService     ErrorCode = "DUPLICATE_SERVICE"
+	CodeConflictingFields    ErrorCode = "CONFLICTING_FIELDS"
+	CodeMissingChannel       ErrorCode = "MISSING_CHANNEL"
+	CodeInvalidChannel       ErrorCode = "INVALID_CHANNEL"
+	CodeMissingUid           ErrorCode = "MISSING_UID"
+	CodeInvalidUid           ErrorCode = "INVALID_UID"
+	CodeInvalidRole          ErrorCode = "INVALID_ROLE"
+	CodeInvalidExpiry        ErrorCode = "INVALID_EXPIRY"
+	CodeMissingProject       ErrorCode = "MISSING_PROJECT"
+	CodeMissingToken         ErrorCode = "MISSING_TOKEN"
+	CodeInvalidToken         ErrorCode = "INVALID_TOKEN"
+	CodeEmptyBatch           ErrorCode = "EMPTY_BATCH"
+	CodeBatchTooLarge        ErrorCode = "BATCH_TOO_LARGE"
+	CodeInvalidCertificate   ErrorCode = "INVALID_CERTIFICATE"
+	CodeCertificateActive    ErrorCode = "CERTIFICATE_ACTIVE"
+	CodeInvalidQuery         ErrorCode = "INVALID_QUERY"
+	CodeExpiryTooShort       ErrorCode = "EXPIRY_TOO_SHORT"
+	CodeExpiryTooLong        ErrorCode = "EXPIRY_TOO_LONG"
+	CodeUnauthenticated      ErrorCode = "UNAUTHENTICATED"
+	CodeAdminRequired        ErrorCode = "ADMIN_REQUIRED"
+	CodeOriginNotAllowed     ErrorCode = "ORIGIN_NOT_ALLOWED"
+	CodePolicyViolation      ErrorCode = "POLICY_VIOLATION"
+	CodeRateLimited          ErrorCode = "RATE_LIMITED"
+	CodeUnknownProject       ErrorCode = "UNKNOWN_PROJECT"
+	CodeNotFound             ErrorCode = "NOT_FOUND"
+	CodeAuditUnavailable     ErrorCode = "AUDIT_UNAVAILABLE"
+	CodeBodyTooLarge         ErrorCode = "BODY_TOO_LARGE"
+	CodeInternal             ErrorCode = "INTERNAL_ERROR"
+)
+
+// Error is an error response of the service.
+//
+// Example usage:
+//
+//	var apiErr *client.Error
+//	if errors.As(err, &apiErr) && apiErr.Code == client.CodeRateLimited {
+//	    time.Sleep(apiErr.RetryAfter)
+//	}
+type Error struct {
+	StatusCode int           // The HTTP status code of the response
+	Code       ErrorCode     // The machine-readable error code, empty if the response was not sent by the service
+	Message    string        // The human-readable error message
+	Field      string        // The request field at fault, if any
+	Rule       string        // The policy rule that denied the request, if any
+	Scope      string        // The exceeded rate limit: "client", "uid" or "channel", if any
+	RetryAfter time.Duration // The time until the request may be retried, if rate limited
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("token service: %d %s", e.StatusCode, e.Message)
+	}
+	if e.Field != "" {
+		return fmt.Sprintf("token service: %s (%s): %s", e.Code, e.Field, e.Message)
+	}
+	return fmt.Sprintf("token service: %s: %s", e.Code, e.Message)
+}
+
+// Is reports whether the target is an *Error with the same code, so errors.Is(err, &client.Error{Code: code})
+// matches the errors of that code.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code != "" && t.Code == e.Code
+}
+
+// temporary reports whether the request may succeed if retried as is.
+func (e *Error) temporary() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// errorBody is the JSON body of the error responses of the service.
+type errorBody struct {
+	Status     int       `json:"status"`
+	Code       ErrorCode `json:"code"`
+	Error      string    `json:"error"`
+	Field      string    `json:"field,omitempty"`
+	Rule       string    `json:"rule,omitempty"`
+	Scope      string    `json:"scope,omitempty"`
+	RetryAfter int       `json:"retryAfter,omitempty"`
+}
+
+// toError returns the Error of the body.
+func (b errorBody) toError() *Error {
+	return &Error{
+		StatusCode: b.Status, Code: b.Code, Message: b.Error, Field: b.Field, Rule: b.Rule, Scope: b.Scope,
+		RetryAfter: time.Duration(b.RetryAfter) * time.Second,
+	}
+}
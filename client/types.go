@@ -0,0 +1,32 @@
+package client
+
+// TokenRequest is the JSON payload of a token request, as accepted by POST /getToken.
+// Only the fields of the requested token type are required, the others can be left empty.
+type TokenRequest struct {
+	TokenType           string   `json:"tokenType"`                     // The token type: "rtc", "rtm", or "chat"
+	Channel             string   `json:"channel,omitempty"`             // The channel name (used for RTC and RTM tokens)
+	RtcRole             string   `json:"role,omitempty"`                // The role of the user for RTC tokens (publisher or subscriber)
+	Uid                 string   `json:"uid,omitempty"`                 // The user ID or account (used for RTC, RTM, and some chat tokens)
+	ExpirationSeconds   int      `json:"expire,omitempty"`              // The token expiration time in seconds (used for all token types)
+	JoinChannelExpire   int      `json:"joinChannelExpire,omitempty"`   // The join channel privilege expiration in seconds (RTC only)
+	PubAudioExpire      int      `json:"pubAudioExpire,omitempty"`      // The publish audio privilege expiration in seconds (RTC publisher only)
+	PubVideoExpire      int      `json:"pubVideoExpire,omitempty"`      // The publish video privilege expiration in seconds (RTC publisher only)
+	PubDataStreamExpire int      `json:"pubDataStreamExpire,omitempty"` // The publish data stream privilege expiration in seconds (RTC publisher only)
+	Project             string   `json:"project,omitempty"`             // The registered project to sign the token for (default project if empty)
+	Services            []string `json:"services,omitempty"`            // The services to include in a single token: "rtc", "rtm" and/or "chat"
+	SeparateTokens      bool     `json:"separateTokens,omitempty"`      // Whether to return one token per service instead of a single token
+}
+
+// TokenResponse is the JSON body of a successful POST /getToken response.
+type TokenResponse struct {
+	Token  string            `json:"token,omitempty"`  // The generated token
+	Tokens map[string]string `json:"tokens,omitempty"` // The generated tokens, indexed by service
+}
+
+// TokenResult is the outcome of a single token request of a batch.
+type TokenResult struct {
+	Status int               `json:"status"`           // The status code of the item: 200 on success
+	Token  string            `json:"token,omitempty"`  // The generated token, if successful
+	Tokens map[string]string `json:"tokens,omitempty"` // The generated tokens, indexed by service, if separate tokens were requested
+	Err    *Error            `json:"-"`                // The error, if the token could not be generated
+}
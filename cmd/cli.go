@@ -0,0 +1,312 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/AgoraIO-Community/agora-token-service/service"
+	"github.com/gin-gonic/gin"
+	"github.com/joho/godotenv"
+)
+
+// usage is printed by the help command and when no command matches.
+const usage = `Usage: agora-token-service [command] [flags]
+
+Commands:
+  serve     Run the token service (default)
+  rtc       Generate an RTC token
+  rtm       Generate an RTM token
+  chat      Generate a chat token, for a user with -uid or for the app otherwise
+  inspect   Decode and verify a token
+  version   Print the build information
+
+The tokens are signed with the credentials of APP_ID and APP_CERTIFICATE, the .env file, or the
+configuration file given with -config or CONFIG_FILE. Run "agora-token-service <command> -h" for
+the flags of a command.
+`
+
+// Exit codes of the commands.
+const (
+	exitOK    = 0
+	exitError = 1 // The token could not be generated or inspected
+	exitUsage = 2 // The command or its flags are invalid
+)
+
+// command runs a subcommand with the service configured by its flags, writing the results to stdout.
+type command func(s *service.Service, out *output, args []string) error
+
+// runCLI runs the subcommand of args, such as "rtc -channel room -uid 42", and returns the exit code.
+//
+// Parameters:
+//   - args: []string - The command line arguments, without the program name.
+//   - stdout, stderr: io.Writer - Receive the results, and the errors and usage messages.
+//
+// Returns:
+//   - int: 0 on success, 1 if the token could not be generated or inspected, 2 on usage errors.
+//
+// Example usage:
+//
+//	os.Exit(runCLI([]string{"rtc", "-channel", "room", "-uid", "42", "-o", "json"}, os.Stdout, os.Stderr))
+func runCLI(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configFile := flags.String("config", os.Getenv("CONFIG_FILE"), "the configuration file with the credentials")
+	format := flags.String("o", "text", "the output format: text or json")
+
+	var run command
+	switch args[0] {
+	case "rtc":
+		run = rtcCommand(flags)
+	case "rtm":
+		run = rtmCommand(flags)
+	case "chat":
+		run = chatCommand(flags)
+	case "inspect":
+		flags.Usage = func() {
+			fmt.Fprintln(stderr, "Usage: agora-token-service inspect [flags] [token]\n\nThe token is read from stdin when omitted.")
+			flags.PrintDefaults()
+		}
+		run = inspectCommand(os.Stdin)
+	case "version":
+		run = versionCommand
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", args[0], usage)
+		return exitUsage
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	out := &output{stdout: stdout, stderr: stderr, json: *format == "json"}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(stderr, "invalid output format %q, expected text or json\n", *format)
+		return exitUsage
+	}
+
+	var s *service.Service
+	if args[0] != "version" {
+		var err error
+		if s, err = loadService(*configFile); err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitUsage
+		}
+	}
+	if err := run(s, out, flags.Args()); err != nil {
+		out.error(err)
+		return exitError
+	}
+	return exitOK
+}
+
+// loadService builds the service of the configuration file, if any, the .env file and the environment.
+// The commands only sign and inspect tokens, so the audit sinks, the rate limit store and the listeners of the
+// server are left out, rather than opened and never closed.
+func loadService(configFile string) (*service.Service, error) {
+	// The .env file is optional, as for the server
+	_ = godotenv.Load()
+	// Keep the route listing of gin out of the output of the commands
+	gin.SetMode(gin.ReleaseMode)
+	config, err := service.LoadConfig(configFile)
+	if err != nil {
+		return nil, err
+	}
+	config.Audit = service.AuditConfig{}
+	config.RateLimits = service.RateLimitConfig{}
+	config.TLS = service.TLSConfig{}
+	config.GRPCPort = ""
+	return service.NewServiceFromConfig(config)
+}
+
+// expireFlags registers the flags of the token expiration, and of the RTC privilege expirations with privileges set.
+func expireFlags(flags *flag.FlagSet, tokenRequest *service.TokenRequest, privileges bool) {
+	flags.IntVar(&tokenRequest.ExpirationSeconds, "expire", 0, "the token expiration in seconds (default: the configured default)")
+	if privileges {
+		flags.IntVar(&tokenRequest.JoinChannelExpire, "join-expire", 0, "the join channel privilege expiration in seconds")
+		flags.IntVar(&tokenRequest.PubAudioExpire, "pub-audio-expire", 0, "the publish audio privilege expiration in seconds")
+		flags.IntVar(&tokenRequest.PubVideoExpire, "pub-video-expire", 0, "the publish video privilege expiration in seconds")
+		flags.IntVar(&tokenRequest.PubDataStreamExpire, "pub-data-stream-expire", 0, "the publish data stream privilege expiration in seconds")
+	}
+	flags.StringVar(&tokenRequest.Project, "project", "", "the registered project to sign the token for (default project if empty)")
+}
+
+// rtcCommand generates an RTC token with service.GenRtcToken.
+func rtcCommand(flags *flag.FlagSet) command {
+	tokenRequest := &service.TokenRequest{TokenType: "rtc"}
+	flags.StringVar(&tokenRequest.Channel, "channel", "", "the channel name (required)")
+	flags.StringVar(&tokenRequest.Uid, "uid", "", "the user ID, or user account if not numeric (required, 0 for any user)")
+	flags.StringVar(&tokenRequest.RtcRole, "role", "subscriber", "the role of the user: publisher or subscriber")
+	expireFlags(flags, tokenRequest, true)
+	return func(s *service.Service, out *output, _ []string) error {
+		token, err := s.GenRtcToken(*tokenRequest)
+		if err != nil {
+			return err
+		}
+		return out.token(token)
+	}
+}
+
+// rtmCommand generates an RTM token with service.GenRtmToken.
+func rtmCommand(flags *flag.FlagSet) command {
+	tokenRequest := &service.TokenRequest{TokenType: "rtm"}
+	flags.StringVar(&tokenRequest.Uid, "uid", "", "the user ID (required)")
+	flags.StringVar(&tokenRequest.Channel, "channel", "", "the stream channel name, \"*\" for any channel")
+	expireFlags(flags, tokenRequest, false)
+	return func(s *service.Service, out *output, _ []string) error {
+		token, err := s.GenRtmToken(*tokenRequest)
+		if err != nil {
+			return err
+		}
+		return out.token(token)
+	}
+}
+
+// chatCommand generates a chat token with service.GenChatToken.
+func chatCommand(flags *flag.FlagSet) command {
+	tokenRequest := &service.TokenRequest{TokenType: "chat"}
+	flags.StringVar(&tokenRequest.Uid, "uid", "", "the chat user ID, for a user token (default app token)")
+	expireFlags(flags, tokenRequest, false)
+	return func(s *service.Service, out *output, _ []string) error {
+		token, err := s.GenChatToken(*tokenRequest)
+		if err != nil {
+			return err
+		}
+		return out.token(token)
+	}
+}
+
+// inspectCommand decodes and verifies the token given as argument, or read from stdin.
+func inspectCommand(stdin io.Reader) command {
+	return func(s *service.Service, out *output, args []string) error {
+		var token string
+		switch len(args) {
+		case 0:
+			content, err := io.ReadAll(stdin)
+			if err != nil {
+				return err
+			}
+			token = strings.TrimSpace(string(content))
+		case 1:
+			token = args[0]
+		default:
+			return errors.New("expected a single token")
+		}
+		info, err := s.InspectToken(token)
+		if err != nil {
+			// Reported as by POST /inspectToken
+			return &service.APIError{Status: http.StatusBadRequest, Code: service.CodeInvalidToken, Field: "token", Message: err.Error()}
+		}
+		return out.tokenInfo(info)
+	}
+}
+
+// versionCommand prints the build information.
+func versionCommand(_ *service.Service, out *output, _ []string) error {
+	info := service.GetBuildInfo()
+	if out.json {
+		return out.encode(info)
+	}
+	version := info.Version
+	if info.Commit != "" {
+		version += " (" + info.Commit + ")"
+	}
+	_, err := fmt.Fprintln(out.stdout, version, info.GoVersion)
+	return err
+}
+
+// output writes the results of the commands as plain text or JSON.
+type output struct {
+	stdout, stderr io.Writer
+	json           bool
+}
+
+// encode writes the value as indented JSON.
+func (o *output) encode(value any) error {
+	encoder := json.NewEncoder(o.stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// token writes a generated token: alone on its line, or as the body of POST /getToken.
+func (o *output) token(token string) error {
+	if o.json {
+		return o.encode(service.TokenResponse{Token: token})
+	}
+	_, err := fmt.Fprintln(o.stdout, token)
+	return err
+}
+
+// tokenInfo writes the content of an inspected token.
+func (o *output) tokenInfo(info *service.TokenInfo) error {
+	if o.json {
+		return o.encode(info)
+	}
+	formatTime := func(unix uint32) string {
+		return time.Unix(int64(unix), 0).UTC().Format(time.RFC3339)
+	}
+	signature := "invalid"
+	if info.SignatureValid {
+		signature = "valid, " + info.SignedWith + " certificate"
+	}
+	expires := formatTime(info.ExpiresAt)
+	if info.Expired {
+		expires += " (expired)"
+	}
+
+	w := tabwriter.NewWriter(o.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "App ID:\t%s\n", info.AppID)
+	if info.Project != "" {
+		fmt.Fprintf(w, "Project:\t%s\n", info.Project)
+	}
+	fmt.Fprintf(w, "Signature:\t%s\n", signature)
+	fmt.Fprintf(w, "Issued:\t%s\n", formatTime(info.IssueTs))
+	fmt.Fprintf(w, "Expires:\t%s\n", expires)
+	for _, serviceInfo := range info.Services {
+		fmt.Fprintf(w, "Service:\t%s", serviceInfo.Type)
+		if serviceInfo.Channel != "" {
+			fmt.Fprintf(w, " channel=%s", serviceInfo.Channel)
+		}
+		if serviceInfo.Uid != "" {
+			fmt.Fprintf(w, " uid=%s", serviceInfo.Uid)
+		}
+		fmt.Fprintln(w)
+		for _, privilege := range serviceInfo.Privileges {
+			fmt.Fprintf(w, "  %s\texpires %s\n", privilege.Name, formatTime(privilege.ExpiresAt))
+		}
+	}
+	return w.Flush()
+}
+
+// error writes the error to stderr: its message, or the error response of the REST API as JSON.
+func (o *output) error(err error) {
+	response := service.NewErrorResponse(err)
+	if o.json {
+		json.NewEncoder(o.stderr).Encode(response)
+		return
+	}
+	if response.Code == service.CodeInternal {
+		fmt.Fprintf(o.stderr, "error: %s\n", err)
+		return
+	}
+	if response.Field != "" {
+		fmt.Fprintf(o.stderr, "error: %s (%s): %s\n", response.Code, response.Field, response.Error)
+		return
+	}
+	fmt.Fprintf(o.stderr, "error: %s: %s\n", response.Code, response.Error)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AgoraIO-Community/agora-token-service/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// runTestCLI runs the command with test credentials, returning its exit code, stdout and stderr.
+func runTestCLI(t *testing.T, args ...string) (int, string, string) {
+	t.Setenv("APP_ID", "6ce46dd303d54056a52f9a34c13c547e")
+	t.Setenv("APP_CERTIFICATE", "77be7e16f7482cef9fe796205b85831e")
+	var stdout, stderr bytes.Buffer
+	code := runCLI(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestGenerateAndInspect(t *testing.T) {
+	code, stdout, stderr := runTestCLI(t, "rtc", "-channel", "room", "-uid", "42", "-role", "publisher", "-expire", "600")
+	if !assert.Equal(t, exitOK, code, stderr) {
+		return
+	}
+	token := strings.TrimSpace(stdout)
+	assert.NotContains(t, token, "\n")
+
+	code, stdout, stderr = runTestCLI(t, "inspect", "-o", "json", token)
+	if assert.Equal(t, exitOK, code, stderr) {
+		var info service.TokenInfo
+		if assert.NoError(t, json.Unmarshal([]byte(stdout), &info)) && assert.Len(t, info.Services, 1) {
+			assert.True(t, info.SignatureValid)
+			assert.Equal(t, uint32(600), info.Expire)
+			assert.Equal(t, "room", info.Services[0].Channel)
+			assert.Equal(t, "42", info.Services[0].Uid)
+			assert.Len(t, info.Services[0].Privileges, 4)
+		}
+	}
+
+	code, stdout, _ = runTestCLI(t, "inspect", token)
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "valid, active certificate")
+	assert.Contains(t, stdout, "publishAudioStream")
+
+	for _, args := range [][]string{{"rtm", "-uid", "user", "-o", "json"}, {"chat", "-o", "json"}, {"chat", "-uid", "user", "-o", "json"}} {
+		code, stdout, stderr = runTestCLI(t, args...)
+		if assert.Equal(t, exitOK, code, stderr) {
+			var response service.TokenResponse
+			assert.NoError(t, json.Unmarshal([]byte(stdout), &response))
+			assert.NotEmpty(t, response.Token, args)
+		}
+	}
+}
+
+func TestServerResources(t *testing.T) {
+	// The audit sinks and rate limit store of the server are not opened
+	database := filepath.Join(t.TempDir(), "audit.db")
+	t.Setenv("AUDIT_SINKS", "sqlite")
+	t.Setenv("AUDIT_DATABASE", database)
+	t.Setenv("RATE_LIMIT_UID", "1/h")
+	t.Setenv("RATE_LIMIT_REDIS_URL", "redis://127.0.0.1:1")
+	for i := 0; i < 2; i++ {
+		code, _, stderr := runTestCLI(t, "rtm", "-uid", "user")
+		assert.Equal(t, exitOK, code, stderr)
+	}
+	assert.NoFileExists(t, database)
+
+	// The expiration defaults to the configured one
+	t.Setenv("EXPIRE_MIN", "7200")
+	code, stdout, stderr := runTestCLI(t, "rtm", "-uid", "user")
+	if assert.Equal(t, exitOK, code, stderr) {
+		_, stdout, _ = runTestCLI(t, "inspect", "-o", "json", strings.TrimSpace(stdout))
+		var info service.TokenInfo
+		if assert.NoError(t, json.Unmarshal([]byte(stdout), &info)) {
+			assert.Equal(t, uint32(7200), info.Expire)
+		}
+	}
+}
+
+func TestCLIErrors(t *testing.T) {
+	code, stdout, stderr := runTestCLI(t, "rtc", "-channel", "room", "-uid", "4294967296")
+	assert.Equal(t, exitError, code)
+	assert.Empty(t, stdout)
+	assert.Contains(t, stderr, "INVALID_UID (uid)")
+
+	code, _, stderr = runTestCLI(t, "rtm", "-o", "json")
+	assert.Equal(t, exitError, code)
+	var response service.ErrorResponse
+	if assert.NoError(t, json.Unmarshal([]byte(stderr), &response)) {
+		assert.Equal(t, service.CodeMissingUid, response.Code)
+	}
+
+	code, _, stderr = runTestCLI(t, "inspect", "not-a-token")
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stderr, "INVALID_TOKEN")
+
+	for _, args := range [][]string{{}, {"unknown"}, {"rtc", "-unknown"}, {"rtm", "-uid", "user", "-o", "xml"}} {
+		code, _, _ = runTestCLI(t, args...)
+		assert.Equal(t, exitUsage, code, args)
+	}
+
+	t.Setenv("APP_ID", "")
+	var stderrBuffer bytes.Buffer
+	assert.Equal(t, exitUsage, runCLI([]string{"rtm", "-uid", "user"}, &bytes.Buffer{}, &stderrBuffer))
+}
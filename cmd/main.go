@@ -1,12 +1,20 @@
 package main
 
 import (
+	"context"
+	"os"
+
 	"github.com/AgoraIO-Community/agora-token-service/service"
 )
 
 func main() {
+	// Without a command, or with "serve", run the token service; otherwise run a command of the CLI
+	if len(os.Args) > 1 && os.Args[1] != "serve" {
+		os.Exit(runCLI(os.Args[1:], os.Stdout, os.Stderr))
+	}
 	s := service.NewService()
-	// Stop is called on another thread, but waits for an interrupt
-	go s.Stop()
-	s.Start()
+	// Run serves until SIGINT or SIGTERM, then drains the in-flight requests before returning
+	if err := s.Run(context.Background()); err != nil {
+		os.Exit(1)
+	}
 }
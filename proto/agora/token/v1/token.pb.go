@@ -0,0 +1,1092 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        (unknown)
+// source: agora/token/v1/token.proto
+
+package tokenv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// RtcRole is the role of the user in an RTC channel.
+type RtcRole int32
+
+const (
+	// RTC_ROLE_UNSPECIFIED defaults to subscriber.
+	RtcRole_RTC_ROLE_UNSPECIFIED RtcRole = 0
+	RtcRole_RTC_ROLE_PUBLISHER   RtcRole = 1
+	RtcRole_RTC_ROLE_SUBSCRIBER  RtcRole = 2
+)
+
+// Enum value maps for RtcRole.
+var (
+	RtcRole_name = map[int32]string{
+		0: "RTC_ROLE_UNSPECIFIED",
+		1: "RTC_ROLE_PUBLISHER",
+		2: "RTC_ROLE_SUBSCRIBER",
+	}
+	RtcRole_value = map[string]int32{
+		"RTC_ROLE_UNSPECIFIED": 0,
+		"RTC_ROLE_PUBLISHER":   1,
+		"RTC_ROLE_SUBSCRIBER":  2,
+	}
+)
+
+func (x RtcRole) Enum() *RtcRole {
+	p := new(RtcRole)
+	*p = x
+	return p
+}
+
+func (x RtcRole) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (RtcRole) Descriptor() protoreflect.EnumDescriptor {
+	return file_agora_token_v1_token_proto_enumTypes[0].Descriptor()
+}
+
+func (RtcRole) Type() protoreflect.EnumType {
+	return &file_agora_token_v1_token_proto_enumTypes[0]
+}
+
+func (x RtcRole) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use RtcRole.Descriptor instead.
+func (RtcRole) EnumDescriptor() ([]byte, []int) {
+	return file_agora_token_v1_token_proto_rawDescGZIP(), []int{0}
+}
+
+type GenerateRtcTokenRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The registered project to sign the token for, the default project if empty.
+	Project string `protobuf:"bytes,1,opt,name=project,proto3" json:"project,omitempty"`
+	// The channel name.
+	Channel string `protobuf:"bytes,2,opt,name=channel,proto3" json:"channel,omitempty"`
+	// The numeric user ID, or the user account.
+	Uid  string  `protobuf:"bytes,3,opt,name=uid,proto3" json:"uid,omitempty"`
+	Role RtcRole `protobuf:"varint,4,opt,name=role,proto3,enum=agora.token.v1.RtcRole" json:"role,omitempty"`
+	// The token expiration in seconds, 3600 if zero.
+	Expire uint32 `protobuf:"varint,5,opt,name=expire,proto3" json:"expire,omitempty"`
+	// The privilege expirations in seconds, falling back to expire when zero. Publish privileges are only
+	// granted to publishers.
+	JoinChannelExpire   uint32 `protobuf:"varint,6,opt,name=join_channel_expire,json=joinChannelExpire,proto3" json:"join_channel_expire,omitempty"`
+	PubAudioExpire      uint32 `protobuf:"varint,7,opt,name=pub_audio_expire,json=pubAudioExpire,proto3" json:"pub_audio_expire,omitempty"`
+	PubVideoExpire      uint32 `protobuf:"varint,8,opt,name=pub_video_expire,json=pubVideoExpire,proto3" json:"pub_video_expire,omitempty"`
+	PubDataStreamExpire uint32 `protobuf:"varint,9,opt,name=pub_data_stream_expire,json=pubDataStreamExpire,proto3" json:"pub_data_stream_expire,omitempty"`
+}
+
+func (x *GenerateRtcTokenRequest) Reset() {
+	*x = GenerateRtcTokenRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_agora_token_v1_token_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GenerateRtcTokenRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateRtcTokenRequest) ProtoMessage() {}
+
+func (x *GenerateRtcTokenRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_agora_token_v1_token_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateRtcTokenRequest.ProtoReflect.Descriptor instead.
+func (*GenerateRtcTokenRequest) Descriptor() ([]byte, []int) {
+	return file_agora_token_v1_token_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *GenerateRtcTokenRequest) GetProject() string {
+	if x != nil {
+		return x.Project
+	}
+	return ""
+}
+
+func (x *GenerateRtcTokenRequest) GetChannel() string {
+	if x != nil {
+		return x.Channel
+	}
+	return ""
+}
+
+func (x *GenerateRtcTokenRequest) GetUid() string {
+	if x != nil {
+		return x.Uid
+	}
+	return ""
+}
+
+func (x *GenerateRtcTokenRequest) GetRole() RtcRole {
+	if x != nil {
+		return x.Role
+	}
+	return RtcRole_RTC_ROLE_UNSPECIFIED
+}
+
+func (x *GenerateRtcTokenRequest) GetExpire() uint32 {
+	if x != nil {
+		return x.Expire
+	}
+	return 0
+}
+
+func (x *GenerateRtcTokenRequest) GetJoinChannelExpire() uint32 {
+	if x != nil {
+		return x.JoinChannelExpire
+	}
+	return 0
+}
+
+func (x *GenerateRtcTokenRequest) GetPubAudioExpire() uint32 {
+	if x != nil {
+		return x.PubAudioExpire
+	}
+	return 0
+}
+
+func (x *GenerateRtcTokenRequest) GetPubVideoExpire() uint32 {
+	if x != nil {
+		return x.PubVideoExpire
+	}
+	return 0
+}
+
+func (x *GenerateRtcTokenRequest) GetPubDataStreamExpire() uint32 {
+	if x != nil {
+		return x.PubDataStreamExpire
+	}
+	return 0
+}
+
+type GenerateRtcTokenResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Token string `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+}
+
+func (x *GenerateRtcTokenResponse) Reset() {
+	*x = GenerateRtcTokenResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_agora_token_v1_token_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GenerateRtcTokenResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateRtcTokenResponse) ProtoMessage() {}
+
+func (x *GenerateRtcTokenResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_agora_token_v1_token_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateRtcTokenResponse.ProtoReflect.Descriptor instead.
+func (*GenerateRtcTokenResponse) Descriptor() ([]byte, []int) {
+	return file_agora_token_v1_token_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GenerateRtcTokenResponse) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+type GenerateRtmTokenRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The registered project to sign the token for, the default project if empty.
+	Project string `protobuf:"bytes,1,opt,name=project,proto3" json:"project,omitempty"`
+	// The user ID.
+	Uid string `protobuf:"bytes,2,opt,name=uid,proto3" json:"uid,omitempty"`
+	// The optional channel the token is restricted to.
+	Channel string `protobuf:"bytes,3,opt,name=channel,proto3" json:"channel,omitempty"`
+	// The token expiration in seconds, 3600 if zero.
+	Expire uint32 `protobuf:"varint,4,opt,name=expire,proto3" json:"expire,omitempty"`
+}
+
+func (x *GenerateRtmTokenRequest) Reset() {
+	*x = GenerateRtmTokenRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_agora_token_v1_token_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GenerateRtmTokenRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateRtmTokenRequest) ProtoMessage() {}
+
+func (x *GenerateRtmTokenRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_agora_token_v1_token_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateRtmTokenRequest.ProtoReflect.Descriptor instead.
+func (*GenerateRtmTokenRequest) Descriptor() ([]byte, []int) {
+	return file_agora_token_v1_token_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GenerateRtmTokenRequest) GetProject() string {
+	if x != nil {
+		return x.Project
+	}
+	return ""
+}
+
+func (x *GenerateRtmTokenRequest) GetUid() string {
+	if x != nil {
+		return x.Uid
+	}
+	return ""
+}
+
+func (x *GenerateRtmTokenRequest) GetChannel() string {
+	if x != nil {
+		return x.Channel
+	}
+	return ""
+}
+
+func (x *GenerateRtmTokenRequest) GetExpire() uint32 {
+	if x != nil {
+		return x.Expire
+	}
+	return 0
+}
+
+type GenerateRtmTokenResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Token string `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+}
+
+func (x *GenerateRtmTokenResponse) Reset() {
+	*x = GenerateRtmTokenResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_agora_token_v1_token_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GenerateRtmTokenResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateRtmTokenResponse) ProtoMessage() {}
+
+func (x *GenerateRtmTokenResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_agora_token_v1_token_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateRtmTokenResponse.ProtoReflect.Descriptor instead.
+func (*GenerateRtmTokenResponse) Descriptor() ([]byte, []int) {
+	return file_agora_token_v1_token_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GenerateRtmTokenResponse) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+type GenerateChatTokenRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The registered project to sign the token for, the default project if empty.
+	Project string `protobuf:"bytes,1,opt,name=project,proto3" json:"project,omitempty"`
+	// The user ID of a chat user token, empty for a chat app token.
+	Uid string `protobuf:"bytes,2,opt,name=uid,proto3" json:"uid,omitempty"`
+	// The token expiration in seconds, 3600 if zero.
+	Expire uint32 `protobuf:"varint,3,opt,name=expire,proto3" json:"expire,omitempty"`
+}
+
+func (x *GenerateChatTokenRequest) Reset() {
+	*x = GenerateChatTokenRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_agora_token_v1_token_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GenerateChatTokenRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateChatTokenRequest) ProtoMessage() {}
+
+func (x *GenerateChatTokenRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_agora_token_v1_token_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateChatTokenRequest.ProtoReflect.Descriptor instead.
+func (*GenerateChatTokenRequest) Descriptor() ([]byte, []int) {
+	return file_agora_token_v1_token_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GenerateChatTokenRequest) GetProject() string {
+	if x != nil {
+		return x.Project
+	}
+	return ""
+}
+
+func (x *GenerateChatTokenRequest) GetUid() string {
+	if x != nil {
+		return x.Uid
+	}
+	return ""
+}
+
+func (x *GenerateChatTokenRequest) GetExpire() uint32 {
+	if x != nil {
+		return x.Expire
+	}
+	return 0
+}
+
+type GenerateChatTokenResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Token string `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+}
+
+func (x *GenerateChatTokenResponse) Reset() {
+	*x = GenerateChatTokenResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_agora_token_v1_token_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GenerateChatTokenResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateChatTokenResponse) ProtoMessage() {}
+
+func (x *GenerateChatTokenResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_agora_token_v1_token_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateChatTokenResponse.ProtoReflect.Descriptor instead.
+func (*GenerateChatTokenResponse) Descriptor() ([]byte, []int) {
+	return file_agora_token_v1_token_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GenerateChatTokenResponse) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+// TokenRequest is a single item of a GenerateTokens batch.
+type TokenRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Request:
+	//	*TokenRequest_Rtc
+	//	*TokenRequest_Rtm
+	//	*TokenRequest_Chat
+	Request isTokenRequest_Request `protobuf_oneof:"request"`
+}
+
+func (x *TokenRequest) Reset() {
+	*x = TokenRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_agora_token_v1_token_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TokenRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TokenRequest) ProtoMessage() {}
+
+func (x *TokenRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_agora_token_v1_token_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TokenRequest.ProtoReflect.Descriptor instead.
+func (*TokenRequest) Descriptor() ([]byte, []int) {
+	return file_agora_token_v1_token_proto_rawDescGZIP(), []int{6}
+}
+
+func (m *TokenRequest) GetRequest() isTokenRequest_Request {
+	if m != nil {
+		return m.Request
+	}
+	return nil
+}
+
+func (x *TokenRequest) GetRtc() *GenerateRtcTokenRequest {
+	if x, ok := x.GetRequest().(*TokenRequest_Rtc); ok {
+		return x.Rtc
+	}
+	return nil
+}
+
+func (x *TokenRequest) GetRtm() *GenerateRtmTokenRequest {
+	if x, ok := x.GetRequest().(*TokenRequest_Rtm); ok {
+		return x.Rtm
+	}
+	return nil
+}
+
+func (x *TokenRequest) GetChat() *GenerateChatTokenRequest {
+	if x, ok := x.GetRequest().(*TokenRequest_Chat); ok {
+		return x.Chat
+	}
+	return nil
+}
+
+type isTokenRequest_Request interface {
+	isTokenRequest_Request()
+}
+
+type TokenRequest_Rtc struct {
+	Rtc *GenerateRtcTokenRequest `protobuf:"bytes,1,opt,name=rtc,proto3,oneof"`
+}
+
+type TokenRequest_Rtm struct {
+	Rtm *GenerateRtmTokenRequest `protobuf:"bytes,2,opt,name=rtm,proto3,oneof"`
+}
+
+type TokenRequest_Chat struct {
+	Chat *GenerateChatTokenRequest `protobuf:"bytes,3,opt,name=chat,proto3,oneof"`
+}
+
+func (*TokenRequest_Rtc) isTokenRequest_Request() {}
+
+func (*TokenRequest_Rtm) isTokenRequest_Request() {}
+
+func (*TokenRequest_Chat) isTokenRequest_Request() {}
+
+type GenerateTokensRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Requests []*TokenRequest `protobuf:"bytes,1,rep,name=requests,proto3" json:"requests,omitempty"`
+}
+
+func (x *GenerateTokensRequest) Reset() {
+	*x = GenerateTokensRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_agora_token_v1_token_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GenerateTokensRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateTokensRequest) ProtoMessage() {}
+
+func (x *GenerateTokensRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_agora_token_v1_token_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateTokensRequest.ProtoReflect.Descriptor instead.
+func (*GenerateTokensRequest) Descriptor() ([]byte, []int) {
+	return file_agora_token_v1_token_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GenerateTokensRequest) GetRequests() []*TokenRequest {
+	if x != nil {
+		return x.Requests
+	}
+	return nil
+}
+
+// TokenResult is the outcome of a single item of a GenerateTokens batch.
+type TokenResult struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The gRPC status code of the item, OK (0) on success.
+	Code uint32 `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	// The generated token, if successful.
+	Token string `protobuf:"bytes,2,opt,name=token,proto3" json:"token,omitempty"`
+	// The error message, if the token could not be generated.
+	Error string `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	// The policy rule that denied the item, if any.
+	Rule string `protobuf:"bytes,4,opt,name=rule,proto3" json:"rule,omitempty"`
+	// The seconds until the item may be retried, if rate limited.
+	RetryAfter uint32 `protobuf:"varint,5,opt,name=retry_after,json=retryAfter,proto3" json:"retry_after,omitempty"`
+	// The machine-readable error code of the REST API, e.g. MISSING_CHANNEL, if the token could not be generated.
+	Reason string `protobuf:"bytes,6,opt,name=reason,proto3" json:"reason,omitempty"`
+	// The request field at fault, if any.
+	Field string `protobuf:"bytes,7,opt,name=field,proto3" json:"field,omitempty"`
+}
+
+func (x *TokenResult) Reset() {
+	*x = TokenResult{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_agora_token_v1_token_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TokenResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TokenResult) ProtoMessage() {}
+
+func (x *TokenResult) ProtoReflect() protoreflect.Message {
+	mi := &file_agora_token_v1_token_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TokenResult.ProtoReflect.Descriptor instead.
+func (*TokenResult) Descriptor() ([]byte, []int) {
+	return file_agora_token_v1_token_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *TokenResult) GetCode() uint32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *TokenResult) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *TokenResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *TokenResult) GetRule() string {
+	if x != nil {
+		return x.Rule
+	}
+	return ""
+}
+
+func (x *TokenResult) GetRetryAfter() uint32 {
+	if x != nil {
+		return x.RetryAfter
+	}
+	return 0
+}
+
+func (x *TokenResult) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *TokenResult) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+type GenerateTokensResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The results, in the order of the requests.
+	Results []*TokenResult `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+}
+
+func (x *GenerateTokensResponse) Reset() {
+	*x = GenerateTokensResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_agora_token_v1_token_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GenerateTokensResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateTokensResponse) ProtoMessage() {}
+
+func (x *GenerateTokensResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_agora_token_v1_token_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateTokensResponse.ProtoReflect.Descriptor instead.
+func (*GenerateTokensResponse) Descriptor() ([]byte, []int) {
+	return file_agora_token_v1_token_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GenerateTokensResponse) GetResults() []*TokenResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+var File_agora_token_v1_token_proto protoreflect.FileDescriptor
+
+var file_agora_token_v1_token_proto_rawDesc = []byte{
+	0x0a, 0x1a, 0x61, 0x67, 0x6f, 0x72, 0x61, 0x2f, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x2f, 0x76, 0x31,
+	0x2f, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0e, 0x61, 0x67,
+	0x6f, 0x72, 0x61, 0x2e, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x2e, 0x76, 0x31, 0x22, 0xdd, 0x02, 0x0a,
+	0x17, 0x47, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x65, 0x52, 0x74, 0x63, 0x54, 0x6f, 0x6b, 0x65,
+	0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x70, 0x72, 0x6f, 0x6a,
+	0x65, 0x63, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x70, 0x72, 0x6f, 0x6a, 0x65,
+	0x63, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x07, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x12, 0x10, 0x0a, 0x03,
+	0x75, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75, 0x69, 0x64, 0x12, 0x2b,
+	0x0a, 0x04, 0x72, 0x6f, 0x6c, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x17, 0x2e, 0x61,
+	0x67, 0x6f, 0x72, 0x61, 0x2e, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x74,
+	0x63, 0x52, 0x6f, 0x6c, 0x65, 0x52, 0x04, 0x72, 0x6f, 0x6c, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x65,
+	0x78, 0x70, 0x69, 0x72, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x06, 0x65, 0x78, 0x70,
+	0x69, 0x72, 0x65, 0x12, 0x2e, 0x0a, 0x13, 0x6a, 0x6f, 0x69, 0x6e, 0x5f, 0x63, 0x68, 0x61, 0x6e,
+	0x6e, 0x65, 0x6c, 0x5f, 0x65, 0x78, 0x70, 0x69, 0x72, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0d,
+	0x52, 0x11, 0x6a, 0x6f, 0x69, 0x6e, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x45, 0x78, 0x70,
+	0x69, 0x72, 0x65, 0x12, 0x28, 0x0a, 0x10, 0x70, 0x75, 0x62, 0x5f, 0x61, 0x75, 0x64, 0x69, 0x6f,
+	0x5f, 0x65, 0x78, 0x70, 0x69, 0x72, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0e, 0x70,
+	0x75, 0x62, 0x41, 0x75, 0x64, 0x69, 0x6f, 0x45, 0x78, 0x70, 0x69, 0x72, 0x65, 0x12, 0x28, 0x0a,
+	0x10, 0x70, 0x75, 0x62, 0x5f, 0x76, 0x69, 0x64, 0x65, 0x6f, 0x5f, 0x65, 0x78, 0x70, 0x69, 0x72,
+	0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0e, 0x70, 0x75, 0x62, 0x56, 0x69, 0x64, 0x65,
+	0x6f, 0x45, 0x78, 0x70, 0x69, 0x72, 0x65, 0x12, 0x33, 0x0a, 0x16, 0x70, 0x75, 0x62, 0x5f, 0x64,
+	0x61, 0x74, 0x61, 0x5f, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x5f, 0x65, 0x78, 0x70, 0x69, 0x72,
+	0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x13, 0x70, 0x75, 0x62, 0x44, 0x61, 0x74, 0x61,
+	0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x45, 0x78, 0x70, 0x69, 0x72, 0x65, 0x22, 0x30, 0x0a, 0x18,
+	0x47, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x65, 0x52, 0x74, 0x63, 0x54, 0x6f, 0x6b, 0x65, 0x6e,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x6f, 0x6b, 0x65,
+	0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x22, 0x77,
+	0x0a, 0x17, 0x47, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x65, 0x52, 0x74, 0x6d, 0x54, 0x6f, 0x6b,
+	0x65, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x70, 0x72, 0x6f,
+	0x6a, 0x65, 0x63, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x70, 0x72, 0x6f, 0x6a,
+	0x65, 0x63, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x75, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x03, 0x75, 0x69, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x12,
+	0x16, 0x0a, 0x06, 0x65, 0x78, 0x70, 0x69, 0x72, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0d, 0x52,
+	0x06, 0x65, 0x78, 0x70, 0x69, 0x72, 0x65, 0x22, 0x30, 0x0a, 0x18, 0x47, 0x65, 0x6e, 0x65, 0x72,
+	0x61, 0x74, 0x65, 0x52, 0x74, 0x6d, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x05, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x22, 0x5e, 0x0a, 0x18, 0x47, 0x65, 0x6e,
+	0x65, 0x72, 0x61, 0x74, 0x65, 0x43, 0x68, 0x61, 0x74, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x70, 0x72, 0x6f, 0x6a, 0x65, 0x63, 0x74,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x70, 0x72, 0x6f, 0x6a, 0x65, 0x63, 0x74, 0x12,
+	0x10, 0x0a, 0x03, 0x75, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75, 0x69,
+	0x64, 0x12, 0x16, 0x0a, 0x06, 0x65, 0x78, 0x70, 0x69, 0x72, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x0d, 0x52, 0x06, 0x65, 0x78, 0x70, 0x69, 0x72, 0x65, 0x22, 0x31, 0x0a, 0x19, 0x47, 0x65, 0x6e,
+	0x65, 0x72, 0x61, 0x74, 0x65, 0x43, 0x68, 0x61, 0x74, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x22, 0xd3, 0x01, 0x0a,
+	0x0c, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x3b, 0x0a,
+	0x03, 0x72, 0x74, 0x63, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x27, 0x2e, 0x61, 0x67, 0x6f,
+	0x72, 0x61, 0x2e, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x6e, 0x65,
+	0x72, 0x61, 0x74, 0x65, 0x52, 0x74, 0x63, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x48, 0x00, 0x52, 0x03, 0x72, 0x74, 0x63, 0x12, 0x3b, 0x0a, 0x03, 0x72, 0x74,
+	0x6d, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x27, 0x2e, 0x61, 0x67, 0x6f, 0x72, 0x61, 0x2e,
+	0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74,
+	0x65, 0x52, 0x74, 0x6d, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x48, 0x00, 0x52, 0x03, 0x72, 0x74, 0x6d, 0x12, 0x3e, 0x0a, 0x04, 0x63, 0x68, 0x61, 0x74, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x28, 0x2e, 0x61, 0x67, 0x6f, 0x72, 0x61, 0x2e, 0x74, 0x6f,
+	0x6b, 0x65, 0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x65, 0x43,
+	0x68, 0x61, 0x74, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x48,
+	0x00, 0x52, 0x04, 0x63, 0x68, 0x61, 0x74, 0x42, 0x09, 0x0a, 0x07, 0x72, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x22, 0x51, 0x0a, 0x15, 0x47, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x65, 0x54, 0x6f,
+	0x6b, 0x65, 0x6e, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x38, 0x0a, 0x08, 0x72,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1c, 0x2e,
+	0x61, 0x67, 0x6f, 0x72, 0x61, 0x2e, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x54,
+	0x6f, 0x6b, 0x65, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x52, 0x08, 0x72, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x73, 0x22, 0xb0, 0x01, 0x0a, 0x0b, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x52,
+	0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0d, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x6f, 0x6b,
+	0x65, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x12,
+	0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05,
+	0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x75, 0x6c, 0x65, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x75, 0x6c, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x72, 0x65, 0x74,
+	0x72, 0x79, 0x5f, 0x61, 0x66, 0x74, 0x65, 0x72, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0a,
+	0x72, 0x65, 0x74, 0x72, 0x79, 0x41, 0x66, 0x74, 0x65, 0x72, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65,
+	0x61, 0x73, 0x6f, 0x6e, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x65, 0x61, 0x73,
+	0x6f, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x18, 0x07, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x05, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x22, 0x4f, 0x0a, 0x16, 0x47, 0x65, 0x6e, 0x65,
+	0x72, 0x61, 0x74, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x35, 0x0a, 0x07, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x18, 0x01, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x61, 0x67, 0x6f, 0x72, 0x61, 0x2e, 0x74, 0x6f, 0x6b, 0x65,
+	0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74,
+	0x52, 0x07, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x2a, 0x54, 0x0a, 0x07, 0x52, 0x74, 0x63,
+	0x52, 0x6f, 0x6c, 0x65, 0x12, 0x18, 0x0a, 0x14, 0x52, 0x54, 0x43, 0x5f, 0x52, 0x4f, 0x4c, 0x45,
+	0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x16,
+	0x0a, 0x12, 0x52, 0x54, 0x43, 0x5f, 0x52, 0x4f, 0x4c, 0x45, 0x5f, 0x50, 0x55, 0x42, 0x4c, 0x49,
+	0x53, 0x48, 0x45, 0x52, 0x10, 0x01, 0x12, 0x17, 0x0a, 0x13, 0x52, 0x54, 0x43, 0x5f, 0x52, 0x4f,
+	0x4c, 0x45, 0x5f, 0x53, 0x55, 0x42, 0x53, 0x43, 0x52, 0x49, 0x42, 0x45, 0x52, 0x10, 0x02, 0x32,
+	0xa7, 0x03, 0x0a, 0x0c, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65,
+	0x12, 0x65, 0x0a, 0x10, 0x47, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x65, 0x52, 0x74, 0x63, 0x54,
+	0x6f, 0x6b, 0x65, 0x6e, 0x12, 0x27, 0x2e, 0x61, 0x67, 0x6f, 0x72, 0x61, 0x2e, 0x74, 0x6f, 0x6b,
+	0x65, 0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x65, 0x52, 0x74,
+	0x63, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x28, 0x2e,
+	0x61, 0x67, 0x6f, 0x72, 0x61, 0x2e, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x47,
+	0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x65, 0x52, 0x74, 0x63, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x65, 0x0a, 0x10, 0x47, 0x65, 0x6e, 0x65, 0x72,
+	0x61, 0x74, 0x65, 0x52, 0x74, 0x6d, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x12, 0x27, 0x2e, 0x61, 0x67,
+	0x6f, 0x72, 0x61, 0x2e, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x6e,
+	0x65, 0x72, 0x61, 0x74, 0x65, 0x52, 0x74, 0x6d, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x28, 0x2e, 0x61, 0x67, 0x6f, 0x72, 0x61, 0x2e, 0x74, 0x6f, 0x6b,
+	0x65, 0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x65, 0x52, 0x74,
+	0x6d, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x68,
+	0x0a, 0x11, 0x47, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x65, 0x43, 0x68, 0x61, 0x74, 0x54, 0x6f,
+	0x6b, 0x65, 0x6e, 0x12, 0x28, 0x2e, 0x61, 0x67, 0x6f, 0x72, 0x61, 0x2e, 0x74, 0x6f, 0x6b, 0x65,
+	0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x65, 0x43, 0x68, 0x61,
+	0x74, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x29, 0x2e,
+	0x61, 0x67, 0x6f, 0x72, 0x61, 0x2e, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x47,
+	0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x65, 0x43, 0x68, 0x61, 0x74, 0x54, 0x6f, 0x6b, 0x65, 0x6e,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x5f, 0x0a, 0x0e, 0x47, 0x65, 0x6e, 0x65,
+	0x72, 0x61, 0x74, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x73, 0x12, 0x25, 0x2e, 0x61, 0x67, 0x6f,
+	0x72, 0x61, 0x2e, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x6e, 0x65,
+	0x72, 0x61, 0x74, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x26, 0x2e, 0x61, 0x67, 0x6f, 0x72, 0x61, 0x2e, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x2e,
+	0x76, 0x31, 0x2e, 0x47, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e,
+	0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x4f, 0x5a, 0x4d, 0x67, 0x69, 0x74,
+	0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x41, 0x67, 0x6f, 0x72, 0x61, 0x49, 0x4f, 0x2d,
+	0x43, 0x6f, 0x6d, 0x6d, 0x75, 0x6e, 0x69, 0x74, 0x79, 0x2f, 0x61, 0x67, 0x6f, 0x72, 0x61, 0x2d,
+	0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x2d, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2f, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x2f, 0x61, 0x67, 0x6f, 0x72, 0x61, 0x2f, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x2f,
+	0x76, 0x31, 0x3b, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x76, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x33,
+}
+
+var (
+	file_agora_token_v1_token_proto_rawDescOnce sync.Once
+	file_agora_token_v1_token_proto_rawDescData = file_agora_token_v1_token_proto_rawDesc
+)
+
+func file_agora_token_v1_token_proto_rawDescGZIP() []byte {
+	file_agora_token_v1_token_proto_rawDescOnce.Do(func() {
+		file_agora_token_v1_token_proto_rawDescData = protoimpl.X.CompressGZIP(file_agora_token_v1_token_proto_rawDescData)
+	})
+	return file_agora_token_v1_token_proto_rawDescData
+}
+
+var file_agora_token_v1_token_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_agora_token_v1_token_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_agora_token_v1_token_proto_goTypes = []any{
+	(RtcRole)(0),                      // 0: agora.token.v1.RtcRole
+	(*GenerateRtcTokenRequest)(nil),   // 1: agora.token.v1.GenerateRtcTokenRequest
+	(*GenerateRtcTokenResponse)(nil),  // 2: agora.token.v1.GenerateRtcTokenResponse
+	(*GenerateRtmTokenRequest)(nil),   // 3: agora.token.v1.GenerateRtmTokenRequest
+	(*GenerateRtmTokenResponse)(nil),  // 4: agora.token.v1.GenerateRtmTokenResponse
+	(*GenerateChatTokenRequest)(nil),  // 5: agora.token.v1.GenerateChatTokenRequest
+	(*GenerateChatTokenResponse)(nil), // 6: agora.token.v1.GenerateChatTokenResponse
+	(*TokenRequest)(nil),              // 7: agora.token.v1.TokenRequest
+	(*GenerateTokensRequest)(nil),     // 8: agora.token.v1.GenerateTokensRequest
+	(*TokenResult)(nil),               // 9: agora.token.v1.TokenResult
+	(*GenerateTokensResponse)(nil),    // 10: agora.token.v1.GenerateTokensResponse
+}
+var file_agora_token_v1_token_proto_depIdxs = []int32{
+	0,  // 0: agora.token.v1.GenerateRtcTokenRequest.role:type_name -> agora.token.v1.RtcRole
+	1,  // 1: agora.token.v1.TokenRequest.rtc:type_name -> agora.token.v1.GenerateRtcTokenRequest
+	3,  // 2: agora.token.v1.TokenRequest.rtm:type_name -> agora.token.v1.GenerateRtmTokenRequest
+	5,  // 3: agora.token.v1.TokenRequest.chat:type_name -> agora.token.v1.GenerateChatTokenRequest
+	7,  // 4: agora.token.v1.GenerateTokensRequest.requests:type_name -> agora.token.v1.TokenRequest
+	9,  // 5: agora.token.v1.GenerateTokensResponse.results:type_name -> agora.token.v1.TokenResult
+	1,  // 6: agora.token.v1.TokenService.GenerateRtcToken:input_type -> agora.token.v1.GenerateRtcTokenRequest
+	3,  // 7: agora.token.v1.TokenService.GenerateRtmToken:input_type -> agora.token.v1.GenerateRtmTokenRequest
+	5,  // 8: agora.token.v1.TokenService.GenerateChatToken:input_type -> agora.token.v1.GenerateChatTokenRequest
+	8,  // 9: agora.token.v1.TokenService.GenerateTokens:input_type -> agora.token.v1.GenerateTokensRequest
+	2,  // 10: agora.token.v1.TokenService.GenerateRtcToken:output_type -> agora.token.v1.GenerateRtcTokenResponse
+	4,  // 11: agora.token.v1.TokenService.GenerateRtmToken:output_type -> agora.token.v1.GenerateRtmTokenResponse
+	6,  // 12: agora.token.v1.TokenService.GenerateChatToken:output_type -> agora.token.v1.GenerateChatTokenResponse
+	10, // 13: agora.token.v1.TokenService.GenerateTokens:output_type -> agora.token.v1.GenerateTokensResponse
+	10, // [10:14] is the sub-list for method output_type
+	6,  // [6:10] is the sub-list for method input_type
+	6,  // [6:6] is the sub-list for extension type_name
+	6,  // [6:6] is the sub-list for extension extendee
+	0,  // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_agora_token_v1_token_proto_init() }
+func file_agora_token_v1_token_proto_init() {
+	if File_agora_token_v1_token_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_agora_token_v1_token_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*GenerateRtcTokenRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_agora_token_v1_token_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*GenerateRtcTokenResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_agora_token_v1_token_proto_msgTypes[2].Exporter = func(v any, i int) any {
+			switch v := v.(*GenerateRtmTokenRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_agora_token_v1_token_proto_msgTypes[3].Exporter = func(v any, i int) any {
+			switch v := v.(*GenerateRtmTokenResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_agora_token_v1_token_proto_msgTypes[4].Exporter = func(v any, i int) any {
+			switch v := v.(*GenerateChatTokenRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_agora_token_v1_token_proto_msgTypes[5].Exporter = func(v any, i int) any {
+			switch v := v.(*GenerateChatTokenResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_agora_token_v1_token_proto_msgTypes[6].Exporter = func(v any, i int) any {
+			switch v := v.(*TokenRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_agora_token_v1_token_proto_msgTypes[7].Exporter = func(v any, i int) any {
+			switch v := v.(*GenerateTokensRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_agora_token_v1_token_proto_msgTypes[8].Exporter = func(v any, i int) any {
+			switch v := v.(*TokenResult); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_agora_token_v1_token_proto_msgTypes[9].Exporter = func(v any, i int) any {
+			switch v := v.(*GenerateTokensResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_agora_token_v1_token_proto_msgTypes[6].OneofWrappers = []any{
+		(*TokenRequest_Rtc)(nil),
+		(*TokenRequest_Rtm)(nil),
+		(*TokenRequest_Chat)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_agora_token_v1_token_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   10,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_agora_token_v1_token_proto_goTypes,
+		DependencyIndexes: file_agora_token_v1_token_proto_depIdxs,
+		EnumInfos:         file_agora_token_v1_token_proto_enumTypes,
+		MessageInfos:      file_agora_token_v1_token_proto_msgTypes,
+	}.Build()
+	File_agora_token_v1_token_proto = out.File
+	file_agora_token_v1_token_proto_rawDesc = nil
+	file_agora_token_v1_token_proto_goTypes = nil
+	file_agora_token_v1_token_proto_depIdxs = nil
+}
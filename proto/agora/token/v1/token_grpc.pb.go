@@ -0,0 +1,255 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: agora/token/v1/token.proto
+
+package tokenv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	TokenService_GenerateRtcToken_FullMethodName  = "/agora.token.v1.TokenService/GenerateRtcToken"
+	TokenService_GenerateRtmToken_FullMethodName  = "/agora.token.v1.TokenService/GenerateRtmToken"
+	TokenService_GenerateChatToken_FullMethodName = "/agora.token.v1.TokenService/GenerateChatToken"
+	TokenService_GenerateTokens_FullMethodName    = "/agora.token.v1.TokenService/GenerateTokens"
+)
+
+// TokenServiceClient is the client API for TokenService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// TokenService generates Agora tokens. It mirrors the POST /getToken and POST /getTokens REST endpoints,
+// sharing their validation, authentication, policies and rate limits.
+//
+// Authenticate with the same credentials as the REST endpoints, sent as metadata: "x-api-key" for API keys,
+// "authorization" for bearer JWTs, or a client certificate when mutual TLS is enabled.
+type TokenServiceClient interface {
+	// GenerateRtcToken generates a token to join an RTC channel.
+	GenerateRtcToken(ctx context.Context, in *GenerateRtcTokenRequest, opts ...grpc.CallOption) (*GenerateRtcTokenResponse, error)
+	// GenerateRtmToken generates a token to log in to RTM.
+	GenerateRtmToken(ctx context.Context, in *GenerateRtmTokenRequest, opts ...grpc.CallOption) (*GenerateRtmTokenResponse, error)
+	// GenerateChatToken generates a chat user token, or a chat app token when the uid is empty.
+	GenerateChatToken(ctx context.Context, in *GenerateChatTokenRequest, opts ...grpc.CallOption) (*GenerateChatTokenResponse, error)
+	// GenerateTokens generates a batch of tokens. Items fail independently: the results report the status of each.
+	GenerateTokens(ctx context.Context, in *GenerateTokensRequest, opts ...grpc.CallOption) (*GenerateTokensResponse, error)
+}
+
+type tokenServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTokenServiceClient(cc grpc.ClientConnInterface) TokenServiceClient {
+	return &tokenServiceClient{cc}
+}
+
+func (c *tokenServiceClient) GenerateRtcToken(ctx context.Context, in *GenerateRtcTokenRequest, opts ...grpc.CallOption) (*GenerateRtcTokenResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GenerateRtcTokenResponse)
+	err := c.cc.Invoke(ctx, TokenService_GenerateRtcToken_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tokenServiceClient) GenerateRtmToken(ctx context.Context, in *GenerateRtmTokenRequest, opts ...grpc.CallOption) (*GenerateRtmTokenResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GenerateRtmTokenResponse)
+	err := c.cc.Invoke(ctx, TokenService_GenerateRtmToken_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tokenServiceClient) GenerateChatToken(ctx context.Context, in *GenerateChatTokenRequest, opts ...grpc.CallOption) (*GenerateChatTokenResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GenerateChatTokenResponse)
+	err := c.cc.Invoke(ctx, TokenService_GenerateChatToken_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tokenServiceClient) GenerateTokens(ctx context.Context, in *GenerateTokensRequest, opts ...grpc.CallOption) (*GenerateTokensResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GenerateTokensResponse)
+	err := c.cc.Invoke(ctx, TokenService_GenerateTokens_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TokenServiceServer is the server API for TokenService service.
+// All implementations must embed UnimplementedTokenServiceServer
+// for forward compatibility.
+//
+// TokenService generates Agora tokens. It mirrors the POST /getToken and POST /getTokens REST endpoints,
+// sharing their validation, authentication, policies and rate limits.
+//
+// Authenticate with the same credentials as the REST endpoints, sent as metadata: "x-api-key" for API keys,
+// "authorization" for bearer JWTs, or a client certificate when mutual TLS is enabled.
+type TokenServiceServer interface {
+	// GenerateRtcToken generates a token to join an RTC channel.
+	GenerateRtcToken(context.Context, *GenerateRtcTokenRequest) (*GenerateRtcTokenResponse, error)
+	// GenerateRtmToken generates a token to log in to RTM.
+	GenerateRtmToken(context.Context, *GenerateRtmTokenRequest) (*GenerateRtmTokenResponse, error)
+	// GenerateChatToken generates a chat user token, or a chat app token when the uid is empty.
+	GenerateChatToken(context.Context, *GenerateChatTokenRequest) (*GenerateChatTokenResponse, error)
+	// GenerateTokens generates a batch of tokens. Items fail independently: the results report the status of each.
+	GenerateTokens(context.Context, *GenerateTokensRequest) (*GenerateTokensResponse, error)
+	mustEmbedUnimplementedTokenServiceServer()
+}
+
+// UnimplementedTokenServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedTokenServiceServer struct{}
+
+func (UnimplementedTokenServiceServer) GenerateRtcToken(context.Context, *GenerateRtcTokenRequest) (*GenerateRtcTokenResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GenerateRtcToken not implemented")
+}
+func (UnimplementedTokenServiceServer) GenerateRtmToken(context.Context, *GenerateRtmTokenRequest) (*GenerateRtmTokenResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GenerateRtmToken not implemented")
+}
+func (UnimplementedTokenServiceServer) GenerateChatToken(context.Context, *GenerateChatTokenRequest) (*GenerateChatTokenResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GenerateChatToken not implemented")
+}
+func (UnimplementedTokenServiceServer) GenerateTokens(context.Context, *GenerateTokensRequest) (*GenerateTokensResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GenerateTokens not implemented")
+}
+func (UnimplementedTokenServiceServer) mustEmbedUnimplementedTokenServiceServer() {}
+func (UnimplementedTokenServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeTokenServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TokenServiceServer will
+// result in compilation errors.
+type UnsafeTokenServiceServer interface {
+	mustEmbedUnimplementedTokenServiceServer()
+}
+
+func RegisterTokenServiceServer(s grpc.ServiceRegistrar, srv TokenServiceServer) {
+	// If the following call pancis, it indicates UnimplementedTokenServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&TokenService_ServiceDesc, srv)
+}
+
+func _TokenService_GenerateRtcToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GenerateRtcTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenServiceServer).GenerateRtcToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TokenService_GenerateRtcToken_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenServiceServer).GenerateRtcToken(ctx, req.(*GenerateRtcTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TokenService_GenerateRtmToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GenerateRtmTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenServiceServer).GenerateRtmToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TokenService_GenerateRtmToken_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenServiceServer).GenerateRtmToken(ctx, req.(*GenerateRtmTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TokenService_GenerateChatToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GenerateChatTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenServiceServer).GenerateChatToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TokenService_GenerateChatToken_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenServiceServer).GenerateChatToken(ctx, req.(*GenerateChatTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TokenService_GenerateTokens_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GenerateTokensRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenServiceServer).GenerateTokens(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TokenService_GenerateTokens_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenServiceServer).GenerateTokens(ctx, req.(*GenerateTokensRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// TokenService_ServiceDesc is the grpc.ServiceDesc for TokenService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var TokenService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "agora.token.v1.TokenService",
+	HandlerType: (*TokenServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GenerateRtcToken",
+			Handler:    _TokenService_GenerateRtcToken_Handler,
+		},
+		{
+			MethodName: "GenerateRtmToken",
+			Handler:    _TokenService_GenerateRtmToken_Handler,
+		},
+		{
+			MethodName: "GenerateChatToken",
+			Handler:    _TokenService_GenerateChatToken_Handler,
+		},
+		{
+			MethodName: "GenerateTokens",
+			Handler:    _TokenService_GenerateTokens_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "agora/token/v1/token.proto",
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerAdminRoutes adds the admin endpoints to the router, behind the AuthMiddleware and adminMiddleware.
+func (s *Service) registerAdminRoutes(router gin.IRoutes) {
+	router.GET("certificates", s.getCertificates)
+	router.POST("certificates/rotate", s.rotateCertificate)
+	router.GET("audit", s.getAudit)
+}
+
+// adminMiddleware restricts the admin endpoints to the principals listed in the admin subjects, qualified with
+// their authentication method, so a JWT "sub" claim can not impersonate an API key of the same name.
+// It responds with 403 Forbidden to everyone else, and to every request when no admin subject is configured.
+func (s *Service) adminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, authenticated := PrincipalFromContext(c.Request.Context())
+		if !authenticated || !matchesAny(s.adminSubjects, principal.String()) {
+			abortWithError(c, &APIError{
+				Status: http.StatusForbidden, Code: CodeAdminRequired, Message: "Forbidden: admin access required",
+			})
+			return
+		}
+		c.Next()
+	}
+}
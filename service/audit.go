@@ -0,0 +1,462 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// The audit sinks of AuditConfig.Sinks.
+	auditSinkStdout = "stdout"
+	auditSinkFile   = "file"
+	auditSinkSQLite = "sqlite"
+
+	// defaultAuditMaxSizeMB is the size, in megabytes, beyond which the audit file is rotated.
+	defaultAuditMaxSizeMB = 100
+
+	// defaultAuditMaxBackups is the number of rotated audit files kept.
+	defaultAuditMaxBackups = 5
+
+	// auditRecordTimeout bounds the recording of a token, which delays the response and configuration reloads.
+	auditRecordTimeout = 2 * time.Second
+)
+
+// AuditRecord describes a single issued token. The token itself is never recorded, only its fingerprint,
+// so the records can tell who got a given token without allowing anyone to reuse it.
+type AuditRecord struct {
+	Time        time.Time `json:"time"`                // When the token was issued
+	RequestID   string    `json:"requestId,omitempty"` // The ID of the request that issued the token
+	Principal   string    `json:"principal,omitempty"` // The authenticated caller, as "<method>:<subject>", empty without authentication
+	ClientIP    string    `json:"clientIp,omitempty"`  // The IP address of the caller
+	Route       string    `json:"route"`               // The endpoints that issued the token: "post", "batch", "legacy", "grpc" or "grpc_batch"
+	Project     string    `json:"project,omitempty"`   // The registered project that signed the token, empty for the default project
+	TokenType   string    `json:"tokenType"`           // The services of the token: "rtc", "rtm" or "chat", joined with "+" for multi-service tokens
+	Channel     string    `json:"channel,omitempty"`   // The channel name (RTC and RTM tokens)
+	Uid         string    `json:"uid,omitempty"`       // The user ID or account
+	Role        string    `json:"role,omitempty"`      // The RTC role: "publisher" or "subscriber" (RTC tokens only)
+	Privileges  []string  `json:"privileges"`          // The granted privileges, as "<service>.<privilege>", e.g. "rtc.publishAudioStream"
+	Expire      uint32    `json:"expire"`              // The token lifetime in seconds
+	ExpiresAt   time.Time `json:"expiresAt"`           // When the token expires
+	Fingerprint string    `json:"fingerprint"`         // The TokenFingerprint of the token
+}
+
+// AuditSink stores the audit records of the issued tokens.
+// Implementations must be safe for concurrent use.
+type AuditSink interface {
+	// Record stores the record of an issued token.
+	Record(ctx context.Context, record AuditRecord) error
+
+	// Close flushes the pending records and releases the resources of the sink.
+	Close() error
+}
+
+// TokenFingerprint returns the hex encoded SHA-256 hash of a token, which identifies the token in the audit records.
+//
+// Example usage:
+//
+//	// Find who the leaked token was issued to
+//	fingerprint := service.TokenFingerprint("007eJxTYBBbsfRc...")
+func TokenFingerprint(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// JSONAuditSink writes the audit records as JSON lines, such as to stdout.
+type JSONAuditSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONAuditSink returns a sink writing one JSON record per line to w.
+func NewJSONAuditSink(w io.Writer) *JSONAuditSink {
+	return &JSONAuditSink{w: w}
+}
+
+// Record implements AuditSink.
+func (j *JSONAuditSink) Record(_ context.Context, record AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, err = j.w.Write(append(line, '\n'))
+	return err
+}
+
+// Close implements AuditSink. The writer is left open.
+func (j *JSONAuditSink) Close() error {
+	return nil
+}
+
+// FileAuditSink writes the audit records to a JSON lines file, rotated once it exceeds its maximum size.
+type FileAuditSink struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// NewFileAuditSink opens, or creates, the JSON lines file of a sink.
+//
+// Parameters:
+//   - path: string - The audit file. Records are appended to it.
+//   - maxSize: int64 - The size, in bytes, beyond which the file is rotated.
+//   - maxBackups: int - The number of rotated files kept, named <path>.1 (the latest), <path>.2, and so on.
+//
+// Returns:
+//   - *FileAuditSink: The sink.
+//   - error: An error if the file can not be opened.
+//
+// Example usage:
+//
+//	sink, err := service.NewFileAuditSink("/var/log/agora/audit.jsonl", 100<<20, 5)
+func NewFileAuditSink(path string, maxSize int64, maxBackups int) (*FileAuditSink, error) {
+	f := &FileAuditSink{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// open opens the audit file for appending, and reads its current size.
+func (f *FileAuditSink) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit file: %w", err)
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open audit file: %w", err)
+	}
+	f.file, f.size = file, stat.Size()
+	return nil
+}
+
+// Record implements AuditSink.
+func (f *FileAuditSink) Record(_ context.Context, record AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return errors.New("audit file closed")
+	}
+	if f.size > 0 && f.size+int64(len(line)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := f.file.Write(line)
+	f.size += int64(n)
+	return err
+}
+
+// rotate renames the audit file to <path>.1, shifting the previous backups and dropping the oldest one,
+// then starts a new file. The caller must hold mu.
+func (f *FileAuditSink) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("failed to rotate audit file: %w", err)
+	}
+	f.file = nil
+	for i := f.maxBackups - 1; i >= 1; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", f.path, i), fmt.Sprintf("%s.%d", f.path, i+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to rotate audit file: %w", err)
+		}
+	}
+	var err error
+	if f.maxBackups > 0 {
+		err = os.Rename(f.path, f.path+".1")
+	} else {
+		err = os.Remove(f.path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to rotate audit file: %w", err)
+	}
+	return f.open()
+}
+
+// Close implements AuditSink.
+func (f *FileAuditSink) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// loadAuditSinks opens the sinks of the configuration. The sinks already opened are closed if one fails.
+func loadAuditSinks(config AuditConfig) ([]AuditSink, error) {
+	var sinks []AuditSink
+	for _, name := range config.Sinks {
+		var sink AuditSink
+		var err error
+		switch name {
+		case auditSinkStdout:
+			sink = NewJSONAuditSink(os.Stdout)
+		case auditSinkFile:
+			sink, err = NewFileAuditSink(config.File, int64(config.MaxSizeMB)<<20, config.MaxBackups)
+		case auditSinkSQLite:
+			sink, err = NewSQLiteAuditSink(config.Database)
+		default:
+			err = fmt.Errorf("unknown audit sink %q", name)
+		}
+		if err != nil {
+			closeAuditSinks(sinks)
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks, nil
+}
+
+// closeAuditSinks closes the sinks, returning their errors joined.
+func closeAuditSinks(sinks []AuditSink) error {
+	var errs []error
+	for _, sink := range sinks {
+		if err := sink.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// auditTokens records the tokens issued for the token request in the audit sinks. Failures are logged, as the
+// tokens have already been issued. The records are written even if the request is cancelled, such as when the
+// client disconnects, within auditRecordTimeout. The caller must hold mu for reading.
+func (s *Service) auditTokens(ctx context.Context, tokenReq TokenRequest, route string, tokens ...string) {
+	if len(s.auditSinks) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), auditRecordTimeout)
+	defer cancel()
+	for _, token := range tokens {
+		record, err := newAuditRecord(ctx, tokenReq, route, token)
+		if err != nil {
+			s.log(ctx).Error("audit record failed", "error", err, "fingerprint", TokenFingerprint(token))
+			continue
+		}
+		for _, sink := range s.auditSinks {
+			if err := sink.Record(ctx, record); err != nil {
+				s.log(ctx).Error("audit record failed", "error", err, "fingerprint", record.Fingerprint)
+			}
+		}
+	}
+}
+
+// auditResponse records the tokens of a token response in the audit sinks.
+func (s *Service) auditResponse(ctx context.Context, tokenReq TokenRequest, route string, response TokenResponse) {
+	if response.Token != "" {
+		s.auditTokens(ctx, tokenReq, route, response.Token)
+	}
+	for _, serviceReq := range tokenReq.serviceRequests() {
+		if token, ok := response.Tokens[serviceReq.TokenType]; ok {
+			s.auditTokens(ctx, serviceReq, route, token)
+		}
+	}
+}
+
+// newAuditRecord describes an issued token, with the services, privileges and expiration read from the token.
+func newAuditRecord(ctx context.Context, tokenReq TokenRequest, route, token string) (AuditRecord, error) {
+	_, content, err := decodeToken(token)
+	if err != nil {
+		return AuditRecord{}, err
+	}
+	info, err := unpackTokenContent(content)
+	if err != nil {
+		return AuditRecord{}, err
+	}
+
+	record := AuditRecord{
+		Time:        time.Now().UTC(),
+		RequestID:   RequestIDFromContext(ctx),
+		ClientIP:    clientIPFromContext(ctx),
+		Route:       route,
+		Project:     tokenReq.Project,
+		Channel:     tokenReq.Channel,
+		Uid:         tokenReq.Uid,
+		Privileges:  []string{},
+		Expire:      info.Expire,
+		ExpiresAt:   time.Unix(int64(info.ExpiresAt), 0).UTC(),
+		Fingerprint: TokenFingerprint(token),
+	}
+	if principal, ok := PrincipalFromContext(ctx); ok {
+		record.Principal = principal.String()
+	}
+	serviceTypes := make([]string, 0, len(info.Services))
+	for _, service := range info.Services {
+		serviceTypes = append(serviceTypes, service.Type)
+		if service.Type == "rtc" {
+			record.Role = requestedRole(tokenReq)
+		}
+		for _, privilege := range service.Privileges {
+			record.Privileges = append(record.Privileges, service.Type+"."+privilege.Name)
+		}
+	}
+	record.TokenType = strings.Join(serviceTypes, "+")
+	return record, nil
+}
+
+// auditQuerier is implemented by the audit sinks that can be searched, such as SQLiteAuditSink.
+type auditQuerier interface {
+	Query(ctx context.Context, query AuditQuery) (*AuditPage, error)
+}
+
+// errAuditUnavailable is returned for audit searches when no audit sink can be searched.
+var errAuditUnavailable = &APIError{
+	Status: http.StatusNotFound, Code: CodeAuditUnavailable, Message: "not found: no sqlite audit sink is configured",
+}
+
+// QueryAudit searches the records of the issued tokens in the first audit sink that can be searched, such as
+// the "sqlite" sink, most recent first.
+//
+// Parameters:
+//   - ctx: context.Context - The context of the search.
+//   - query: AuditQuery - The filters and the page to return.
+//
+// Returns:
+//   - *AuditPage: The matching records, and the cursor of the next page if there are more.
+//   - error: An APIError if the query is invalid or no audit sink can be searched, or the error of the sink.
+//
+// Example usage:
+//
+//	page, err := service.QueryAudit(ctx, service.AuditQuery{Channel: "room", TokenType: "rtc"})
+//	for page != nil && err == nil {
+//	    export(page.Records)
+//	    if page.NextCursor == "" {
+//	        break
+//	    }
+//	    page, err = service.QueryAudit(ctx, service.AuditQuery{Channel: "room", TokenType: "rtc", Cursor: page.NextCursor})
+//	}
+func (s *Service) QueryAudit(ctx context.Context, query AuditQuery) (*AuditPage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.queryAudit(ctx, query)
+}
+
+// queryAudit validates the query and searches the audit sink. The caller must hold mu for reading.
+func (s *Service) queryAudit(ctx context.Context, query AuditQuery) (*AuditPage, error) {
+	if query.Limit < 0 || query.Limit > maxAuditPageSize {
+		return nil, badRequest(CodeInvalidQuery, "limit", "invalid: limit must be between 1 and %d", maxAuditPageSize)
+	}
+	switch query.TokenType {
+	case "", "rtc", "rtm", "chat":
+	default:
+		return nil, badRequest(CodeInvalidQuery, "type", "invalid: type must be rtc, rtm or chat, got %q", query.TokenType)
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return nil, badRequest(CodeInvalidQuery, "to", "invalid: to must be after from")
+	}
+	for _, sink := range s.auditSinks {
+		if querier, ok := sink.(auditQuerier); ok {
+			return querier.Query(ctx, query)
+		}
+	}
+	return nil, errAuditUnavailable
+}
+
+// auditCSVHeader holds the columns of the CSV export of the audit records.
+var auditCSVHeader = []string{
+	"time", "requestId", "principal", "clientIp", "route", "project", "tokenType", "channel", "uid", "role",
+	"privileges", "expire", "expiresAt", "fingerprint",
+}
+
+// getAudit responds with a page of the audit records matching the query parameters, as JSON or, with
+// format=csv or an Accept: text/csv header, as a CSV export. The cursor of the next page is also sent in
+// the X-Next-Cursor header.
+func (s *Service) getAudit(c *gin.Context) {
+	query := AuditQuery{
+		Channel:   c.Query("channel"),
+		Uid:       c.Query("uid"),
+		TokenType: c.Query("type"),
+		Cursor:    c.Query("cursor"),
+	}
+	for field, value := range map[string]*time.Time{"from": &query.From, "to": &query.To} {
+		if param := c.Query(field); param != "" {
+			parsed, err := time.Parse(time.RFC3339, param)
+			if err != nil {
+				abortWithError(c, badRequest(CodeInvalidQuery, field, "invalid: %s must be an RFC 3339 time, got %q", field, param))
+				return
+			}
+			*value = parsed
+		}
+	}
+	if param := c.Query("limit"); param != "" {
+		limit, err := strconv.Atoi(param)
+		if err != nil || limit < 1 {
+			abortWithError(c, badRequest(CodeInvalidQuery, "limit", "invalid: limit must be between 1 and %d", maxAuditPageSize))
+			return
+		}
+		query.Limit = limit
+	}
+	format := c.Query("format")
+	if format == "" && strings.Contains(c.GetHeader("Accept"), "text/csv") {
+		format = "csv"
+	}
+	if format != "" && format != "json" && format != "csv" {
+		abortWithError(c, badRequest(CodeInvalidQuery, "format", "invalid: format must be json or csv, got %q", format))
+		return
+	}
+
+	page, err := s.queryAudit(c.Request.Context(), query)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	if page.NextCursor != "" {
+		c.Header("X-Next-Cursor", page.NextCursor)
+	}
+	if format != "csv" {
+		c.JSON(http.StatusOK, page)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="audit.csv"`)
+	c.Status(http.StatusOK)
+	w := csv.NewWriter(c.Writer)
+	w.Write(auditCSVHeader)
+	for _, record := range page.Records {
+		w.Write([]string{
+			record.Time.Format(time.RFC3339Nano), csvCell(record.RequestID), csvCell(record.Principal), record.ClientIP,
+			record.Route, record.Project, record.TokenType, csvCell(record.Channel), csvCell(record.Uid), record.Role,
+			strings.Join(record.Privileges, " "),
+			strconv.FormatUint(uint64(record.Expire), 10), record.ExpiresAt.Format(time.RFC3339), record.Fingerprint,
+		})
+	}
+	w.Flush()
+}
+
+// csvCell escapes the values chosen by the callers, such as channel names and uids, that spreadsheets would
+// evaluate as formulas.
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
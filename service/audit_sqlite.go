@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	// Registers the "sqlite" database/sql driver, implemented in pure Go
+	_ "modernc.org/sqlite"
+)
+
+// auditSchema creates the audit table of the SQLite sink, indexed for the searches by channel, uid and time.
+// Times are stored as unix nanoseconds (time) and seconds (expires_at), so they sort and compare as integers.
+const auditSchema = `
+CREATE TABLE IF NOT EXISTS audit (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	time        INTEGER NOT NULL,
+	request_id  TEXT NOT NULL,
+	principal   TEXT NOT NULL,
+	client_ip   TEXT NOT NULL,
+	route       TEXT NOT NULL,
+	project     TEXT NOT NULL,
+	token_type  TEXT NOT NULL,
+	channel     TEXT NOT NULL,
+	uid         TEXT NOT NULL,
+	role        TEXT NOT NULL,
+	privileges  TEXT NOT NULL,
+	expire      INTEGER NOT NULL,
+	expires_at  INTEGER NOT NULL,
+	fingerprint TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS audit_time ON audit (time);
+CREATE INDEX IF NOT EXISTS audit_channel ON audit (channel, time);
+CREATE INDEX IF NOT EXISTS audit_uid ON audit (uid, time);
+CREATE INDEX IF NOT EXISTS audit_fingerprint ON audit (fingerprint);
+`
+
+const (
+	// defaultAuditPageSize is the number of records of an audit page when no limit is set.
+	defaultAuditPageSize = 100
+
+	// maxAuditPageSize is the largest number of records of an audit page.
+	maxAuditPageSize = 1000
+)
+
+// AuditQuery selects the audit records of a search. Empty fields match every record.
+type AuditQuery struct {
+	Channel   string    // The channel name
+	Uid       string    // The user ID or account
+	TokenType string    // A service of the tokens: "rtc", "rtm" or "chat", matching multi-service tokens too
+	From      time.Time // The records issued at or after From
+	To        time.Time // The records issued before To
+	Limit     int       // The number of records of the page, defaultAuditPageSize if 0
+	Cursor    string    // The NextCursor of the previous page, empty for the first page
+}
+
+// AuditPage is a page of audit records, most recent first.
+type AuditPage struct {
+	Records    []AuditRecord `json:"records"`              // The records of the page
+	NextCursor string        `json:"nextCursor,omitempty"` // The cursor of the next page, empty on the last page
+}
+
+// SQLiteAuditSink stores the audit records in a SQLite database, where they can be searched.
+type SQLiteAuditSink struct {
+	db *sql.DB
+}
+
+// NewSQLiteAuditSink opens, or creates, the SQLite database of a sink. The database is written in WAL mode,
+// so searches do not block the records of the issued tokens.
+//
+// Parameters:
+//   - path: string - The database file.
+//
+// Returns:
+//   - *SQLiteAuditSink: The sink.
+//   - error: An error if the database can not be opened or its schema created.
+//
+// Example usage:
+//
+//	sink, err := service.NewSQLiteAuditSink("/var/lib/agora/audit.db")
+func NewSQLiteAuditSink(path string) (*SQLiteAuditSink, error) {
+	dsn := (&url.URL{
+		Scheme:   "file",
+		Opaque:   path,
+		RawQuery: "_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)",
+	}).String()
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit database: %w", err)
+	}
+	if _, err := db.Exec(auditSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create audit database: %w", err)
+	}
+	return &SQLiteAuditSink{db: db}, nil
+}
+
+// Record implements AuditSink.
+func (q *SQLiteAuditSink) Record(ctx context.Context, record AuditRecord) error {
+	privileges, err := json.Marshal(record.Privileges)
+	if err != nil {
+		return err
+	}
+	_, err = q.db.ExecContext(ctx, `
+		INSERT INTO audit (time, request_id, principal, client_ip, route, project, token_type, channel, uid, role,
+			privileges, expire, expires_at, fingerprint)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		record.Time.UnixNano(), record.RequestID, record.Principal, record.ClientIP, record.Route, record.Project,
+		record.TokenType, record.Channel, record.Uid, record.Role, string(privileges), record.Expire,
+		record.ExpiresAt.Unix(), record.Fingerprint,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store audit record: %w", err)
+	}
+	return nil
+}
+
+// Close implements AuditSink.
+func (q *SQLiteAuditSink) Close() error {
+	return q.db.Close()
+}
+
+// Query searches the audit records, most recent first.
+//
+// Parameters:
+//   - ctx: context.Context - The context of the search.
+//   - query: AuditQuery - The filters and the page to return.
+//
+// Returns:
+//   - *AuditPage: The matching records, and the cursor of the next page if there are more.
+//   - error: An error if the cursor is malformed or the database can not be read.
+//
+// Notes:
+//   - Pages are delimited by the last record of the previous page rather than by an offset, so records
+//     issued while paginating neither shift nor repeat the next pages.
+//
+// Example usage:
+//
+//	page, err := sink.Query(ctx, service.AuditQuery{Channel: "room", From: time.Now().Add(-24 * time.Hour)})
+func (q *SQLiteAuditSink) Query(ctx context.Context, query AuditQuery) (*AuditPage, error) {
+	var conditions []string
+	var args []any
+	where := func(condition string, arg any) {
+		conditions = append(conditions, condition)
+		args = append(args, arg)
+	}
+	if query.Channel != "" {
+		where("channel = ?", query.Channel)
+	}
+	if query.Uid != "" {
+		where("uid = ?", query.Uid)
+	}
+	if query.TokenType != "" {
+		// Multi-service tokens hold their services joined with "+"
+		where("'+' || token_type || '+' LIKE ?", "%+"+query.TokenType+"+%")
+	}
+	if !query.From.IsZero() {
+		where("time >= ?", query.From.UnixNano())
+	}
+	if !query.To.IsZero() {
+		where("time < ?", query.To.UnixNano())
+	}
+	if query.Cursor != "" {
+		cursorTime, cursorID, err := parseAuditCursor(query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, "(time < ? OR (time = ? AND id < ?))")
+		args = append(args, cursorTime, cursorTime, cursorID)
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultAuditPageSize
+	}
+
+	statement := `
+		SELECT id, time, request_id, principal, client_ip, route, project, token_type, channel, uid, role,
+			privileges, expire, expires_at, fingerprint
+		FROM audit`
+	if len(conditions) > 0 {
+		statement += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	// One more record than the limit tells whether there is a next page
+	statement += " ORDER BY time DESC, id DESC LIMIT ?"
+	rows, err := q.db.QueryContext(ctx, statement, append(args, limit+1)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit records: %w", err)
+	}
+	defer rows.Close()
+
+	page := &AuditPage{Records: []AuditRecord{}}
+	var lastTime, lastID int64
+	for rows.Next() {
+		if len(page.Records) == limit {
+			page.NextCursor = fmt.Sprintf("%d.%d", lastTime, lastID)
+			break
+		}
+		var record AuditRecord
+		var privileges string
+		var expiresAt int64
+		err := rows.Scan(&lastID, &lastTime, &record.RequestID, &record.Principal, &record.ClientIP, &record.Route,
+			&record.Project, &record.TokenType, &record.Channel, &record.Uid, &record.Role, &privileges,
+			&record.Expire, &expiresAt, &record.Fingerprint)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit record: %w", err)
+		}
+		if err := json.Unmarshal([]byte(privileges), &record.Privileges); err != nil {
+			return nil, fmt.Errorf("failed to read audit record: %w", err)
+		}
+		record.Time = time.Unix(0, lastTime).UTC()
+		record.ExpiresAt = time.Unix(expiresAt, 0).UTC()
+		page.Records = append(page.Records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query audit records: %w", err)
+	}
+	return page, nil
+}
+
+// parseAuditCursor returns the time and ID of the last record of a page, as encoded in its NextCursor.
+func parseAuditCursor(cursor string) (int64, int64, error) {
+	errCursor := badRequest(CodeInvalidQuery, "cursor", "invalid: malformed cursor")
+	timePart, idPart, found := strings.Cut(cursor, ".")
+	if !found {
+		return 0, 0, errCursor
+	}
+	cursorTime, err := strconv.ParseInt(timePart, 10, 64)
+	if err != nil {
+		return 0, 0, errCursor
+	}
+	cursorID, err := strconv.ParseInt(idPart, 10, 64)
+	if err != nil {
+		return 0, 0, errCursor
+	}
+	return cursorTime, cursorID, nil
+}
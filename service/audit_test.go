@@ -0,0 +1,286 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileAuditSinkRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := NewFileAuditSink(path, 300, 2)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer sink.Close()
+
+	for i := 0; i < 10; i++ {
+		record := AuditRecord{Time: time.Unix(int64(i), 0).UTC(), Route: routePost, TokenType: "rtc", Privileges: []string{}}
+		assert.NoError(t, sink.Record(context.Background(), record))
+	}
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		stat, err := os.Stat(name)
+		if assert.NoError(t, err, name) {
+			assert.LessOrEqual(t, stat.Size(), int64(300), name)
+			assert.Equal(t, os.FileMode(0o600), stat.Mode().Perm(), name)
+		}
+	}
+	// Only maxBackups rotated files are kept
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+
+	// The latest record is in the current file, the older ones in the backups
+	content, _ := os.ReadFile(path)
+	assert.Contains(t, string(content), `"time":"1970-01-01T00:00:09Z"`)
+	content, _ = os.ReadFile(path + ".1")
+	assert.NotContains(t, string(content), `"time":"1970-01-01T00:00:09Z"`)
+
+	assert.NoError(t, sink.Close())
+	assert.Error(t, sink.Record(context.Background(), AuditRecord{}))
+}
+
+func TestSQLiteAuditSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.db")
+	sink, err := NewSQLiteAuditSink(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	record := AuditRecord{
+		Time: time.Now().UTC(), Route: routePost, TokenType: "rtc", Channel: "room", Uid: "42", Role: "publisher",
+		Privileges: []string{"rtc.joinChannel"}, Expire: 3600, ExpiresAt: time.Now().Add(time.Hour), Fingerprint: "abc",
+	}
+	assert.NoError(t, sink.Record(context.Background(), record))
+	assert.NoError(t, sink.Close())
+
+	// The records persist across reopening the database
+	sink, err = NewSQLiteAuditSink(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer sink.Close()
+	var count int
+	var privileges string
+	row := sink.db.QueryRow(`SELECT COUNT(*), MAX(privileges) FROM audit WHERE channel = ? AND uid = ?`, "room", "42")
+	if assert.NoError(t, row.Scan(&count, &privileges)) {
+		assert.Equal(t, 1, count)
+		assert.Equal(t, `["rtc.joinChannel"]`, privileges)
+	}
+}
+
+func TestAuditTokensCancelledRequest(t *testing.T) {
+	sink, err := NewSQLiteAuditSink(filepath.Join(t.TempDir(), "audit.db"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer sink.Close()
+	token, err := testService.GenRtmToken(TokenRequest{Uid: "user"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// The token was issued, so it is recorded even though the client went away
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	service := &Service{logger: slog.Default(), auditSinks: []AuditSink{sink}}
+	service.auditTokens(ctx, TokenRequest{TokenType: "rtm", Uid: "user"}, routePost, token)
+	page, err := sink.Query(context.Background(), AuditQuery{Uid: "user"})
+	if assert.NoError(t, err) && assert.Len(t, page.Records, 1) {
+		assert.Equal(t, TokenFingerprint(token), page.Records[0].Fingerprint)
+	}
+}
+
+func TestAuditTokens(t *testing.T) {
+	var records bytes.Buffer
+	auditFile := filepath.Join(t.TempDir(), "audit.jsonl")
+	config := DefaultConfig()
+	config.Audit.Sinks = []string{auditSinkFile}
+	config.Audit.File = auditFile
+	s, err := New(Options{
+		AppID: testAppID, AppCertificate: testAppCertificate,
+		Projects:   map[string]*Project{"staging": {AppID: testAppID, AppCertificate: testAppCertificate}},
+		AuditSinks: []AuditSink{NewJSONAuditSink(&records)},
+		Config:     config,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	serve := func(method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set(requestIDHeader, "audit-request")
+		resp := httptest.NewRecorder()
+		s.Handler().ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := serve(http.MethodPost, "/getToken", `{"tokenType": "rtc", "channel": "room", "uid": "42", "role": "subscriber"}`)
+	if !assert.Equal(t, http.StatusOK, resp.Code, resp.Body.String()) {
+		return
+	}
+	var response TokenResponse
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+
+	var record AuditRecord
+	if assert.NoError(t, json.Unmarshal(records.Bytes(), &record)) {
+		assert.Equal(t, "audit-request", record.RequestID)
+		assert.Equal(t, routePost, record.Route)
+		assert.Equal(t, "rtc", record.TokenType)
+		assert.Equal(t, "room", record.Channel)
+		assert.Equal(t, "42", record.Uid)
+		assert.Equal(t, "subscriber", record.Role)
+		assert.Equal(t, []string{"rtc.joinChannel"}, record.Privileges)
+		assert.NotEmpty(t, record.ClientIP)
+		assert.Equal(t, TokenFingerprint(response.Token), record.Fingerprint)
+		assert.WithinDuration(t, record.Time.Add(time.Duration(record.Expire)*time.Second), record.ExpiresAt, time.Second)
+	}
+	// The token itself is never recorded
+	assert.NotContains(t, records.String(), response.Token)
+
+	// Every token of the legacy and multi-service endpoints is recorded, in every sink
+	records.Reset()
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/rte/room/publisher/uid/42/", "").Code)
+	assert.Equal(t, 2, strings.Count(records.String(), "\n"))
+	assert.Contains(t, records.String(), `"route":"legacy"`)
+	records.Reset()
+	resp = serve(http.MethodPost, "/getToken", `{"services": ["rtc", "rtm"], "channel": "room", "uid": "42"}`)
+	assert.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.Contains(t, records.String(), `"tokenType":"rtc+rtm"`)
+
+	// Tokens issued through the project routes are audited with the route project
+	records.Reset()
+	resp = serve(http.MethodPost, "/projects/staging/getToken", `{"tokenType": "rtm", "uid": "42"}`)
+	assert.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	if assert.NoError(t, json.Unmarshal(records.Bytes(), &record)) {
+		assert.Equal(t, "staging", record.Project)
+		assert.Equal(t, uint32(3600), record.Expire)
+	}
+
+	// Failed requests are not audited
+	records.Reset()
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/getToken", `{"tokenType": "rtc"}`).Code)
+	assert.Empty(t, records.String())
+
+	assert.NoError(t, s.Shutdown(context.Background()))
+	content, err := os.ReadFile(auditFile)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 5, strings.Count(string(content), "\n"))
+	}
+}
+
+func TestAuditConfigValidate(t *testing.T) {
+	config := DefaultConfig()
+	config.AppID, config.AppCertificate = testAppID, testAppCertificate
+	config.Audit = AuditConfig{Sinks: []string{"file", "sqlite", "syslog"}, MaxSizeMB: 0, MaxBackups: -1}
+	err := config.Validate()
+	if assert.Error(t, err) {
+		for _, field := range []string{"audit.file:", "audit.database:", "audit.sinks: expected", "audit.maxSizeMb:", "audit.maxBackups:"} {
+			assert.Contains(t, err.Error(), field)
+		}
+	}
+}
+
+func TestQueryAudit(t *testing.T) {
+	config := DefaultConfig()
+	config.Auth.APIKeys = map[string]string{"backend": "backend-key", "ops": "ops-key"}
+	config.Admin.Subjects = []string{"apiKey:ops"}
+	config.Audit.Sinks = []string{auditSinkSQLite}
+	config.Audit.Database = filepath.Join(t.TempDir(), "audit.db")
+	s, err := New(Options{AppID: testAppID, AppCertificate: testAppCertificate, Config: config})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer s.Shutdown(context.Background())
+
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i, record := range []AuditRecord{
+		{Channel: "room", Uid: "1", TokenType: "rtc"},
+		{Channel: "room", Uid: "2", TokenType: "rtc+rtm"},
+		{Channel: "lobby", Uid: "1", TokenType: "rtm"},
+		{Channel: "room", Uid: "=HYPERLINK(\"x\")", TokenType: "rtc"},
+	} {
+		record.Time = start.Add(time.Duration(i) * time.Minute)
+		record.Route, record.Privileges, record.Fingerprint = routePost, []string{"rtc.joinChannel"}, strconv.Itoa(i)
+		assert.NoError(t, s.ownedAuditSinks[0].Record(context.Background(), record))
+	}
+
+	serve := func(url, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		req.Header.Set("X-API-Key", key)
+		resp := httptest.NewRecorder()
+		s.Handler().ServeHTTP(resp, req)
+		return resp
+	}
+	fingerprints := func(resp *httptest.ResponseRecorder) []string {
+		var page AuditPage
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &page), resp.Body.String())
+		fingerprints := []string{}
+		for _, record := range page.Records {
+			fingerprints = append(fingerprints, record.Fingerprint)
+		}
+		return fingerprints
+	}
+
+	assert.Equal(t, http.StatusForbidden, serve("/admin/audit", "backend-key").Code)
+
+	// Most recent first, with the filters combined
+	resp := serve("/admin/audit?channel=room&type=rtc", "ops-key")
+	assert.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.Equal(t, []string{"3", "1", "0"}, fingerprints(resp))
+	assert.Equal(t, []string{"1", "0"}, fingerprints(serve("/admin/audit?channel=room&type=rtc&to=2024-01-01T12:03:00Z", "ops-key")))
+	assert.Equal(t, []string{"2", "0"}, fingerprints(serve("/admin/audit?uid=1", "ops-key")))
+	assert.Equal(t, []string{"2", "1"}, fingerprints(serve("/admin/audit?type=rtm", "ops-key")))
+	assert.Equal(t, []string{"3", "2"}, fingerprints(serve("/admin/audit?from=2024-01-01T12:02:00Z", "ops-key")))
+
+	// Pages follow each other without repeating records
+	resp = serve("/admin/audit?limit=3", "ops-key")
+	assert.Equal(t, []string{"3", "2", "1"}, fingerprints(resp))
+	cursor := resp.Header().Get("X-Next-Cursor")
+	if assert.NotEmpty(t, cursor) {
+		resp = serve("/admin/audit?limit=3&cursor="+cursor, "ops-key")
+		assert.Equal(t, []string{"0"}, fingerprints(resp))
+		assert.Empty(t, resp.Header().Get("X-Next-Cursor"))
+	}
+
+	resp = serve("/admin/audit?channel=room&format=csv", "ops-key")
+	if assert.Equal(t, http.StatusOK, resp.Code, resp.Body.String()) {
+		assert.Equal(t, "text/csv; charset=utf-8", resp.Header().Get("Content-Type"))
+		lines := strings.Split(strings.TrimSpace(resp.Body.String()), "\n")
+		if assert.Len(t, lines, 4) {
+			assert.Equal(t, strings.Join(auditCSVHeader, ","), lines[0])
+			// Formulas are not evaluated by spreadsheets
+			assert.Contains(t, lines[1], `"'=HYPERLINK(""x"")"`)
+		}
+	}
+
+	for url, field := range map[string]string{
+		"/admin/audit?limit=0":        "limit",
+		"/admin/audit?limit=1001":     "limit",
+		"/admin/audit?type=video":     "type",
+		"/admin/audit?from=yesterday": "from",
+		"/admin/audit?cursor=next":    "cursor",
+		"/admin/audit?format=xml":     "format",
+		"/admin/audit?from=2024-01-02T00:00:00Z&to=2024-01-01T00:00:00Z": "to",
+	} {
+		response := NewErrorResponse(&APIError{})
+		resp := serve(url, "ops-key")
+		assert.Equal(t, http.StatusBadRequest, resp.Code, url)
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+		assert.Equal(t, CodeInvalidQuery, response.Code, url)
+		assert.Equal(t, field, response.Field, url)
+	}
+
+	// The search requires a sqlite sink
+	_, err = testService.QueryAudit(context.Background(), AuditQuery{})
+	assert.Equal(t, CodeAuditUnavailable, NewErrorResponse(err).Code)
+}
@@ -0,0 +1,397 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Principal is the authenticated caller of the token endpoints.
+type Principal struct {
+	// Subject identifies the caller: the API key name, the HMAC key ID, the JWT "sub" claim or the common name
+	// of the client certificate.
+	Subject string
+
+	// Method is the authentication method that produced the principal: "apiKey", "hmac", "jwt" or "mtls".
+	Method string
+
+	// Claims holds the verified JWT claims, or the subject attributes of the client certificate.
+	// It is empty for the other methods.
+	Claims map[string]interface{}
+}
+
+// principalMethods are the authentication methods a Principal can come from.
+var principalMethods = []string{"apiKey", "hmac", "jwt", "mtls"}
+
+// String returns the principal qualified with its method, such as "apiKey:backend", as it is audited and
+// matched against the admin subjects.
+func (p *Principal) String() string {
+	return p.Method + ":" + p.Subject
+}
+
+// Authenticator verifies the credentials carried by a request.
+type Authenticator interface {
+	// Authenticate returns the principal of the request. It returns errNoCredentials when the request
+	// carries no credentials for this authenticator, and any other error when the credentials are invalid.
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// errNoCredentials is returned by an Authenticator when the request carries none of its credentials.
+var errNoCredentials = errors.New("missing credentials")
+
+// principalContextKey is the request context key holding the authenticated Principal.
+type principalContextKey struct{}
+
+// PrincipalFromContext returns the authenticated principal stored in the request context by the AuthMiddleware.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey{}).(*Principal)
+	return principal, ok
+}
+
+// AuthMiddleware authenticates requests with the configured authenticators.
+// The first authenticator that recognizes the request credentials decides the outcome, and the
+// resulting principal is stored in both the request context and the gin context under "principal".
+// Requests are let through unauthenticated when no authenticator is configured.
+func (s *Service) AuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(s.authenticators) == 0 {
+			c.Next()
+			return
+		}
+
+		// Signed requests are read in full before their signature is checked
+		maxSize := s.maxRequestBodySize()
+		if c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize)
+		}
+		principal, err := s.authenticate(c.Request)
+		if tooLarge := bodyTooLarge(err, maxSize); tooLarge != nil {
+			abortWithError(c, tooLarge)
+			return
+		}
+		if err != nil {
+			abortWithError(c, &APIError{
+				Status: http.StatusUnauthorized, Code: CodeUnauthenticated, Message: "Unauthorized: " + err.Error(),
+			})
+			return
+		}
+		c.Set("principal", principal)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), principalContextKey{}, principal))
+		c.Next()
+	}
+}
+
+// authenticate returns the principal of the request, as decided by the first authenticator that recognizes
+// the request credentials.
+func (s *Service) authenticate(r *http.Request) (*Principal, error) {
+	for _, authenticator := range s.authenticators {
+		principal, err := authenticator.Authenticate(r)
+		if errors.Is(err, errNoCredentials) {
+			continue
+		}
+		return principal, err
+	}
+	return nil, errNoCredentials
+}
+
+// loadAuthenticators builds the authenticators enabled by the configuration:
+//   - apiKeys (AUTH_API_KEYS): name:key pairs, sent in the X-API-Key header.
+//   - hmacKeys (AUTH_HMAC_KEYS): keyId:secret pairs, used to sign requests.
+//   - jwksFile (AUTH_JWKS_FILE): a JWKS file with the keys bearer JWTs are verified against,
+//     optionally restricted with jwtIssuer (AUTH_JWT_ISSUER) and jwtAudience (AUTH_JWT_AUDIENCE).
+func loadAuthenticators(config AuthConfig) ([]Authenticator, error) {
+	var authenticators []Authenticator
+
+	if len(config.APIKeys) > 0 {
+		authenticators = append(authenticators, NewAPIKeyAuthenticator(config.APIKeys))
+	}
+
+	if len(config.HMACKeys) > 0 {
+		authenticators = append(authenticators, NewHMACAuthenticator(config.HMACKeys))
+	}
+
+	if config.JWKSFile != "" {
+		jwtAuthenticator, err := NewJWTAuthenticator(config.JWKSFile, config.JWTIssuer, config.JWTAudience)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, jwtAuthenticator)
+	}
+
+	return authenticators, nil
+}
+
+// parseKeyPairs parses comma separated name:value pairs into a map.
+func parseKeyPairs(pairs string) (map[string]string, error) {
+	keys := make(map[string]string)
+	for _, pair := range strings.Split(pairs, ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(pair), ":")
+		if !found || name == "" || value == "" {
+			return nil, fmt.Errorf("expected name:value, got %q", pair)
+		}
+		keys[name] = value
+	}
+	return keys, nil
+}
+
+// APIKeyAuthenticator authenticates requests carrying a static API key in the X-API-Key header.
+type APIKeyAuthenticator struct {
+	// keys maps each API key name to its key.
+	keys map[string]string
+}
+
+// NewAPIKeyAuthenticator returns an APIKeyAuthenticator accepting the given keys, indexed by name.
+func NewAPIKeyAuthenticator(keys map[string]string) *APIKeyAuthenticator {
+	return &APIKeyAuthenticator{keys: keys}
+}
+
+// Authenticate implements Authenticator.
+func (a *APIKeyAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	apiKey := r.Header.Get("X-API-Key")
+	if apiKey == "" {
+		return nil, errNoCredentials
+	}
+	for name, key := range a.keys {
+		if subtle.ConstantTimeCompare([]byte(apiKey), []byte(key)) == 1 {
+			return &Principal{Subject: name, Method: "apiKey"}, nil
+		}
+	}
+	return nil, errors.New("invalid API key")
+}
+
+// hmacMaxClockSkew is how far the X-Auth-Timestamp of a signed request may be from the server time.
+const hmacMaxClockSkew = 5 * time.Minute
+
+// HMACAuthenticator authenticates requests signed with a shared secret.
+//
+// Signed requests carry the X-Auth-Key-Id, X-Auth-Timestamp (unix seconds) and X-Auth-Signature headers.
+// The signature is the hex encoded HMAC-SHA256, keyed with the secret, of:
+//
+//	METHOD + "\n" + REQUEST_URI + "\n" + TIMESTAMP + "\n" + hex(SHA256(body))
+type HMACAuthenticator struct {
+	// secrets maps each key ID to its shared secret.
+	secrets map[string]string
+}
+
+// NewHMACAuthenticator returns an HMACAuthenticator accepting the given secrets, indexed by key ID.
+func NewHMACAuthenticator(secrets map[string]string) *HMACAuthenticator {
+	return &HMACAuthenticator{secrets: secrets}
+}
+
+// Authenticate implements Authenticator.
+func (a *HMACAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	signature := r.Header.Get("X-Auth-Signature")
+	if signature == "" {
+		return nil, errNoCredentials
+	}
+	keyID := r.Header.Get("X-Auth-Key-Id")
+	secret, exists := a.secrets[keyID]
+	if !exists {
+		return nil, errors.New("unknown HMAC key ID")
+	}
+
+	timestamp := r.Header.Get("X-Auth-Timestamp")
+	unixTime, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid HMAC timestamp")
+	}
+	if skew := time.Since(time.Unix(unixTime, 0)); skew > hmacMaxClockSkew || skew < -hmacMaxClockSkew {
+		return nil, errors.New("HMAC timestamp outside of the allowed window")
+	}
+
+	var body []byte
+	if r.Body != nil {
+		if body, err = io.ReadAll(r.Body); err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	expected := SignRequest(secret, r.Method, r.URL.RequestURI(), timestamp, body)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return nil, errors.New("invalid HMAC signature")
+	}
+	return &Principal{Subject: keyID, Method: "hmac"}, nil
+}
+
+// SignRequest returns the HMACAuthenticator signature of a request, for use by clients.
+func SignRequest(secret, method, requestURI, timestamp string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(method + "\n" + requestURI + "\n" + timestamp + "\n" + hex.EncodeToString(bodyHash[:])))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// JWTAuthenticator authenticates requests carrying a bearer JWT signed by one of the keys of a local JWKS file.
+type JWTAuthenticator struct {
+	// keys maps each key ID to its public key.
+	keys map[string]interface{}
+
+	// parser validates the token signature algorithm, expiry, issuer and audience.
+	parser *jwt.Parser
+}
+
+// NewJWTAuthenticator loads the JWKS file and returns a JWTAuthenticator verifying tokens against its keys.
+// When set, the issuer and audience are required to match the "iss" and "aud" claims.
+func NewJWTAuthenticator(jwksFile, issuer, audience string) (*JWTAuthenticator, error) {
+	content, err := os.ReadFile(jwksFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS file: %s", err)
+	}
+	keys, err := parseJWKS(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS file %s: %s", jwksFile, err)
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	}
+	if issuer != "" {
+		options = append(options, jwt.WithIssuer(issuer))
+	}
+	if audience != "" {
+		options = append(options, jwt.WithAudience(audience))
+	}
+	return &JWTAuthenticator{keys: keys, parser: jwt.NewParser(options...)}, nil
+}
+
+// Authenticate implements Authenticator.
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	scheme, tokenString, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !strings.EqualFold(scheme, "Bearer") || tokenString == "" {
+		return nil, errNoCredentials
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := a.parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, exists := a.keys[kid]
+		if !exists {
+			return nil, fmt.Errorf("unknown key ID %q", kid)
+		}
+		return key, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid bearer token: %s", err)
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return nil, errors.New("invalid bearer token: missing subject")
+	}
+	return &Principal{Subject: subject, Method: "jwt", Claims: claims}, nil
+}
+
+// jsonWebKey is a single public key of a JWKS document (RFC 7517).
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKS parses the RSA, EC and Ed25519 signing keys of a JWKS document, indexed by key ID.
+func parseJWKS(content []byte) (map[string]interface{}, error) {
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(content, &jwks); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]interface{})
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %s", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no signing keys found")
+	}
+	return keys, nil
+}
+
+// publicKey converts the JWK to its crypto public key.
+func (jwk jsonWebKey) publicKey() (interface{}, error) {
+	decode := base64.RawURLEncoding.DecodeString
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decode(jwk.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus: %s", err)
+		}
+		e, err := decode(jwk.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent: %s", err)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := decode(jwk.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x coordinate: %s", err)
+		}
+		y, err := decode(jwk.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid y coordinate: %s", err)
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return key, nil
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := decode(jwk.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 public key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+}
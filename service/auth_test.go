@@ -0,0 +1,209 @@
+package service
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeJWKS writes a JWKS file with an RSA and an EC key and returns its path.
+func writeJWKS(t *testing.T, rsaKey *rsa.PrivateKey, ecKey *ecdsa.PrivateKey) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	jwks := map[string]interface{}{
+		"keys": []map[string]string{
+			{"kid": "rsa-key", "kty": "RSA", "use": "sig", "n": encode(rsaKey.N.Bytes()), "e": encode(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{"kid": "ec-key", "kty": "EC", "crv": "P-256", "x": encode(ecKey.X.Bytes()), "y": encode(ecKey.Y.Bytes())},
+		},
+	}
+	content, _ := json.Marshal(jwks)
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(jwksFile, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return jwksFile
+}
+
+// signJWT returns a JWT with the claims, signed with the key and key ID.
+func signJWT(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("AUTH_API_KEYS", "backend:secret-api-key, ci:another-key")
+	t.Setenv("AUTH_HMAC_KEYS", "provisioner:hmac-secret")
+	t.Setenv("AUTH_JWKS_FILE", writeJWKS(t, rsaKey, ecKey))
+	t.Setenv("AUTH_JWT_ISSUER", "https://issuer.example.com")
+	service := NewService()
+
+	validClaims := jwt.MapClaims{"sub": "user-42", "iss": "https://issuer.example.com", "exp": time.Now().Add(time.Hour).Unix()}
+	expiredClaims := jwt.MapClaims{"sub": "user-42", "iss": "https://issuer.example.com", "exp": time.Now().Add(-time.Hour).Unix()}
+	wrongIssuerClaims := jwt.MapClaims{"sub": "user-42", "iss": "https://evil.example.com", "exp": time.Now().Add(time.Hour).Unix()}
+
+	body := []byte(`{"tokenType": "rtm", "uid": "user123"}`)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		code    int
+	}{
+		{"no credentials", nil, http.StatusUnauthorized},
+		{"valid API key", map[string]string{"X-API-Key": "another-key"}, http.StatusOK},
+		{"invalid API key", map[string]string{"X-API-Key": "wrong"}, http.StatusUnauthorized},
+		{"valid HMAC signature", map[string]string{
+			"X-Auth-Key-Id":    "provisioner",
+			"X-Auth-Timestamp": now,
+			"X-Auth-Signature": SignRequest("hmac-secret", http.MethodPost, "/getToken", now, body),
+		}, http.StatusOK},
+		{"HMAC signature over another body", map[string]string{
+			"X-Auth-Key-Id":    "provisioner",
+			"X-Auth-Timestamp": now,
+			"X-Auth-Signature": SignRequest("hmac-secret", http.MethodPost, "/getToken", now, []byte(`{}`)),
+		}, http.StatusUnauthorized},
+		{"stale HMAC timestamp", map[string]string{
+			"X-Auth-Key-Id":    "provisioner",
+			"X-Auth-Timestamp": stale,
+			"X-Auth-Signature": SignRequest("hmac-secret", http.MethodPost, "/getToken", stale, body),
+		}, http.StatusUnauthorized},
+		{"unknown HMAC key", map[string]string{
+			"X-Auth-Key-Id":    "unknown",
+			"X-Auth-Timestamp": now,
+			"X-Auth-Signature": SignRequest("hmac-secret", http.MethodPost, "/getToken", now, body),
+		}, http.StatusUnauthorized},
+		{"valid RS256 JWT", map[string]string{"Authorization": "Bearer " + signJWT(t, jwt.SigningMethodRS256, "rsa-key", rsaKey, validClaims)}, http.StatusOK},
+		{"valid ES256 JWT", map[string]string{"Authorization": "Bearer " + signJWT(t, jwt.SigningMethodES256, "ec-key", ecKey, validClaims)}, http.StatusOK},
+		{"expired JWT", map[string]string{"Authorization": "Bearer " + signJWT(t, jwt.SigningMethodRS256, "rsa-key", rsaKey, expiredClaims)}, http.StatusUnauthorized},
+		{"JWT from another issuer", map[string]string{"Authorization": "Bearer " + signJWT(t, jwt.SigningMethodRS256, "rsa-key", rsaKey, wrongIssuerClaims)}, http.StatusUnauthorized},
+		{"JWT signed with an unknown key", map[string]string{"Authorization": "Bearer " + signJWT(t, jwt.SigningMethodRS256, "rsa-key", otherKey, validClaims)}, http.StatusUnauthorized},
+		{"JWT signed with HS256", map[string]string{"Authorization": "Bearer " + signJWT(t, jwt.SigningMethodHS256, "rsa-key", []byte("secret"), validClaims)}, http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(http.MethodPost, "/getToken", bytes.NewBuffer(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for header, value := range test.headers {
+			req.Header.Set(header, value)
+		}
+		resp := httptest.NewRecorder()
+		service.Server.Handler.ServeHTTP(resp, req)
+		assert.Equal(t, test.code, resp.Code, test.name, resp.Body)
+	}
+
+	// Bodies are limited before being read for the signature
+	largeBody := bytes.Repeat([]byte(" "), int(service.maxRequestBodySize())+1)
+	req, _ := http.NewRequest(http.MethodPost, "/getToken", bytes.NewBuffer(largeBody))
+	req.Header.Set("X-Auth-Key-Id", "provisioner")
+	req.Header.Set("X-Auth-Timestamp", now)
+	req.Header.Set("X-Auth-Signature", SignRequest("hmac-secret", http.MethodPost, "/getToken", now, largeBody))
+	resp := httptest.NewRecorder()
+	service.Server.Handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.Code)
+	assert.Contains(t, resp.Body.String(), string(CodeBodyTooLarge))
+
+	// Legacy routes are protected too, while /ping is not
+	req, _ = http.NewRequest(http.MethodGet, "/rtm/username/", nil)
+	resp = httptest.NewRecorder()
+	service.Server.Handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	req, _ = http.NewRequest(http.MethodGet, "/ping", nil)
+	resp = httptest.NewRecorder()
+	service.Server.Handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestAdminSubjectMethods(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AUTH_API_KEYS", "ops:ops-key")
+	t.Setenv("AUTH_JWKS_FILE", writeJWKS(t, rsaKey, ecKey))
+	t.Setenv("ADMIN_SUBJECTS", "apiKey:ops")
+	service := NewService()
+	opsClaims := jwt.MapClaims{"sub": "ops", "exp": time.Now().Add(time.Hour).Unix()}
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		code   int
+	}{
+		{"admin API key", "X-API-Key", "ops-key", http.StatusOK},
+		{"JWT subject named after the admin API key", "Authorization", "Bearer " + signJWT(t, jwt.SigningMethodRS256, "rsa-key", rsaKey, opsClaims), http.StatusForbidden},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest(http.MethodGet, "/admin/certificates", nil)
+		req.Header.Set(test.header, test.value)
+		resp := httptest.NewRecorder()
+		service.Server.Handler.ServeHTTP(resp, req)
+		assert.Equal(t, test.code, resp.Code, test.name)
+	}
+
+	// Subjects must be qualified with their method
+	config := DefaultConfig()
+	config.AppID, config.AppCertificate = testAppID, testAppCertificate
+	config.Admin.Subjects = []string{"ops"}
+	assert.ErrorContains(t, config.Validate(), `admin.subjects: expected <method>:<subject>`)
+}
+
+func TestPrincipalFromContext(t *testing.T) {
+	service := &Service{authenticators: []Authenticator{NewAPIKeyAuthenticator(map[string]string{"backend": "secret-api-key"})}}
+
+	var principal *Principal
+	router := gin.New()
+	router.GET("/whoami", service.AuthMiddleware(), func(c *gin.Context) {
+		principal, _ = PrincipalFromContext(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/whoami", nil)
+	req.Header.Set("X-API-Key", "secret-api-key")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if assert.NotNil(t, principal) {
+		assert.Equal(t, "backend", principal.Subject)
+		assert.Equal(t, "apiKey", principal.Method)
+	}
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CertificateStatus describes the certificates of a project, identified by their fingerprints.
+type CertificateStatus struct {
+	Project     string     `json:"project"`               // The project name, empty for the default project
+	AppID       string     `json:"appId"`                 // The App ID of the project
+	Active      string     `json:"active"`                // The fingerprint of the active certificate
+	Previous    []string   `json:"previous"`              // The fingerprints of the previous certificates, most recent first
+	ActiveSince *time.Time `json:"activeSince,omitempty"` // When the active certificate was switched to, if it was since start
+}
+
+// RotateCertificateRequest is the JSON payload of POST /admin/certificates/rotate.
+type RotateCertificateRequest struct {
+	Project     string `json:"project"`     // The project name, empty for the default project
+	Certificate string `json:"certificate"` // The certificate to sign with, either new or one of the previous certificates
+}
+
+// certificateFingerprint identifies a certificate without disclosing it.
+func certificateFingerprint(certificate string) string {
+	digest := sha256.Sum256([]byte(certificate))
+	return hex.EncodeToString(digest[:8])
+}
+
+// RotateCertificate switches the active certificate of the project. The certificate previously active is kept
+// as the most recent previous certificate, so the tokens it signed can still be verified. Switching to one of
+// the previous certificates rolls a rotation back.
+//
+// Parameters:
+//   - projectName: string - The project name, empty for the default project.
+//   - certificate: string - The certificate to sign new tokens with.
+//
+// Returns:
+//   - *CertificateStatus: The certificates of the project after the switch.
+//   - error: An error if the project is unknown, or the certificate is invalid or already active.
+//
+// Notes:
+//   - The switch is logged and timestamped, but not persisted: a configuration reload applies the
+//     configured certificates again. Update the configuration to make the switch permanent.
+//
+// Example usage:
+//
+//	status, err := service.RotateCertificate("staging", "fedcba9876543210fedcba9876543210")
+func (s *Service) RotateCertificate(projectName, certificate string) (*CertificateStatus, error) {
+	return s.switchCertificate(projectName, certificate, "admin")
+}
+
+// switchCertificate makes the certificate active for the project, keeping the one it replaces as the most recent
+// previous certificate. The source of the switch is logged.
+func (s *Service) switchCertificate(projectName, certificate, source string) (*CertificateStatus, error) {
+	if !credentialPattern.MatchString(certificate) {
+		return nil, badRequest(CodeInvalidCertificate, "certificate", "invalid: certificate must be 32 hexadecimal characters")
+	}
+
+	s.credentialsMu.Lock()
+	defer s.credentialsMu.Unlock()
+	var project *Project
+	if projectName == "" {
+		if s.appID == "" {
+			return nil, errMissingProject
+		}
+		project = s.defaultProject()
+	} else if registered, exists := s.projects[projectName]; exists {
+		project = registered
+	} else {
+		return nil, fmt.Errorf("%w: %s", errUnknownProject, projectName)
+	}
+	if project.AppCertificate == certificate {
+		return nil, badRequest(CodeCertificateActive, "certificate", "invalid: certificate is already active")
+	}
+
+	previous := []string{project.AppCertificate}
+	for _, previousCertificate := range project.PreviousCertificates {
+		if previousCertificate != certificate {
+			previous = append(previous, previousCertificate)
+		}
+	}
+	// Projects are shared with in-flight requests, so they are replaced rather than modified
+	rotated := &Project{Name: projectName, AppID: project.AppID, AppCertificate: certificate, PreviousCertificates: previous}
+	if projectName == "" {
+		s.appCertificate, s.previousCertificates = certificate, previous
+	} else {
+		projects := make(map[string]*Project, len(s.projects))
+		for name, registered := range s.projects {
+			projects[name] = registered
+		}
+		projects[projectName] = rotated
+		s.projects = projects
+	}
+	s.certificateRotated(projectName, rotated, source)
+	status := s.certificateStatus(rotated)
+	return &status, nil
+}
+
+// CertificateStatuses returns the certificates of every project, default project first.
+func (s *Service) CertificateStatuses() []CertificateStatus {
+	s.credentialsMu.RLock()
+	defer s.credentialsMu.RUnlock()
+	var statuses []CertificateStatus
+	if s.appID != "" {
+		statuses = append(statuses, s.certificateStatus(s.defaultProject()))
+	}
+	for _, name := range sortedKeys(s.projects) {
+		statuses = append(statuses, s.certificateStatus(s.projects[name]))
+	}
+	return statuses
+}
+
+// certificateStatus describes the certificates of the project. The caller must hold credentialsMu.
+func (s *Service) certificateStatus(project *Project) CertificateStatus {
+	status := CertificateStatus{
+		Project:  project.Name,
+		AppID:    project.AppID,
+		Active:   certificateFingerprint(project.AppCertificate),
+		Previous: []string{},
+	}
+	for _, certificate := range project.PreviousCertificates {
+		status.Previous = append(status.Previous, certificateFingerprint(certificate))
+	}
+	if rotatedAt, rotated := s.certificatesRotatedAt[project.Name]; rotated {
+		status.ActiveSince = &rotatedAt
+	}
+	return status
+}
+
+// certificateRotated timestamps and logs the switch of the active certificate of the project.
+// The caller must hold credentialsMu for writing.
+func (s *Service) certificateRotated(projectName string, project *Project, source string) {
+	rotatedAt := time.Now().UTC()
+	if s.certificatesRotatedAt == nil {
+		s.certificatesRotatedAt = make(map[string]time.Time)
+	}
+	s.certificatesRotatedAt[projectName] = rotatedAt
+	s.log(context.Background()).Warn("certificate rotated",
+		"project", projectName,
+		"app_id", project.AppID,
+		"active", certificateFingerprint(project.AppCertificate),
+		"previous", len(project.PreviousCertificates),
+		"source", source,
+		"rotated_at", rotatedAt,
+	)
+}
+
+// getCertificates responds with the certificate fingerprints of every project.
+func (s *Service) getCertificates(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"certificates": s.CertificateStatuses()})
+}
+
+// rotateCertificate switches the active certificate of a project, as requested by an admin.
+func (s *Service) rotateCertificate(c *gin.Context) {
+	var rotateReq RotateCertificateRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&rotateReq); err != nil {
+		abortWithError(c, badRequest(CodeInvalidJSON, "", "%s", err))
+		return
+	}
+	status, err := s.RotateCertificate(rotateReq.Project, rotateReq.Certificate)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const rotatedCert = "00112233445566778899aabbccddeeff"
+
+func TestRotateCertificate(t *testing.T) {
+	service := NewService()
+	originalCert := service.appCertificate
+
+	before, err := service.GenRtcToken(TokenRequest{TokenType: "rtc", Channel: "room", Uid: "1"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	status, err := service.RotateCertificate("", rotatedCert)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assert.Equal(t, certificateFingerprint(rotatedCert), status.Active)
+	assert.Equal(t, []string{certificateFingerprint(originalCert)}, status.Previous)
+	assert.NotNil(t, status.ActiveSince)
+
+	after, err := service.GenRtcToken(TokenRequest{TokenType: "rtc", Channel: "room", Uid: "1"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// New tokens are signed with the active certificate, older ones are still accepted
+	for token, signedWith := range map[string]string{before: "previous", after: "active"} {
+		info, err := service.InspectToken(token)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		assert.True(t, info.SignatureValid)
+		assert.Equal(t, signedWith, info.SignedWith)
+	}
+
+	// Rolling back keeps the rotated certificate as a previous one
+	status, err = service.RotateCertificate("", originalCert)
+	if assert.NoError(t, err) {
+		assert.Equal(t, certificateFingerprint(originalCert), status.Active)
+		assert.Equal(t, []string{certificateFingerprint(rotatedCert)}, status.Previous)
+	}
+
+	_, err = service.RotateCertificate("", originalCert)
+	assert.Error(t, err)
+	_, err = service.RotateCertificate("", "not-a-certificate")
+	assert.Error(t, err)
+	_, err = service.RotateCertificate("unknown", rotatedCert)
+	assert.ErrorIs(t, err, errUnknownProject)
+}
+
+func TestCertificateAdminEndpoints(t *testing.T) {
+	t.Setenv("AUTH_API_KEYS", "ops:ops-key,backend:backend-key")
+	t.Setenv("ADMIN_SUBJECTS", "apiKey:ops")
+	t.Setenv("PROJECT_STAGING_APP_ID", stagingAppID)
+	t.Setenv("PROJECT_STAGING_APP_CERTIFICATE", stagingCert)
+	service := NewService()
+
+	tests := []struct {
+		method string
+		url    string
+		apiKey string
+		body   []byte
+		code   int
+	}{
+		{http.MethodGet, "/admin/certificates", "", nil, http.StatusUnauthorized},
+		{http.MethodGet, "/admin/certificates", "backend-key", nil, http.StatusForbidden},
+		{http.MethodPost, "/admin/certificates/rotate", "backend-key", []byte(`{"project": "staging", "certificate": "` + rotatedCert + `"}`), http.StatusForbidden},
+		{http.MethodPost, "/admin/certificates/rotate", "ops-key", []byte(`{"project": "staging", "certificate": "` + rotatedCert + `"}`), http.StatusOK},
+		{http.MethodPost, "/admin/certificates/rotate", "ops-key", []byte(`{"project": "staging", "certificate": "` + rotatedCert + `"}`), http.StatusBadRequest},
+		{http.MethodPost, "/admin/certificates/rotate", "ops-key", []byte(`{"project": "unknown", "certificate": "` + rotatedCert + `"}`), http.StatusNotFound},
+		{http.MethodGet, "/admin/certificates", "ops-key", nil, http.StatusOK},
+	}
+	var resp *httptest.ResponseRecorder
+	for _, test := range tests {
+		req, _ := http.NewRequest(test.method, test.url, bytes.NewBuffer(test.body))
+		req.Header.Set("X-API-Key", test.apiKey)
+		resp = httptest.NewRecorder()
+		service.Server.Handler.ServeHTTP(resp, req)
+		assert.Equal(t, test.code, resp.Code, test.url, resp.Body)
+	}
+
+	var response struct {
+		Certificates []CertificateStatus `json:"certificates"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, response.Certificates, 2) {
+		assert.Equal(t, "", response.Certificates[0].Project)
+		assert.Nil(t, response.Certificates[0].ActiveSince)
+		staging := response.Certificates[1]
+		assert.Equal(t, "staging", staging.Project)
+		assert.Equal(t, certificateFingerprint(rotatedCert), staging.Active)
+		assert.Equal(t, []string{certificateFingerprint(stagingCert)}, staging.Previous)
+		assert.NotNil(t, staging.ActiveSince)
+		assert.NotContains(t, resp.Body.String(), stagingCert)
+	}
+
+	// Tokens of the rotated project are signed with the new certificate
+	project, _ := service.lookupProject("staging")
+	assert.Equal(t, rotatedCert, project.AppCertificate)
+}
+
+func TestRotateCertificateOnReload(t *testing.T) {
+	t.Setenv("APP_ID", "")
+	t.Setenv("APP_CERTIFICATE", "")
+	configFile := writeConfig(t, "config.yaml", "appId: "+stagingAppID+"\nappCertificate: "+stagingCert+"\n")
+	t.Setenv("CONFIG_FILE", configFile)
+	service := NewService()
+
+	token, err := service.GenRtmToken(TokenRequest{TokenType: "rtm", Uid: "user"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	content := "appId: " + stagingAppID + "\nappCertificate: " + rotatedCert + "\nappPreviousCertificates: [" + stagingCert + "]\n"
+	if err := os.WriteFile(configFile, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, service.Reload())
+
+	info, err := service.InspectToken(token)
+	if assert.NoError(t, err) {
+		assert.True(t, info.SignatureValid)
+		assert.Equal(t, "previous", info.SignedWith)
+	}
+	statuses := service.CertificateStatuses()
+	if assert.Len(t, statuses, 1) {
+		assert.Equal(t, certificateFingerprint(rotatedCert), statuses[0].Active)
+		assert.NotNil(t, statuses[0].ActiveSince)
+	}
+}
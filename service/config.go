@@ -0,0 +1,634 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the typed configuration of the service. It is loaded from an optional YAML, TOML or JSON file,
+// and overridden by the environment variables.
+type Config struct {
+	// AppID and AppCertificate are the credentials of the default project (APP_ID, APP_CERTIFICATE).
+	AppID          string `yaml:"appId" toml:"appId"`
+	AppCertificate string `yaml:"appCertificate" toml:"appCertificate"`
+
+	// AppCertificateFile is a file holding the certificate of the default project, such as a mounted Docker or
+	// Kubernetes secret (APP_CERTIFICATE_FILE). It replaces AppCertificate.
+	AppCertificateFile string `yaml:"appCertificateFile" toml:"appCertificateFile"`
+
+	// AppPreviousCertificates are the certificates the default project was rotated away from, still accepted
+	// when verifying tokens (APP_PREVIOUS_CERTIFICATES, comma separated).
+	AppPreviousCertificates []string `yaml:"appPreviousCertificates" toml:"appPreviousCertificates"`
+
+	// ServerPort is the port the service listens to (SERVER_PORT or PORT). It is not reloadable.
+	ServerPort string `yaml:"serverPort" toml:"serverPort"`
+
+	// GRPCPort is the port the gRPC TokenService listens to (GRPC_PORT). gRPC is disabled when empty.
+	// It is not reloadable.
+	GRPCPort string `yaml:"grpcPort" toml:"grpcPort"`
+
+	// CORSAllowOrigin is "*" or a comma separated list of allowed origins (CORS_ALLOW_ORIGIN).
+	CORSAllowOrigin string `yaml:"corsAllowOrigin" toml:"corsAllowOrigin"`
+
+	// TrustedProxies are the IP addresses or CIDR ranges of the reverse proxies whose X-Forwarded-For header is
+	// trusted to identify the client (TRUSTED_PROXIES, comma separated). The client is the remote address of the
+	// connection when empty. It is not reloadable.
+	TrustedProxies []string `yaml:"trustedProxies" toml:"trustedProxies"`
+
+	// ProjectsFile is a JSON file of additional projects (PROJECTS_FILE).
+	ProjectsFile string `yaml:"projectsFile" toml:"projectsFile"`
+
+	// Projects are additional projects, indexed by name (PROJECT_<NAME>_APP_ID, PROJECT_<NAME>_APP_CERTIFICATE).
+	Projects map[string]*Project `yaml:"projects" toml:"projects"`
+
+	// PolicyFile is the YAML or JSON file of policy rules (POLICY_FILE).
+	PolicyFile string `yaml:"policyFile" toml:"policyFile"`
+
+	Auth       AuthConfig      `yaml:"auth" toml:"auth"`
+	RateLimits RateLimitConfig `yaml:"rateLimits" toml:"rateLimits"`
+	Batch      BatchConfig     `yaml:"batch" toml:"batch"`
+	Log        LogConfig       `yaml:"log" toml:"log"`
+	Admin      AdminConfig     `yaml:"admin" toml:"admin"`
+	TLS        TLSConfig       `yaml:"tls" toml:"tls"`
+	Shutdown   ShutdownConfig  `yaml:"shutdown" toml:"shutdown"`
+	Expire     ExpireConfig    `yaml:"expire" toml:"expire"`
+	OpenAPI    OpenAPIConfig   `yaml:"openapi" toml:"openapi"`
+	Audit      AuditConfig     `yaml:"audit" toml:"audit"`
+
+	// Vault reads the certificate of the default project from a Vault compatible key/value secrets engine.
+	Vault VaultConfig `yaml:"vault" toml:"vault"`
+
+	// SecretsRefreshInterval is how often the certificates read from files or Vault are refreshed, such as "5m"
+	// (SECRETS_REFRESH_INTERVAL). Refresh is disabled when empty.
+	SecretsRefreshInterval string `yaml:"secretsRefreshInterval" toml:"secretsRefreshInterval"`
+
+	// logger, basePath and auditSinks are the Logger, BasePath and AuditSinks of the Options of New.
+	logger     *slog.Logger
+	basePath   string
+	auditSinks []AuditSink
+}
+
+// VaultConfig configures the Vault secret provider of the default project's certificate.
+type VaultConfig struct {
+	Address   string `yaml:"address" toml:"address"`     // VAULT_ADDR
+	Token     string `yaml:"token" toml:"token"`         // VAULT_TOKEN
+	TokenFile string `yaml:"tokenFile" toml:"tokenFile"` // VAULT_TOKEN_FILE, replaces Token
+	Path      string `yaml:"path" toml:"path"`           // VAULT_SECRET_PATH, such as secret/data/agora
+	Field     string `yaml:"field" toml:"field"`         // VAULT_SECRET_FIELD, "appCertificate" by default
+}
+
+// TLSConfig configures HTTPS serving. The service serves plain HTTP when CertFile is empty.
+// The files are read again when they change, but the other TLS settings require a restart.
+type TLSConfig struct {
+	CertFile     string `yaml:"certFile" toml:"certFile"`         // TLS_CERT_FILE, the PEM certificate chain
+	KeyFile      string `yaml:"keyFile" toml:"keyFile"`           // TLS_KEY_FILE, the PEM private key
+	ClientCAFile string `yaml:"clientCaFile" toml:"clientCaFile"` // TLS_CLIENT_CA_FILE, enables mutual TLS
+	ClientAuth   string `yaml:"clientAuth" toml:"clientAuth"`     // TLS_CLIENT_AUTH: require (default) or optional
+}
+
+// ShutdownConfig configures the graceful shutdown, with durations such as "30s".
+type ShutdownConfig struct {
+	Timeout string `yaml:"timeout" toml:"timeout"` // SHUTDOWN_TIMEOUT, the time allowed to drain requests and run hooks
+	Delay   string `yaml:"delay" toml:"delay"`     // SHUTDOWN_DELAY, the time to keep serving once not ready, 0 by default
+}
+
+// durations parses the timeout and delay.
+func (c ShutdownConfig) durations() (timeout, delay time.Duration, err error) {
+	if timeout, err = time.ParseDuration(c.Timeout); err != nil || timeout <= 0 {
+		return 0, 0, fmt.Errorf("timeout must be a positive duration, such as 30s, got %q", c.Timeout)
+	}
+	if c.Delay != "" {
+		if delay, err = time.ParseDuration(c.Delay); err != nil || delay < 0 {
+			return 0, 0, fmt.Errorf("delay must be a duration, such as 5s, got %q", c.Delay)
+		}
+	}
+	return timeout, delay, nil
+}
+
+// ExpireConfig bounds the token and privilege expirations accepted by the token endpoints, in seconds.
+type ExpireConfig struct {
+	Min int `yaml:"min" toml:"min"` // EXPIRE_MIN, 60 by default
+	Max int `yaml:"max" toml:"max"` // EXPIRE_MAX, 86400 (24 hours) by default
+}
+
+// OpenAPIConfig configures the documentation of the OpenAPI specification served at /openapi.json,
+// and the validation of requests against it.
+type OpenAPIConfig struct {
+	Docs             bool `yaml:"docs" toml:"docs"`                         // OPENAPI_DOCS: serve Swagger UI at /docs
+	ValidateRequests bool `yaml:"validateRequests" toml:"validateRequests"` // OPENAPI_VALIDATE_REQUESTS
+}
+
+// AuditConfig configures the audit log of the issued tokens. Tokens are not audited when Sinks is empty.
+type AuditConfig struct {
+	// Sinks are where the audit records are written: "stdout", "file" and/or "sqlite" (AUDIT_SINKS, comma separated).
+	Sinks []string `yaml:"sinks" toml:"sinks"`
+
+	// File is the JSON lines file of the "file" sink (AUDIT_FILE). It is rotated once it exceeds MaxSizeMB,
+	// keeping MaxBackups rotated files named <file>.1, <file>.2, and so on.
+	File       string `yaml:"file" toml:"file"`
+	MaxSizeMB  int    `yaml:"maxSizeMb" toml:"maxSizeMb"`   // AUDIT_FILE_MAX_SIZE_MB, 100 by default
+	MaxBackups int    `yaml:"maxBackups" toml:"maxBackups"` // AUDIT_FILE_MAX_BACKUPS, 5 by default
+
+	// Database is the SQLite database file of the "sqlite" sink (AUDIT_DATABASE).
+	Database string `yaml:"database" toml:"database"`
+}
+
+// AdminConfig configures the /admin endpoints.
+type AdminConfig struct {
+	// Subjects are the authenticated principals allowed to call the admin endpoints, as <method>:<subject> such as
+	// "apiKey:ops" or "jwt:admin-*", where a trailing "*" matches any suffix (ADMIN_SUBJECTS, comma separated).
+	// The admin endpoints are disabled when empty.
+	Subjects []string `yaml:"subjects" toml:"subjects"`
+}
+
+// AuthConfig configures the authenticators of the token endpoints.
+type AuthConfig struct {
+	APIKeys     map[string]string `yaml:"apiKeys" toml:"apiKeys"`         // AUTH_API_KEYS
+	HMACKeys    map[string]string `yaml:"hmacKeys" toml:"hmacKeys"`       // AUTH_HMAC_KEYS
+	JWKSFile    string            `yaml:"jwksFile" toml:"jwksFile"`       // AUTH_JWKS_FILE
+	JWTIssuer   string            `yaml:"jwtIssuer" toml:"jwtIssuer"`     // AUTH_JWT_ISSUER
+	JWTAudience string            `yaml:"jwtAudience" toml:"jwtAudience"` // AUTH_JWT_AUDIENCE
+}
+
+// RateLimitConfig configures the rate limits, each in the "<count>/<s|m|h>" format.
+type RateLimitConfig struct {
+	Client   string `yaml:"client" toml:"client"`     // RATE_LIMIT_CLIENT
+	Uid      string `yaml:"uid" toml:"uid"`           // RATE_LIMIT_UID
+	Channel  string `yaml:"channel" toml:"channel"`   // RATE_LIMIT_CHANNEL
+	RedisURL string `yaml:"redisUrl" toml:"redisUrl"` // RATE_LIMIT_REDIS_URL
+}
+
+// BatchConfig configures POST /getTokens.
+type BatchConfig struct {
+	MaxSize int `yaml:"maxSize" toml:"maxSize"` // BATCH_MAX_SIZE
+	Workers int `yaml:"workers" toml:"workers"` // BATCH_WORKERS
+}
+
+// LogConfig configures the logger.
+type LogConfig struct {
+	Level  string `yaml:"level" toml:"level"`   // LOG_LEVEL: debug, info, warn or error
+	Format string `yaml:"format" toml:"format"` // LOG_FORMAT: json or text
+	Redact string `yaml:"redact" toml:"redact"` // LOG_REDACT: none, hash or redact
+	// RedactKey is the HMAC key of the "hash" redaction (LOG_REDACT_KEY). It must stay private: anyone holding
+	// it can hash candidate uids and channels and match them against the logs.
+	RedactKey string `yaml:"redactKey" toml:"redactKey"`
+}
+
+var (
+	// credentialPattern matches Agora App IDs and App Certificates.
+	credentialPattern = regexp.MustCompile(`^[0-9a-fA-F]{32}$`)
+
+	// projectNamePattern matches project names, as used in the /projects/:project route prefix.
+	projectNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+)
+
+// DefaultConfig returns the default settings, without credentials, that LoadConfig overrides with the
+// configuration file and environment variables. It is the base of the configurations built in code,
+// such as the Config of Options.
+func DefaultConfig() *Config {
+	return &Config{
+		ServerPort: "8080",
+		Batch:      BatchConfig{MaxSize: defaultBatchMaxSize, Workers: defaultBatchWorkers},
+		Shutdown:   ShutdownConfig{Timeout: defaultShutdownTimeout},
+		Expire:     ExpireConfig{Min: defaultMinExpire, Max: defaultMaxExpire},
+		Audit:      AuditConfig{MaxSizeMB: defaultAuditMaxSizeMB, MaxBackups: defaultAuditMaxBackups},
+	}
+}
+
+// LoadConfig loads the configuration file, if any, applies the environment variables and validates the result.
+// The file format is selected by its extension: .yaml, .yml, .toml or .json.
+// The returned error lists every problem found, one per line.
+//
+// Example usage:
+//
+//	config, err := LoadConfig(os.Getenv("CONFIG_FILE"))
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+func LoadConfig(configFile string) (*Config, error) {
+	config := DefaultConfig()
+	if configFile != "" {
+		if err := config.readFile(configFile); err != nil {
+			return nil, err
+		}
+	}
+	envErr := config.applyEnv()
+	if err := config.readProjectsFile(); err != nil {
+		return nil, errors.Join(envErr, err)
+	}
+	if err := config.resolveSecrets(context.Background()); err != nil {
+		return nil, errors.Join(envErr, err)
+	}
+	if err := errors.Join(envErr, config.Validate()); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// readFile decodes the configuration file according to its extension.
+func (c *Config) readFile(configFile string) error {
+	content, err := os.ReadFile(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %s", err)
+	}
+	switch strings.ToLower(filepath.Ext(configFile)) {
+	case ".yaml", ".yml", ".json":
+		// JSON is a subset of YAML
+		err = yaml.Unmarshal(content, c)
+	case ".toml":
+		err = toml.Unmarshal(content, c)
+	default:
+		return fmt.Errorf("unsupported config file format %q, expected .yaml, .yml, .toml or .json", filepath.Ext(configFile))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %s", configFile, err)
+	}
+	return nil
+}
+
+// readProjectsFile adds the projects of the JSON file referenced by ProjectsFile.
+// Projects of the configuration and environment override projects with the same name from the file.
+//
+// The projects file maps project names to their credentials:
+//
+//	{
+//	  "staging": {"appId": "...", "appCertificate": "..."},
+//	  "production": {"appId": "...", "appCertificateFile": "/run/secrets/production_certificate"}
+//	}
+func (c *Config) readProjectsFile() error {
+	if c.ProjectsFile == "" {
+		return nil
+	}
+	content, err := os.ReadFile(c.ProjectsFile)
+	if err != nil {
+		return fmt.Errorf("failed to read projects file: %s", err)
+	}
+	var projects map[string]*Project
+	if err := json.Unmarshal(content, &projects); err != nil {
+		return fmt.Errorf("failed to parse projects file %s: %s", c.ProjectsFile, err)
+	}
+	for name, project := range projects {
+		if c.Projects == nil {
+			c.Projects = make(map[string]*Project)
+		}
+		if _, exists := c.Projects[name]; !exists {
+			c.Projects[name] = project
+		}
+	}
+	return nil
+}
+
+// applyEnv overrides the configuration with the environment variables that are set and not empty.
+func (c *Config) applyEnv() error {
+	var errs []error
+	setString := func(key string, field *string) {
+		if value, exists := os.LookupEnv(key); exists && len(value) > 0 {
+			*field = value
+		}
+	}
+	setKeys := func(key string, field *map[string]string) {
+		if value, exists := os.LookupEnv(key); exists && len(value) > 0 {
+			keys, err := parseKeyPairs(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %s", key, err))
+				return
+			}
+			*field = keys
+		}
+	}
+	setList := func(key string, field *[]string) {
+		if value, exists := os.LookupEnv(key); exists && len(value) > 0 {
+			*field = splitList(value)
+		}
+	}
+	setInt := func(key string, field *int) {
+		if value, exists := os.LookupEnv(key); exists && len(value) > 0 {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: must be an integer, got %q", key, value))
+				return
+			}
+			*field = parsed
+		}
+	}
+	setBool := func(key string, field *bool) {
+		if value, exists := os.LookupEnv(key); exists && len(value) > 0 {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: must be a boolean, got %q", key, value))
+				return
+			}
+			*field = parsed
+		}
+	}
+
+	setString("APP_ID", &c.AppID)
+	setString("APP_CERTIFICATE", &c.AppCertificate)
+	setString("APP_CERTIFICATE_FILE", &c.AppCertificateFile)
+	setList("APP_PREVIOUS_CERTIFICATES", &c.AppPreviousCertificates)
+	// $PORT is used by Railway, SERVER_PORT takes precedence
+	setString("PORT", &c.ServerPort)
+	setString("SERVER_PORT", &c.ServerPort)
+	setString("GRPC_PORT", &c.GRPCPort)
+	setString("CORS_ALLOW_ORIGIN", &c.CORSAllowOrigin)
+	setList("TRUSTED_PROXIES", &c.TrustedProxies)
+	setString("PROJECTS_FILE", &c.ProjectsFile)
+	setString("POLICY_FILE", &c.PolicyFile)
+	setKeys("AUTH_API_KEYS", &c.Auth.APIKeys)
+	setKeys("AUTH_HMAC_KEYS", &c.Auth.HMACKeys)
+	setString("AUTH_JWKS_FILE", &c.Auth.JWKSFile)
+	setString("AUTH_JWT_ISSUER", &c.Auth.JWTIssuer)
+	setString("AUTH_JWT_AUDIENCE", &c.Auth.JWTAudience)
+	setString("RATE_LIMIT_CLIENT", &c.RateLimits.Client)
+	setString("RATE_LIMIT_UID", &c.RateLimits.Uid)
+	setString("RATE_LIMIT_CHANNEL", &c.RateLimits.Channel)
+	setString("RATE_LIMIT_REDIS_URL", &c.RateLimits.RedisURL)
+	setInt("BATCH_MAX_SIZE", &c.Batch.MaxSize)
+	setInt("BATCH_WORKERS", &c.Batch.Workers)
+	setString("LOG_LEVEL", &c.Log.Level)
+	setString("LOG_FORMAT", &c.Log.Format)
+	setString("LOG_REDACT", &c.Log.Redact)
+	setString("LOG_REDACT_KEY", &c.Log.RedactKey)
+	setList("ADMIN_SUBJECTS", &c.Admin.Subjects)
+	setString("VAULT_ADDR", &c.Vault.Address)
+	setString("VAULT_TOKEN", &c.Vault.Token)
+	setString("VAULT_TOKEN_FILE", &c.Vault.TokenFile)
+	setString("VAULT_SECRET_PATH", &c.Vault.Path)
+	setString("VAULT_SECRET_FIELD", &c.Vault.Field)
+	setString("SECRETS_REFRESH_INTERVAL", &c.SecretsRefreshInterval)
+	setString("TLS_CERT_FILE", &c.TLS.CertFile)
+	setString("TLS_KEY_FILE", &c.TLS.KeyFile)
+	setString("TLS_CLIENT_CA_FILE", &c.TLS.ClientCAFile)
+	setString("TLS_CLIENT_AUTH", &c.TLS.ClientAuth)
+	setString("SHUTDOWN_TIMEOUT", &c.Shutdown.Timeout)
+	setString("SHUTDOWN_DELAY", &c.Shutdown.Delay)
+	setInt("EXPIRE_MIN", &c.Expire.Min)
+	setInt("EXPIRE_MAX", &c.Expire.Max)
+	setBool("OPENAPI_DOCS", &c.OpenAPI.Docs)
+	setBool("OPENAPI_VALIDATE_REQUESTS", &c.OpenAPI.ValidateRequests)
+	setList("AUDIT_SINKS", &c.Audit.Sinks)
+	setString("AUDIT_FILE", &c.Audit.File)
+	setInt("AUDIT_FILE_MAX_SIZE_MB", &c.Audit.MaxSizeMB)
+	setInt("AUDIT_FILE_MAX_BACKUPS", &c.Audit.MaxBackups)
+	setString("AUDIT_DATABASE", &c.Audit.Database)
+
+	for _, env := range os.Environ() {
+		key, appID, _ := strings.Cut(env, "=")
+		if !strings.HasPrefix(key, "PROJECT_") || !strings.HasSuffix(key, "_APP_ID") {
+			continue
+		}
+		envName := strings.TrimSuffix(strings.TrimPrefix(key, "PROJECT_"), "_APP_ID")
+		if envName == "" {
+			continue
+		}
+		if c.Projects == nil {
+			c.Projects = make(map[string]*Project)
+		}
+		c.Projects[strings.ToLower(envName)] = &Project{
+			AppID:                appID,
+			AppCertificate:       os.Getenv("PROJECT_" + envName + "_APP_CERTIFICATE"),
+			AppCertificateFile:   os.Getenv("PROJECT_" + envName + "_APP_CERTIFICATE_FILE"),
+			PreviousCertificates: splitList(os.Getenv("PROJECT_" + envName + "_PREVIOUS_CERTIFICATES")),
+		}
+	}
+
+	if c.ServerPort == "" {
+		c.ServerPort = "8080"
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks every setting of the configuration and returns all the problems found, joined.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(field, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+
+	if c.AppID != "" || c.AppCertificate != "" {
+		if !credentialPattern.MatchString(c.AppID) {
+			invalid("appId", "must be 32 hexadecimal characters")
+		}
+		if !credentialPattern.MatchString(c.AppCertificate) {
+			invalid("appCertificate", "must be 32 hexadecimal characters")
+		}
+		for _, certificate := range c.AppPreviousCertificates {
+			if !credentialPattern.MatchString(certificate) {
+				invalid("appPreviousCertificates", "must be 32 hexadecimal characters")
+				break
+			}
+		}
+	} else if len(c.Projects) == 0 && c.ProjectsFile == "" {
+		invalid("appId", "APP_ID and APP_CERTIFICATE are required when no projects are configured")
+	}
+
+	if port, err := strconv.Atoi(c.ServerPort); err != nil || port <= 0 || port > 65535 {
+		invalid("serverPort", "must be a port number, got %q", c.ServerPort)
+	}
+	if c.GRPCPort != "" {
+		if port, err := strconv.Atoi(c.GRPCPort); err != nil || port <= 0 || port > 65535 {
+			invalid("grpcPort", "must be a port number, got %q", c.GRPCPort)
+		} else if c.GRPCPort == c.ServerPort {
+			invalid("grpcPort", "must differ from serverPort")
+		}
+	}
+
+	if c.CORSAllowOrigin != "*" && c.CORSAllowOrigin != "" {
+		for _, origin := range strings.Split(c.CORSAllowOrigin, ",") {
+			if err := validateOrigin(origin); err != nil {
+				invalid("corsAllowOrigin", "%s", err)
+			}
+		}
+	}
+
+	for _, proxy := range c.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			invalid("trustedProxies", "expected an IP address or CIDR range, got %q", proxy)
+		}
+	}
+
+	for _, name := range sortedKeys(c.Projects) {
+		project, field := c.Projects[name], "projects."+name
+		if !projectNamePattern.MatchString(name) {
+			invalid(field, "name must be lowercase letters, digits, '-' or '_'")
+		}
+		if project == nil || !credentialPattern.MatchString(project.AppID) {
+			invalid(field+".appId", "must be 32 hexadecimal characters")
+		}
+		if project == nil || !credentialPattern.MatchString(project.AppCertificate) {
+			invalid(field+".appCertificate", "must be 32 hexadecimal characters")
+		}
+		if project == nil {
+			continue
+		}
+		for _, certificate := range project.PreviousCertificates {
+			if !credentialPattern.MatchString(certificate) {
+				invalid(field+".previousCertificates", "must be 32 hexadecimal characters")
+				break
+			}
+		}
+	}
+
+	for field, keys := range map[string]map[string]string{"auth.apiKeys": c.Auth.APIKeys, "auth.hmacKeys": c.Auth.HMACKeys} {
+		for name, key := range keys {
+			if name == "" || key == "" {
+				invalid(field, "key names and values can not be empty")
+				break
+			}
+		}
+	}
+	if c.Auth.JWKSFile == "" && (c.Auth.JWTIssuer != "" || c.Auth.JWTAudience != "") {
+		invalid("auth.jwksFile", "is required to verify JWTs")
+	}
+
+	for _, limit := range []struct{ field, value string }{
+		{"rateLimits.client", c.RateLimits.Client}, {"rateLimits.uid", c.RateLimits.Uid}, {"rateLimits.channel", c.RateLimits.Channel},
+	} {
+		if limit.value == "" {
+			continue
+		}
+		if _, err := ParseRateLimit(limit.value); err != nil {
+			invalid(limit.field, "%s", err)
+		}
+	}
+	if c.RateLimits.RedisURL != "" {
+		if _, err := url.Parse(c.RateLimits.RedisURL); err != nil || !strings.HasPrefix(c.RateLimits.RedisURL, "redis") {
+			invalid("rateLimits.redisUrl", "must be a redis:// or rediss:// URL")
+		}
+	}
+
+	if c.Batch.MaxSize <= 0 {
+		invalid("batch.maxSize", "must be a positive integer, got %d", c.Batch.MaxSize)
+	}
+	if c.Batch.Workers <= 0 {
+		invalid("batch.workers", "must be a positive integer, got %d", c.Batch.Workers)
+	}
+
+	if c.SecretsRefreshInterval != "" {
+		if interval, err := time.ParseDuration(c.SecretsRefreshInterval); err != nil || interval <= 0 {
+			invalid("secretsRefreshInterval", "must be a positive duration, such as 5m, got %q", c.SecretsRefreshInterval)
+		}
+	}
+
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		invalid("tls", "certFile and keyFile must be set together")
+	}
+	if c.TLS.ClientCAFile != "" && c.TLS.CertFile == "" {
+		invalid("tls.clientCaFile", "requires certFile and keyFile")
+	}
+	switch c.TLS.ClientAuth {
+	case "", clientAuthRequire, clientAuthOptional:
+	default:
+		invalid("tls.clientAuth", "expected require or optional, got %q", c.TLS.ClientAuth)
+	}
+
+	if _, _, err := c.Shutdown.durations(); err != nil {
+		invalid("shutdown", "%s", err)
+	}
+
+	if c.Expire.Min < 0 {
+		invalid("expire.min", "can not be negative, got %d", c.Expire.Min)
+	}
+	if c.Expire.Max <= 0 || c.Expire.Max > math.MaxUint32 {
+		invalid("expire.max", "must be a positive number of seconds, got %d", c.Expire.Max)
+	} else if c.Expire.Min > c.Expire.Max {
+		invalid("expire", "min can not exceed max")
+	}
+
+	for _, sink := range c.Audit.Sinks {
+		switch sink {
+		case auditSinkStdout:
+		case auditSinkFile:
+			if c.Audit.File == "" {
+				invalid("audit.file", "is required by the file sink")
+			}
+		case auditSinkSQLite:
+			if c.Audit.Database == "" {
+				invalid("audit.database", "is required by the sqlite sink")
+			}
+		default:
+			invalid("audit.sinks", "expected stdout, file or sqlite, got %q", sink)
+		}
+	}
+	if c.Audit.MaxSizeMB <= 0 {
+		invalid("audit.maxSizeMb", "must be a positive integer, got %d", c.Audit.MaxSizeMB)
+	}
+	if c.Audit.MaxBackups < 0 {
+		invalid("audit.maxBackups", "can not be negative, got %d", c.Audit.MaxBackups)
+	}
+
+	for _, subject := range c.Admin.Subjects {
+		if method, _, found := strings.Cut(subject, ":"); !found || !contains(principalMethods, method) {
+			invalid("admin.subjects", "expected <method>:<subject>, with method %s, got %q",
+				strings.Join(principalMethods, ", "), subject)
+		}
+	}
+
+	if _, err := parseLogLevel(c.Log.Level); err != nil {
+		invalid("log.level", "%s", err)
+	}
+	switch strings.ToLower(c.Log.Format) {
+	case "", "json", "text":
+	default:
+		invalid("log.format", "expected json or text, got %q", c.Log.Format)
+	}
+	switch strings.ToLower(c.Log.Redact) {
+	case "", redactNone, redactHash, redactMask:
+	default:
+		invalid("log.redact", "expected none, hash or redact, got %q", c.Log.Redact)
+	}
+	if strings.EqualFold(c.Log.Redact, redactHash) && len(c.Log.RedactKey) < minRedactKeySize {
+		invalid("log.redactKey", "must be at least %d characters with log.redact hash", minRedactKeySize)
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateOrigin checks that the CORS origin is a scheme and host, such as https://example.com.
+func validateOrigin(origin string) error {
+	parsed, err := url.Parse(origin)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%q is not an origin, expected scheme://host[:port]", origin)
+	}
+	if parsed.Path != "" || parsed.RawQuery != "" || parsed.Fragment != "" || parsed.User != nil {
+		return fmt.Errorf("%q is not an origin, it must not contain a path, query or credentials", origin)
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of the map in ascending order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// splitList splits a comma separated list, trimming spaces and dropping empty entries.
+func splitList(value string) []string {
+	var list []string
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeConfig writes the configuration to a temporary file with the given name and returns its path.
+func writeConfig(t *testing.T, name, content string) string {
+	configFile := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(configFile, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return configFile
+}
+
+func TestLoadConfigFormats(t *testing.T) {
+	// Start from a clean environment, as env variables override the file
+	for _, key := range []string{"APP_ID", "APP_CERTIFICATE", "SERVER_PORT", "PORT"} {
+		t.Setenv(key, "")
+	}
+
+	files := map[string]string{
+		"config.yaml": `
+appId: ` + stagingAppID + `
+appCertificate: ` + stagingCert + `
+serverPort: "9090"
+corsAllowOrigin: https://app.example.com,http://localhost:3000
+projects:
+  acme: {appId: ` + acmeAppID + `, appCertificate: ` + acmeCert + `}
+rateLimits:
+  uid: 10/m
+batch:
+  maxSize: 50
+log:
+  redact: hash
+  redactKey: 0123456789abcdef
+`,
+		"config.toml": `
+appId = "` + stagingAppID + `"
+appCertificate = "` + stagingCert + `"
+serverPort = "9090"
+corsAllowOrigin = "https://app.example.com,http://localhost:3000"
+
+[projects.acme]
+appId = "` + acmeAppID + `"
+appCertificate = "` + acmeCert + `"
+
+[rateLimits]
+uid = "10/m"
+
+[batch]
+maxSize = 50
+
+[log]
+redact = "hash"
+redactKey = "0123456789abcdef"
+`,
+		"config.json": `{
+  "appId": "` + stagingAppID + `",
+  "appCertificate": "` + stagingCert + `",
+  "serverPort": "9090",
+  "corsAllowOrigin": "https://app.example.com,http://localhost:3000",
+  "projects": {"acme": {"appId": "` + acmeAppID + `", "appCertificate": "` + acmeCert + `"}},
+  "rateLimits": {"uid": "10/m"},
+  "batch": {"maxSize": 50},
+  "log": {"redact": "hash", "redactKey": "0123456789abcdef"}
+}`,
+	}
+	for name, content := range files {
+		config, err := LoadConfig(writeConfig(t, name, content))
+		if !assert.NoError(t, err, name) {
+			continue
+		}
+		assert.Equal(t, stagingAppID, config.AppID, name)
+		assert.Equal(t, "9090", config.ServerPort, name)
+		assert.Equal(t, "https://app.example.com,http://localhost:3000", config.CORSAllowOrigin, name)
+		assert.Equal(t, &Project{AppID: acmeAppID, AppCertificate: acmeCert}, config.Projects["acme"], name)
+		assert.Equal(t, "10/m", config.RateLimits.Uid, name)
+		assert.Equal(t, 50, config.Batch.MaxSize, name)
+		assert.Equal(t, defaultBatchWorkers, config.Batch.Workers, name)
+		assert.Equal(t, "hash", config.Log.Redact, name)
+		assert.Equal(t, "0123456789abcdef", config.Log.RedactKey, name)
+	}
+
+	_, err := LoadConfig(writeConfig(t, "config.ini", "appId="+stagingAppID))
+	assert.Error(t, err)
+}
+
+func TestLoadConfigEnvOverrides(t *testing.T) {
+	configFile := writeConfig(t, "config.yaml", `
+appId: `+stagingAppID+`
+appCertificate: `+stagingCert+`
+batch:
+  workers: 4
+`)
+	t.Setenv("APP_ID", acmeAppID)
+	t.Setenv("APP_CERTIFICATE", acmeCert)
+	t.Setenv("BATCH_WORKERS", "")
+	t.Setenv("AUTH_API_KEYS", "backend:backend-key")
+
+	config, err := LoadConfig(configFile)
+	if assert.NoError(t, err) {
+		assert.Equal(t, acmeAppID, config.AppID)
+		assert.Equal(t, acmeCert, config.AppCertificate)
+		// Empty variables do not override the file
+		assert.Equal(t, 4, config.Batch.Workers)
+		assert.Equal(t, map[string]string{"backend": "backend-key"}, config.Auth.APIKeys)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	config := &Config{
+		AppID:           "not-an-app-id",
+		AppCertificate:  stagingCert,
+		ServerPort:      "http",
+		CORSAllowOrigin: "https://app.example.com/,localhost:3000",
+		Projects:        map[string]*Project{"Acme": {AppID: acmeAppID}},
+		Auth:            AuthConfig{JWTIssuer: "https://issuer.example.com"},
+		RateLimits:      RateLimitConfig{Uid: "10/d"},
+		Batch:           BatchConfig{MaxSize: 0, Workers: 1},
+		Log:             LogConfig{Level: "verbose", Format: "xml", Redact: "encrypt"},
+		Expire:          ExpireConfig{Min: 600, Max: 300},
+	}
+	err := config.Validate()
+	if assert.Error(t, err) {
+		// Every problem is reported
+		for _, field := range []string{
+			"appId:", "serverPort:", "corsAllowOrigin: \"https://app.example.com/\"", "corsAllowOrigin: \"localhost:3000\"",
+			"projects.Acme:", "projects.Acme.appCertificate:", "auth.jwksFile:", "rateLimits.uid:",
+			"batch.maxSize:", "log.level:", "log.format:", "log.redact:", "expire: min can not exceed max",
+		} {
+			assert.Contains(t, err.Error(), field)
+		}
+		assert.NotContains(t, err.Error(), "\nappCertificate:")
+		assert.NotContains(t, err.Error(), "batch.workers")
+	}
+
+	// Trusted proxies are IP addresses or CIDR ranges
+	config.TrustedProxies = []string{"10.0.0.0/8", "proxy.example.com"}
+	assert.ErrorContains(t, config.Validate(), `trustedProxies: expected an IP address or CIDR range, got "proxy.example.com"`)
+
+	// The hash redaction requires a key
+	config.Log = LogConfig{Redact: "hash", RedactKey: "short"}
+	assert.ErrorContains(t, config.Validate(), "log.redactKey:")
+}
+
+func TestGenTokenDuringReload(t *testing.T) {
+	configFile := writeConfig(t, "config.yaml", "expire:\n  min: 60\n  max: 7200\n")
+	t.Setenv("CONFIG_FILE", configFile)
+	service := NewService()
+
+	// Run with -race: the exported methods must not read the settings while they are replaced
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			assert.NoError(t, service.Reload())
+		}
+	}()
+	for reloading := true; reloading; {
+		select {
+		case <-done:
+			reloading = false
+		default:
+		}
+		_, err := service.GenRtcToken(TokenRequest{TokenType: "rtc", Channel: "room", Uid: "1", RtcRole: "publisher"})
+		assert.NoError(t, err)
+		assert.Equal(t, "ready", service.Readiness(context.Background()).Status)
+	}
+}
+
+func TestReload(t *testing.T) {
+	configFile := writeConfig(t, "config.yaml", `corsAllowOrigin: https://app.example.com`)
+	t.Setenv("CONFIG_FILE", configFile)
+	service := NewService()
+
+	request := func(origin string) int {
+		req, _ := http.NewRequest(http.MethodGet, "/rtm/username/", nil)
+		req.Header.Set("Origin", origin)
+		resp := httptest.NewRecorder()
+		service.Server.Handler.ServeHTTP(resp, req)
+		return resp.Code
+	}
+	assert.Equal(t, http.StatusOK, request("https://app.example.com"))
+	assert.Equal(t, http.StatusForbidden, request("https://other.example.com"))
+
+	if err := os.WriteFile(configFile, []byte(`corsAllowOrigin: https://other.example.com`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, service.Reload())
+	assert.Equal(t, http.StatusForbidden, request("https://app.example.com"))
+	assert.Equal(t, http.StatusOK, request("https://other.example.com"))
+
+	// Invalid configurations are rejected, and the current settings are kept
+	if err := os.WriteFile(configFile, []byte(`corsAllowOrigin: other.example.com`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	err := service.Reload()
+	if assert.Error(t, err) {
+		assert.True(t, strings.HasPrefix(err.Error(), "corsAllowOrigin:"), err)
+	}
+	assert.Equal(t, http.StatusOK, request("https://other.example.com"))
+}
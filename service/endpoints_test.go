@@ -25,6 +25,11 @@ func TestGetTokenValidAndInvalid(t *testing.T) {
 		{"/getToken", http.StatusBadRequest, []byte(``)},
 		{"/getToken", http.StatusOK, []byte(`{"tokenType": "chat"}`)},
 		{"/getToken", http.StatusOK, []byte(`{"tokenType": "chat", "uid": "user123"}`)},
+		{"/getToken", http.StatusOK, []byte(`{"tokenType": "rtc", "channel": "channel123", "role": "publisher", "uid": "123", "expire": 7200, "pubAudioExpire": 600, "pubVideoExpire": 600}`)},
+		{"/getToken", http.StatusBadRequest, []byte(`{"tokenType": "rtc", "channel": "channel123", "role": "subscriber", "uid": "123", "pubAudioExpire": 600}`)},
+		{"/getToken", http.StatusOK, []byte(`{"services": ["rtc", "rtm"], "channel": "channel123", "role": "publisher", "uid": "user123"}`)},
+		{"/getToken", http.StatusOK, []byte(`{"services": ["rtc", "rtm"], "separateTokens": true, "channel": "channel123", "uid": "123"}`)},
+		{"/getToken", http.StatusBadRequest, []byte(`{"services": ["rtc", "unknown"], "channel": "channel123", "uid": "user123"}`)},
 	}
 	for _, httpTest := range tests {
 		testApi, err := http.NewRequest(http.MethodPost, httpTest.url, bytes.NewBuffer(httpTest.body))
@@ -41,6 +46,36 @@ func TestGetTokenValidAndInvalid(t *testing.T) {
 	}
 }
 
+func TestGetTokenMultiService(t *testing.T) {
+	tests := []struct {
+		body     string
+		token    bool
+		services []string
+	}{
+		{`{"services": ["rtc", "rtm"], "channel": "channel123", "uid": "user123"}`, true, nil},
+		{`{"services": ["rtc", "rtm"], "separateTokens": true, "channel": "channel123", "uid": "user123"}`, false, []string{"rtc", "rtm"}},
+	}
+	for _, test := range tests {
+		testApi, err := http.NewRequest(http.MethodPost, "/getToken", bytes.NewBufferString(test.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := httptest.NewRecorder()
+		testService.Server.Handler.ServeHTTP(resp, testApi)
+		assert.Equal(t, http.StatusOK, resp.Code, resp.Body)
+
+		var response TokenResponse
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, test.token, response.Token != "")
+		assert.Len(t, response.Tokens, len(test.services))
+		for _, service := range test.services {
+			assert.NotEmpty(t, response.Tokens[service])
+		}
+	}
+}
+
 func TestRtcValidAndInvalid(t *testing.T) {
 
 	tests := []UrlCodePair{
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorCode is a stable, machine-readable identifier of why a request failed. Every error response carries
+// one in its "code" field, which clients should rely on rather than on the error message.
+type ErrorCode string
+
+const (
+	// Malformed or incomplete requests, answered with 400 Bad Request.
+	CodeInvalidJSON          ErrorCode = "INVALID_JSON"           // The request body is not valid JSON
+	CodeInvalidRequest       ErrorCode = "INVALID_REQUEST"        // The request does not match the OpenAPI specification, when validated
+	CodeUnsupportedTokenType ErrorCode = "UNSUPPORTED_TOKEN_TYPE" // The tokenType is missing or unknown
+	CodeMissingServices      ErrorCode = "MISSING_SERVICES"       // The services list is empty
+	CodeUnsupportedService   ErrorCode = "UNSUPPORTED_SERVICE"    // A requested service is unknown
+	CodeDuplicateService     ErrorCode = "DUPLICATE_SERVICE"      // A service is requested more than once
+	CodeConflictingFields    ErrorCode = "CONFLICTING_FIELDS"     // The field can not be combined with the other fields of the request
+	CodeMissingChannel       ErrorCode = "MISSING_CHANNEL"        // The channel name is required
+	CodeInvalidChannel       ErrorCode = "INVALID_CHANNEL"        // The channel name is too long or has unsupported characters
+	CodeMissingUid           ErrorCode = "MISSING_UID"            // The user ID or account is required
+	CodeInvalidUid           ErrorCode = "INVALID_UID"            // The user ID is out of range, or the user account too long
+	CodeInvalidRole          ErrorCode = "INVALID_ROLE"           // The role is neither publisher nor subscriber
+	CodeInvalidExpiry        ErrorCode = "INVALID_EXPIRY"         // An expiration is malformed or negative
+	CodeMissingProject       ErrorCode = "MISSING_PROJECT"        // No project is selected and no default project is configured
+	CodeMissingToken         ErrorCode = "MISSING_TOKEN"          // The token to inspect is required
+	CodeInvalidToken         ErrorCode = "INVALID_TOKEN"          // The token to inspect can not be decoded
+	CodeEmptyBatch           ErrorCode = "EMPTY_BATCH"            // The batch holds no token request
+	CodeBatchTooLarge        ErrorCode = "BATCH_TOO_LARGE"        // The batch holds more token requests than allowed
+	CodeInvalidCertificate   ErrorCode = "INVALID_CERTIFICATE"    // The certificate is not 32 hexadecimal characters
+	CodeCertificateActive    ErrorCode = "CERTIFICATE_ACTIVE"     // The certificate is already the active one
+	CodeInvalidQuery         ErrorCode = "INVALID_QUERY"          // A query parameter is malformed
+
+	// Well-formed requests with values out of the accepted range, answered with 422 Unprocessable Entity.
+	CodeExpiryTooShort ErrorCode = "EXPIRY_TOO_SHORT" // An expiration is below the shortest one allowed
+	CodeExpiryTooLong  ErrorCode = "EXPIRY_TOO_LONG"  // An expiration exceeds the longest one allowed
+
+	// Requests denied to the caller.
+	CodeUnauthenticated  ErrorCode = "UNAUTHENTICATED"    // 401: The credentials are missing or invalid
+	CodeAdminRequired    ErrorCode = "ADMIN_REQUIRED"     // 403: The endpoint is restricted to the admin subjects
+	CodeOriginNotAllowed ErrorCode = "ORIGIN_NOT_ALLOWED" // 403: The Origin is not allowed by the CORS settings
+	CodePolicyViolation  ErrorCode = "POLICY_VIOLATION"   // 403: A policy rule denies the request
+	CodeRateLimited      ErrorCode = "RATE_LIMITED"       // 429: A rate limit is exceeded
+
+	// Other errors.
+	CodeUnknownProject   ErrorCode = "UNKNOWN_PROJECT"   // 404: The project is not registered
+	CodeNotFound         ErrorCode = "NOT_FOUND"         // 404: No endpoint matches the path
+	CodeAuditUnavailable ErrorCode = "AUDIT_UNAVAILABLE" // 404: No SQLite audit sink is configured
+	CodeBodyTooLarge     ErrorCode = "BODY_TOO_LARGE"    // 413: The request body exceeds the size allowed
+	CodeInternal         ErrorCode = "INTERNAL_ERROR"    // 500: The service failed to process a valid request
+)
+
+// problemContentType is the media type of RFC 7807 problem details.
+const problemContentType = "application/problem+json"
+
+// APIError is an error of the API, reported to clients with its HTTP status code, error code and field.
+// Errors wrapping an APIError are reported the same way, with the message of the wrapping error.
+type APIError struct {
+	Status  int       // The HTTP status code of the response
+	Code    ErrorCode // The machine-readable error code
+	Field   string    // The request field at fault, if any
+	Message string    // The human-readable error message
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// badRequest returns a 400 Bad Request APIError about the field, which may be empty.
+func badRequest(code ErrorCode, field, format string, args ...any) *APIError {
+	return &APIError{Status: http.StatusBadRequest, Code: code, Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+// unprocessable returns a 422 Unprocessable Entity APIError about the field.
+func unprocessable(code ErrorCode, field, format string, args ...any) *APIError {
+	return &APIError{Status: http.StatusUnprocessableEntity, Code: code, Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+// ErrorResponse is the JSON body of every error response of the REST API, and of the failed items of a batch.
+type ErrorResponse struct {
+	Status     int       `json:"status"`               // The HTTP status code
+	Code       ErrorCode `json:"code"`                 // The machine-readable error code
+	Error      string    `json:"error"`                // The human-readable error message
+	Field      string    `json:"field,omitempty"`      // The request field at fault, if any
+	Rule       string    `json:"rule,omitempty"`       // The policy rule that denied the request, if any
+	Scope      string    `json:"scope,omitempty"`      // The exceeded rate limit: "client", "uid" or "channel", if any
+	RetryAfter int       `json:"retryAfter,omitempty"` // The seconds until the request may be retried, if rate limited
+}
+
+// ProblemDetails is the RFC 7807 representation of an ErrorResponse, returned to clients that accept
+// application/problem+json. The ErrorResponse fields other than the status and message are extension members.
+type ProblemDetails struct {
+	Type       string    `json:"type"`                 // Always "about:blank": the code identifies the problem
+	Title      string    `json:"title"`                // The HTTP status text
+	Status     int       `json:"status"`               // The HTTP status code
+	Detail     string    `json:"detail"`               // The human-readable error message
+	Instance   string    `json:"instance,omitempty"`   // The request path
+	Code       ErrorCode `json:"code"`                 // The machine-readable error code
+	Field      string    `json:"field,omitempty"`      // The request field at fault, if any
+	Rule       string    `json:"rule,omitempty"`       // The policy rule that denied the request, if any
+	Scope      string    `json:"scope,omitempty"`      // The exceeded rate limit, if any
+	RetryAfter int       `json:"retryAfter,omitempty"` // The seconds until the request may be retried, if rate limited
+}
+
+// NewErrorResponse describes an error of the service as returned to clients:
+//   - *APIError: its status, code and field, with the message of err, which may wrap it.
+//   - *PolicyViolation: 403 Forbidden, with the rule that denied the request.
+//   - *RateLimitError: 429 Too Many Requests, with the exceeded limit and the seconds until the request may be retried.
+//   - Any other error: 500 Internal Server Error.
+//
+// Parameters:
+//   - err: error - The error returned while processing the request.
+//
+// Returns:
+//   - ErrorResponse: The JSON body of the error response.
+func NewErrorResponse(err error) ErrorResponse {
+	response := ErrorResponse{Status: http.StatusInternalServerError, Code: CodeInternal, Error: err.Error()}
+	var apiErr *APIError
+	var violation *PolicyViolation
+	var limited *RateLimitError
+	switch {
+	case errors.As(err, &apiErr):
+		response.Status, response.Code, response.Field = apiErr.Status, apiErr.Code, apiErr.Field
+	case errors.As(err, &violation):
+		response.Status, response.Code, response.Rule = http.StatusForbidden, CodePolicyViolation, violation.Rule
+	case errors.As(err, &limited):
+		response.Status, response.Code, response.Scope = http.StatusTooManyRequests, CodeRateLimited, limited.Scope
+		response.RetryAfter, _ = strconv.Atoi(limited.retryAfterSeconds())
+	}
+	return response
+}
+
+// problem returns the RFC 7807 representation of the response to the request for the path.
+func (e ErrorResponse) problem(path string) ProblemDetails {
+	return ProblemDetails{
+		Type: "about:blank", Title: http.StatusText(e.Status), Status: e.Status, Detail: e.Error, Instance: path,
+		Code: e.Code, Field: e.Field, Rule: e.Rule, Scope: e.Scope, RetryAfter: e.RetryAfter,
+	}
+}
+
+// writeError responds with the error, as RFC 7807 problem details if the client accepts them, or as an ErrorResponse.
+// Rate limited requests also get the Retry-After header.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	response := NewErrorResponse(err)
+	if response.Code == CodeRateLimited {
+		w.Header().Set("Retry-After", strconv.Itoa(response.RetryAfter))
+	}
+
+	var body any = response
+	contentType := "application/json"
+	if acceptsProblemDetails(r) {
+		body, contentType = response.problem(r.URL.Path), problemContentType
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(response.Status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// acceptsProblemDetails reports whether the Accept header of the request lists application/problem+json.
+func acceptsProblemDetails(r *http.Request) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(accepted); err == nil && mediaType == problemContentType {
+			return true
+		}
+	}
+	return false
+}
+
+// abortWithError responds with the error and aborts the handler chain.
+func abortWithError(c *gin.Context, err error) {
+	c.Error(err)
+	writeError(c.Writer, c.Request, err)
+	c.Abort()
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	tokenv1 "github.com/AgoraIO-Community/agora-token-service/proto/agora/token/v1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrorResponses(t *testing.T) {
+	tests := []struct {
+		method string
+		url    string
+		body   string
+		status int
+		code   ErrorCode
+		field  string
+	}{
+		{http.MethodPost, "/getToken", `{"tokenType": "rtc"`, http.StatusBadRequest, CodeInvalidJSON, ""},
+		{http.MethodPost, "/getToken", `{"tokenType": "video", "uid": "1"}`, http.StatusBadRequest, CodeUnsupportedTokenType, "tokenType"},
+		{http.MethodPost, "/getToken", `{"tokenType": "rtc", "uid": "1"}`, http.StatusBadRequest, CodeMissingChannel, "channel"},
+		{http.MethodPost, "/getToken", `{"tokenType": "rtm"}`, http.StatusBadRequest, CodeMissingUid, "uid"},
+		{http.MethodPost, "/getToken", `{"tokenType": "rtc", "channel": "room", "uid": "1", "expire": 600, "joinChannelExpire": 900}`, http.StatusUnprocessableEntity, CodeExpiryTooLong, "joinChannelExpire"},
+		{http.MethodPost, "/getToken", `{"tokenType": "rtc", "channel": "room", "uid": "1", "pubVideoExpire": -1, "role": "publisher"}`, http.StatusBadRequest, CodeInvalidExpiry, "pubVideoExpire"},
+		{http.MethodPost, "/getToken", `{"tokenType": "rtm", "services": ["rtm"], "uid": "1"}`, http.StatusBadRequest, CodeConflictingFields, "tokenType"},
+		{http.MethodPost, "/getToken", `{"services": ["rtm", "rtm"], "uid": "1"}`, http.StatusBadRequest, CodeDuplicateService, "services"},
+		{http.MethodPost, "/getToken", `{"tokenType": "rtm", "uid": "1", "project": "unknown"}`, http.StatusNotFound, CodeUnknownProject, "project"},
+		{http.MethodPost, "/getTokens", `[]`, http.StatusBadRequest, CodeEmptyBatch, ""},
+		{http.MethodPost, "/inspectToken", `{}`, http.StatusBadRequest, CodeMissingToken, "token"},
+		{http.MethodPost, "/inspectToken", `{"token": "007invalid"}`, http.StatusBadRequest, CodeInvalidToken, "token"},
+		{http.MethodGet, "/rtc/room/publisher/uid/user/", "", http.StatusBadRequest, CodeInvalidUid, "rtcuid"},
+		{http.MethodGet, "/rtc/room/publisher/account/1/", "", http.StatusBadRequest, CodeUnsupportedTokenType, "tokenType"},
+		{http.MethodGet, "/rtm/1/?expiry=soon", "", http.StatusBadRequest, CodeInvalidExpiry, "expiry"},
+		{http.MethodGet, "/rtm/0/", "", http.StatusBadRequest, CodeInvalidUid, "rtmuid"},
+		{http.MethodGet, "/projects/unknown/rtm/1/", "", http.StatusNotFound, CodeUnknownProject, "project"},
+		{http.MethodGet, "/unknown", "", http.StatusNotFound, CodeNotFound, ""},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest(test.method, test.url, bytes.NewBufferString(test.body))
+		resp := httptest.NewRecorder()
+		testService.Server.Handler.ServeHTTP(resp, req)
+
+		var response ErrorResponse
+		assert.Equal(t, test.status, resp.Code, test.url, test.body)
+		assert.Equal(t, "application/json", resp.Header().Get("Content-Type"), test.url)
+		if assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response), test.url) {
+			assert.Equal(t, test.status, response.Status, test.url, test.body)
+			assert.Equal(t, test.code, response.Code, test.url, test.body)
+			assert.Equal(t, test.field, response.Field, test.url, test.body)
+			assert.NotEmpty(t, response.Error, test.url)
+		}
+	}
+}
+
+func TestProblemDetails(t *testing.T) {
+	t.Setenv("AUTH_API_KEYS", "backend:backend-key")
+	t.Setenv("POLICY_FILE", writePolicy(t, testPolicy))
+	t.Setenv("RATE_LIMIT_UID", "1/m")
+	service := NewService()
+
+	tests := []struct {
+		url    string
+		apiKey string
+		body   string
+		status int
+		code   ErrorCode
+	}{
+		{"/getToken", "", `{"tokenType": "rtm", "uid": "1"}`, http.StatusUnauthorized, CodeUnauthenticated},
+		{"/getToken", "backend-key", `{"tokenType": "rtc", "channel": "test", "uid": "1", "role": "publisher"}`, http.StatusForbidden, CodePolicyViolation},
+		{"/getToken", "backend-key", `{"tokenType": "rtm", "uid": "1", "expire": 600}`, http.StatusOK, ""},
+		{"/getToken", "backend-key", `{"tokenType": "rtm", "uid": "1", "expire": 600}`, http.StatusTooManyRequests, CodeRateLimited},
+		{"/admin/certificates", "backend-key", "", http.StatusForbidden, CodeAdminRequired},
+	}
+	for _, test := range tests {
+		method := http.MethodPost
+		if test.body == "" {
+			method = http.MethodGet
+		}
+		req, _ := http.NewRequest(method, test.url, bytes.NewBufferString(test.body))
+		req.Header.Set("Accept", "application/json, application/problem+json")
+		req.Header.Set("X-API-Key", test.apiKey)
+		resp := httptest.NewRecorder()
+		service.Server.Handler.ServeHTTP(resp, req)
+		assert.Equal(t, test.status, resp.Code, test.body, resp.Body)
+		if test.status == http.StatusOK {
+			continue
+		}
+
+		var problem ProblemDetails
+		assert.Equal(t, problemContentType, resp.Header().Get("Content-Type"))
+		if assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &problem)) {
+			assert.Equal(t, "about:blank", problem.Type)
+			assert.Equal(t, http.StatusText(test.status), problem.Title)
+			assert.Equal(t, test.status, problem.Status)
+			assert.Equal(t, test.code, problem.Code)
+			assert.Equal(t, test.url, problem.Instance)
+			assert.NotEmpty(t, problem.Detail)
+		}
+		switch test.code {
+		case CodePolicyViolation:
+			assert.Equal(t, "only-hosts-publish", problem.Rule)
+		case CodeRateLimited:
+			assert.Equal(t, "uid", problem.Scope)
+			assert.Positive(t, problem.RetryAfter)
+			assert.NotEmpty(t, resp.Header().Get("Retry-After"))
+		}
+	}
+}
+
+func TestGRPCErrorDetails(t *testing.T) {
+	t.Setenv("GRPC_PORT", "9090")
+	client := dialGRPC(t, NewService())
+
+	_, err := client.GenerateRtcToken(context.Background(), &tokenv1.GenerateRtcTokenRequest{Uid: "42"})
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	if assert.Len(t, st.Details(), 1) {
+		info, ok := st.Details()[0].(*errdetails.ErrorInfo)
+		if assert.True(t, ok) {
+			assert.Equal(t, string(CodeMissingChannel), info.Reason)
+			assert.Equal(t, grpcErrorDomain, info.Domain)
+			assert.Equal(t, "channel", info.Metadata["field"])
+		}
+	}
+
+	batch, err := client.GenerateTokens(context.Background(), &tokenv1.GenerateTokensRequest{Requests: []*tokenv1.TokenRequest{
+		{Request: &tokenv1.TokenRequest_Rtm{Rtm: &tokenv1.GenerateRtmTokenRequest{Uid: "user", Project: "unknown"}}},
+	}})
+	if assert.NoError(t, err) && assert.Len(t, batch.Results, 1) {
+		assert.Equal(t, uint32(codes.NotFound), batch.Results[0].Code)
+		assert.Equal(t, string(CodeUnknownProject), batch.Results[0].Reason)
+		assert.Equal(t, "project", batch.Results[0].Field)
+	}
+}
@@ -0,0 +1,471 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/AgoraIO-Community/go-tokenbuilder/accesstoken"
+	"github.com/AgoraIO-Community/go-tokenbuilder/chatTokenBuilder"
+	rtctokenbuilder2 "github.com/AgoraIO-Community/go-tokenbuilder/rtctokenbuilder"
+	rtmtokenbuilder2 "github.com/AgoraIO-Community/go-tokenbuilder/rtmtokenbuilder"
+)
+
+// TokenGenerator generates the RTC, RTM and chat tokens of Agora projects from plain TokenRequests, without
+// HTTP, gin or the environment, so tokens can be generated from any Go code. Token requests are validated as
+// by the token endpoints, but they are neither authenticated, authorized nor rate limited.
+// A TokenGenerator is safe for concurrent use.
+type TokenGenerator struct {
+	// lookupProject returns the credentials of the named project, the default project for an empty name.
+	lookupProject func(name string) (*Project, error)
+
+	// minExpire and maxExpire bound the requested token and privilege expirations, in seconds.
+	// The default range applies when maxExpire is zero.
+	minExpire int
+	maxExpire int
+}
+
+// GeneratorOptions configures a TokenGenerator. At least the default project or a registered project is required.
+type GeneratorOptions struct {
+	AppID          string              // The App ID of the default project, selected by token requests without a project
+	AppCertificate string              // The certificate signing the tokens of the default project
+	Projects       map[string]*Project // Additional projects, indexed by name, selected with TokenRequest.Project
+	Expire         ExpireConfig        // The bounds of the requested expirations, 60 to 86400 seconds when left empty
+}
+
+// NewTokenGenerator returns a TokenGenerator of the projects of the options.
+//
+// Parameters:
+//   - options: GeneratorOptions - The credentials of the projects, and the bounds of the requested expirations.
+//
+// Returns:
+//   - *TokenGenerator: The token generator.
+//   - error: An error if the credentials are not 32 hexadecimal characters, or the expiration range is not valid.
+//
+// Example usage:
+//
+//	generator, err := service.NewTokenGenerator(service.GeneratorOptions{AppID: appID, AppCertificate: appCertificate})
+//	if err != nil {
+//	    return err
+//	}
+//	token, err := generator.GenRtcToken(service.TokenRequest{Channel: "room", Uid: "42", RtcRole: "publisher"})
+func NewTokenGenerator(options GeneratorOptions) (*TokenGenerator, error) {
+	config := DefaultConfig()
+	config.AppID, config.AppCertificate, config.Projects = options.AppID, options.AppCertificate, options.Projects
+	if options.Expire != (ExpireConfig{}) {
+		config.Expire = options.Expire
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	projects, err := loadProjects(config)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultProject := &Project{AppID: config.AppID, AppCertificate: config.AppCertificate}
+	return &TokenGenerator{
+		lookupProject: func(name string) (*Project, error) {
+			if name == "" {
+				if defaultProject.AppID == "" {
+					return nil, errMissingProject
+				}
+				return defaultProject, nil
+			}
+			project, exists := projects[name]
+			if !exists {
+				return nil, fmt.Errorf("%w: %s", errUnknownProject, name)
+			}
+			return project, nil
+		},
+		minExpire: config.Expire.Min,
+		maxExpire: config.Expire.Max,
+	}, nil
+}
+
+// GenRtcToken generates an RTC token based on the provided TokenRequest and returns it.
+//
+// Parameters:
+//   - tokenRequest: TokenRequest - The TokenRequest struct containing the required information for RTC token generation.
+//
+// Returns:
+//   - string: The generated RTC token.
+//   - error: An error if there are any issues during token generation or validation.
+//
+// Behavior:
+//  1. Validates the required fields in the TokenRequest (channel and UID), and the fields that are set.
+//  2. Sets a default expiration time of 3600 seconds (1 hour) if not provided in the request,
+//     within the configured expiration range.
+//  3. Determines the user's role (publisher or subscriber) based on the "Role" field in the request.
+//  4. Generates the RTC token using the rtctokenbuilder2 package, or with per-privilege expirations
+//     when any of the privilege expiration fields are set.
+//
+// Notes:
+//   - The rtctokenbuilder2 package is used for generating RTC tokens.
+//   - The "Role" field can be "publisher" or "subscriber", which is the default; other values are rejected.
+//   - Numeric UIDs are user IDs, which must fit in 32 bits. Other UIDs are user accounts of up to 255 bytes.
+//   - When privilege expirations are used and "ExpirationSeconds" is not set, the token expires with
+//     its longest lived privilege. Privileges can not outlive an explicitly set token expiration.
+//   - Publish privilege expirations are only accepted for the publisher role.
+//
+// Example usage:
+//
+//	tokenReq := TokenRequest{
+//	    TokenType:  "rtc",
+//	    Channel:    "my_channel",
+//	    Uid:        "user123",
+//	    Role:       "publisher",
+//	    ExpirationSeconds: 3600,
+//	}
+//	token, err := generator.GenRtcToken(tokenReq)
+func (g *TokenGenerator) GenRtcToken(tokenRequest TokenRequest) (string, error) {
+	if tokenRequest.Channel == "" {
+		return "", errMissingChannel
+	}
+	if tokenRequest.Uid == "" {
+		return "", errMissingUid
+	}
+	if err := g.validateTokenRequest(tokenRequest); err != nil {
+		return "", err
+	}
+	project, err := g.lookupProject(tokenRequest.Project)
+	if err != nil {
+		return "", err
+	}
+
+	userRole, _ := parseRole("role", tokenRequest.RtcRole)
+	if tokenRequest.hasPrivilegeExpires() {
+		return g.genRtcTokenWithPrivileges(project, tokenRequest, userRole)
+	}
+
+	if tokenRequest.ExpirationSeconds == 0 {
+		tokenRequest.ExpirationSeconds = g.defaultExpire()
+	}
+
+	uid64, parseErr := strconv.ParseUint(tokenRequest.Uid, 10, 32)
+	if parseErr != nil {
+		return rtctokenbuilder2.BuildTokenWithAccount(
+			project.AppID, project.AppCertificate, tokenRequest.Channel,
+			tokenRequest.Uid, userRole, uint32(tokenRequest.ExpirationSeconds),
+		)
+	}
+
+	return rtctokenbuilder2.BuildTokenWithUid(
+		project.AppID, project.AppCertificate, tokenRequest.Channel,
+		uint32(uid64), userRole, uint32(tokenRequest.ExpirationSeconds),
+	)
+}
+
+// hasPrivilegeExpires reports whether any per-privilege expiration is set on the request.
+func (tokenRequest TokenRequest) hasPrivilegeExpires() bool {
+	return tokenRequest.JoinChannelExpire != 0 || tokenRequest.PubAudioExpire != 0 ||
+		tokenRequest.PubVideoExpire != 0 || tokenRequest.PubDataStreamExpire != 0
+}
+
+// genRtcTokenWithPrivileges builds an RTC token where each privilege carries its own expiration.
+func (g *TokenGenerator) genRtcTokenWithPrivileges(project *Project, tokenRequest TokenRequest, userRole rtctokenbuilder2.Role) (string, error) {
+	privileges, tokenExpire, err := tokenRequest.rtcPrivileges(userRole, g.defaultExpire())
+	if err != nil {
+		return "", err
+	}
+
+	return buildRtcTokenWithPrivileges(
+		project.AppID, project.AppCertificate, tokenRequest.Channel,
+		rtcAccount(tokenRequest.Uid), tokenExpire, privileges,
+	)
+}
+
+// rtcPrivileges returns the expiration of each RTC privilege and of the token itself.
+// Unset privileges fall back to the token expiration, and publish privileges are only granted to publishers.
+// The token expiration defaults to the longest privilege expiration, or defaultExpire if none is set.
+func (tokenRequest TokenRequest) rtcPrivileges(userRole rtctokenbuilder2.Role, defaultExpire int) (privileges rtcPrivilegeExpires, tokenExpire uint32, err error) {
+	privilegeExpires := []struct {
+		field  string
+		expire int
+	}{
+		{"joinChannelExpire", tokenRequest.JoinChannelExpire}, {"pubAudioExpire", tokenRequest.PubAudioExpire},
+		{"pubVideoExpire", tokenRequest.PubVideoExpire}, {"pubDataStreamExpire", tokenRequest.PubDataStreamExpire},
+	}
+	longestPrivilege, longestField := 0, ""
+	for _, privilege := range privilegeExpires {
+		if privilege.expire < 0 {
+			return privileges, 0, badRequest(CodeInvalidExpiry, privilege.field, "invalid: privilege expiration can not be negative")
+		}
+		if privilege.expire > longestPrivilege {
+			longestPrivilege, longestField = privilege.expire, privilege.field
+		}
+	}
+	if userRole != rtctokenbuilder2.RolePublisher &&
+		(tokenRequest.PubAudioExpire != 0 || tokenRequest.PubVideoExpire != 0 || tokenRequest.PubDataStreamExpire != 0) {
+		return privileges, 0, badRequest(CodeConflictingFields, "role", "invalid: publish privilege expirations require the publisher role")
+	}
+
+	expire := tokenRequest.ExpirationSeconds
+	if expire == 0 {
+		expire = longestPrivilege
+	} else if longestPrivilege > expire {
+		return privileges, 0, unprocessable(CodeExpiryTooLong, longestField, "invalid: privilege expiration exceeds the token expiration")
+	}
+	if expire == 0 {
+		expire = defaultExpire
+	}
+
+	privilegeOrDefault := func(privilegeExpire int) uint32 {
+		if privilegeExpire == 0 {
+			return uint32(expire)
+		}
+		return uint32(privilegeExpire)
+	}
+	privileges.joinChannel = privilegeOrDefault(tokenRequest.JoinChannelExpire)
+	if userRole == rtctokenbuilder2.RolePublisher {
+		privileges.publishAudio = privilegeOrDefault(tokenRequest.PubAudioExpire)
+		privileges.publishVideo = privilegeOrDefault(tokenRequest.PubVideoExpire)
+		privileges.publishDataStream = privilegeOrDefault(tokenRequest.PubDataStreamExpire)
+	}
+
+	return privileges, uint32(expire), nil
+}
+
+// rtcAccount returns the RTC service account of a uid, matching rtctokenbuilder2.BuildTokenWithUid for numeric uids.
+func rtcAccount(uid string) string {
+	if uid64, parseErr := strconv.ParseUint(uid, 10, 32); parseErr == nil {
+		return accesstoken.GetUidStr(uint32(uid64))
+	}
+	return uid
+}
+
+// GenRtmToken generates an RTM (Real-Time Messaging) token based on the provided TokenRequest and returns it.
+//
+// Parameters:
+//   - tokenRequest: TokenRequest - The TokenRequest struct containing the required information for RTM token generation.
+//
+// Returns:
+//   - string: The generated RTM token.
+//   - error: An error if there are any issues during token generation or validation.
+//
+// Behavior:
+//  1. Validates the required field in the TokenRequest (UID), and the fields that are set.
+//  2. Sets a default expiration time of 3600 seconds (1 hour) if not provided in the request,
+//     within the configured expiration range.
+//  3. Generates the RTM token using the rtmtokenbuilder2 package.
+//
+// Notes:
+//   - The rtmtokenbuilder2 package is used for generating RTM tokens.
+//   - The "UID" field in TokenRequest is mandatory for RTM token generation.
+//
+// Example usage:
+//
+//	tokenReq := TokenRequest{
+//	    TokenType:  "rtm",
+//	    Uid:        "user123",
+//	    ExpirationSeconds: 3600,
+//	}
+//	token, err := generator.GenRtmToken(tokenReq)
+func (g *TokenGenerator) GenRtmToken(tokenRequest TokenRequest) (string, error) {
+	if tokenRequest.Uid == "" {
+		return "", errMissingUid
+	}
+	if err := g.validateTokenRequest(tokenRequest); err != nil {
+		return "", err
+	}
+	project, err := g.lookupProject(tokenRequest.Project)
+	if err != nil {
+		return "", err
+	}
+	if tokenRequest.ExpirationSeconds == 0 {
+		tokenRequest.ExpirationSeconds = g.defaultExpire()
+	}
+
+	return rtmtokenbuilder2.BuildToken(
+		project.AppID, project.AppCertificate,
+		tokenRequest.Uid,
+		uint32(tokenRequest.ExpirationSeconds),
+		tokenRequest.Channel,
+	)
+}
+
+// GenChatToken generates a chat token based on the provided TokenRequest and returns it.
+//
+// Parameters:
+//   - tokenRequest: TokenRequest - The TokenRequest struct containing the required information for chat token generation.
+//
+// Returns:
+//   - string: The generated chat token.
+//   - error: An error if there are any issues during token generation or validation.
+//
+// Behavior:
+//  1. Validates the fields that are set, and sets a default expiration time of 3600 seconds (1 hour)
+//     if not provided in the request, within the configured expiration range.
+//  2. Determines whether to generate a chat app token or a chat user token based on the "UID" field in the request.
+//  3. Generates the chat token using the chatTokenBuilder package.
+//
+// Notes:
+//   - The chatTokenBuilder package is used for generating chat tokens.
+//   - If the "UID" field is empty, a chat app token is generated; otherwise, a chat user token is generated.
+//
+// Example usage:
+//
+//	// Generate a chat app token
+//	tokenReq := TokenRequest{
+//	    TokenType:  "chat",
+//	    ExpirationSeconds: 3600,
+//	}
+//	token, err := generator.GenChatToken(tokenReq)
+//
+//	// Generate a chat user token
+//	tokenReq := TokenRequest{
+//	    TokenType:  "chat",
+//	    Uid:        "user123",
+//	    ExpirationSeconds: 3600,
+//	}
+//	token, err := generator.GenChatToken(tokenReq)
+func (g *TokenGenerator) GenChatToken(tokenRequest TokenRequest) (string, error) {
+	if err := g.validateTokenRequest(tokenRequest); err != nil {
+		return "", err
+	}
+	project, err := g.lookupProject(tokenRequest.Project)
+	if err != nil {
+		return "", err
+	}
+	if tokenRequest.ExpirationSeconds == 0 {
+		tokenRequest.ExpirationSeconds = g.defaultExpire()
+	}
+
+	var chatToken string
+	var tokenErr error
+
+	if tokenRequest.Uid == "" {
+		chatToken, tokenErr = chatTokenBuilder.BuildChatAppToken(
+			project.AppID, project.AppCertificate, uint32(tokenRequest.ExpirationSeconds),
+		)
+	} else {
+		chatToken, tokenErr = chatTokenBuilder.BuildChatUserToken(
+			project.AppID, project.AppCertificate,
+			tokenRequest.Uid,
+			uint32(tokenRequest.ExpirationSeconds),
+		)
+
+	}
+
+	return chatToken, tokenErr
+}
+
+// GenMultiServiceToken generates a single AccessToken2 embedding each of the services requested in the TokenRequest.
+//
+// Parameters:
+//   - tokenRequest: TokenRequest - The TokenRequest struct listing the "Services" to include in the token.
+//
+// Returns:
+//   - string: The generated token.
+//   - error: An error if there are any issues during token generation or validation.
+//
+// Behavior:
+//  1. Validates the requested services and the fields they require (channel for RTC, UID for RTC and RTM).
+//  2. Determines the token and RTC privilege expirations, the same way as GenRtcToken.
+//  3. Adds the RTC, RTM and chat services to a single token, sharing the UID and expiration.
+//
+// Notes:
+//   - The chat service grants the user privilege, or the app privilege if no UID is set.
+//
+// Example usage:
+//
+//	tokenReq := TokenRequest{
+//	    Services:   []string{"rtc", "rtm"},
+//	    Channel:    "my_channel",
+//	    Uid:        "user123",
+//	    RtcRole:    "publisher",
+//	    ExpirationSeconds: 3600,
+//	}
+//	token, err := generator.GenMultiServiceToken(tokenReq)
+func (g *TokenGenerator) GenMultiServiceToken(tokenRequest TokenRequest) (string, error) {
+	if err := tokenRequest.validateServices(); err != nil {
+		return "", err
+	}
+	if err := g.validateTokenRequest(tokenRequest); err != nil {
+		return "", err
+	}
+	project, err := g.lookupProject(tokenRequest.Project)
+	if err != nil {
+		return "", err
+	}
+
+	includes := func(service string) bool {
+		return contains(tokenRequest.Services, service)
+	}
+	if includes("rtc") && tokenRequest.Channel == "" {
+		return "", errMissingChannel
+	}
+	if (includes("rtc") || includes("rtm")) && tokenRequest.Uid == "" {
+		return "", errMissingUid
+	}
+	if !includes("rtc") && tokenRequest.hasPrivilegeExpires() {
+		return "", badRequest(CodeConflictingFields, "services", "invalid: privilege expirations require the rtc service")
+	}
+
+	userRole, _ := parseRole("role", tokenRequest.RtcRole)
+	rtcPrivileges, tokenExpire, err := tokenRequest.rtcPrivileges(userRole, g.defaultExpire())
+	if err != nil {
+		return "", err
+	}
+
+	token := accesstoken.NewAccessToken(project.AppID, project.AppCertificate, tokenExpire)
+	if includes("rtc") {
+		token.AddService(newRtcService(tokenRequest.Channel, rtcAccount(tokenRequest.Uid), rtcPrivileges))
+	}
+	if includes("rtm") {
+		serviceRtm := accesstoken.NewServiceRtm(tokenRequest.Uid)
+		serviceRtm.AddPrivilege(accesstoken.PrivilegeLogin, tokenExpire)
+		token.AddService(serviceRtm)
+	}
+	if includes("chat") {
+		serviceChat := accesstoken.NewServiceChat(tokenRequest.Uid)
+		if tokenRequest.Uid == "" {
+			serviceChat.AddPrivilege(accesstoken.PrivilegeChatApp, tokenExpire)
+		} else {
+			serviceChat.AddPrivilege(accesstoken.PrivilegeChatUser, tokenExpire)
+		}
+		token.AddService(serviceChat)
+	}
+
+	return token.Build()
+}
+
+// GenServiceTokens generates a separate token for each of the services requested in the TokenRequest.
+// Each token is generated as if the TokenRequest had the service as its "TokenType".
+//
+// Returns:
+//   - map[string]string: The generated tokens, indexed by service.
+//   - error: An error if any of the tokens could not be generated.
+//
+// Example usage:
+//
+//	tokenReq := TokenRequest{
+//	    Services:       []string{"rtc", "rtm"},
+//	    SeparateTokens: true,
+//	    Channel:        "my_channel",
+//	    Uid:            "user123",
+//	}
+//	tokens, err := generator.GenServiceTokens(tokenReq)
+func (g *TokenGenerator) GenServiceTokens(tokenRequest TokenRequest) (map[string]string, error) {
+	if err := tokenRequest.validateServices(); err != nil {
+		return nil, err
+	}
+
+	tokens := make(map[string]string, len(tokenRequest.Services))
+	for _, serviceReq := range tokenRequest.serviceRequests() {
+		var token string
+		var err error
+		switch serviceReq.TokenType {
+		case "rtc":
+			token, err = g.GenRtcToken(serviceReq)
+		case "rtm":
+			token, err = g.GenRtmToken(serviceReq)
+		case "chat":
+			token, err = g.GenChatToken(serviceReq)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", serviceReq.TokenType, err)
+		}
+		tokens[serviceReq.TokenType] = token
+	}
+	return tokens, nil
+}
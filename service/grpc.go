@@ -0,0 +1,351 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	tokenv1 "github.com/AgoraIO-Community/agora-token-service/proto/agora/token/v1"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// grpcRequestIDKey is the metadata key of the request ID of gRPC calls, the counterpart of the X-Request-ID header.
+	grpcRequestIDKey = "x-request-id"
+
+	// grpcErrorDomain is the domain of the ErrorInfo details of failed calls.
+	grpcErrorDomain = "agora-token-service"
+)
+
+// grpcTokenService implements the gRPC TokenService of proto/agora/token/v1. It converts the calls into
+// TokenRequests issued like POST /getToken, so both transports share validation, policies and rate limits.
+type grpcTokenService struct {
+	tokenv1.UnimplementedTokenServiceServer
+	s *Service
+}
+
+// newGRPCServer returns a gRPC server serving the TokenService, over TLS when the service serves HTTPS.
+// Its interceptors apply the settings, logging, recovery, metrics, authentication and client rate limit
+// of the REST endpoints.
+func (s *Service) newGRPCServer() *grpc.Server {
+	options := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(
+			s.grpcSettingsInterceptor(),
+			s.grpcLoggingInterceptor(),
+			s.grpcRecoveryInterceptor(),
+			s.metrics.unaryInterceptor(),
+			s.grpcAuthInterceptor(),
+		),
+	}
+	if s.Server.TLSConfig != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(s.Server.TLSConfig)))
+	}
+	server := grpc.NewServer(options...)
+	tokenv1.RegisterTokenServiceServer(server, &grpcTokenService{s: s})
+	return server
+}
+
+// GenerateRtcToken implements tokenv1.TokenServiceServer.
+func (g *grpcTokenService) GenerateRtcToken(ctx context.Context, req *tokenv1.GenerateRtcTokenRequest) (*tokenv1.GenerateRtcTokenResponse, error) {
+	token, err := g.s.issueGRPCToken(ctx, rtcTokenRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	return &tokenv1.GenerateRtcTokenResponse{Token: token}, nil
+}
+
+// GenerateRtmToken implements tokenv1.TokenServiceServer.
+func (g *grpcTokenService) GenerateRtmToken(ctx context.Context, req *tokenv1.GenerateRtmTokenRequest) (*tokenv1.GenerateRtmTokenResponse, error) {
+	token, err := g.s.issueGRPCToken(ctx, rtmTokenRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	return &tokenv1.GenerateRtmTokenResponse{Token: token}, nil
+}
+
+// GenerateChatToken implements tokenv1.TokenServiceServer.
+func (g *grpcTokenService) GenerateChatToken(ctx context.Context, req *tokenv1.GenerateChatTokenRequest) (*tokenv1.GenerateChatTokenResponse, error) {
+	token, err := g.s.issueGRPCToken(ctx, chatTokenRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	return &tokenv1.GenerateChatTokenResponse{Token: token}, nil
+}
+
+// GenerateTokens implements tokenv1.TokenServiceServer. Like POST /getTokens, the items are generated
+// concurrently and fail independently.
+func (g *grpcTokenService) GenerateTokens(ctx context.Context, req *tokenv1.GenerateTokensRequest) (*tokenv1.GenerateTokensResponse, error) {
+	s := g.s
+	if len(req.Requests) == 0 {
+		return nil, grpcStatus(errEmptyBatch).Err()
+	}
+	if len(req.Requests) > s.batchMaxSize {
+		return nil, grpcStatus(s.errBatchTooLarge()).Err()
+	}
+
+	results := make([]*tokenv1.TokenResult, len(req.Requests))
+	s.generateBatch(len(req.Requests), func(i int) {
+		results[i] = s.grpcTokenResult(ctx, req.Requests[i])
+	})
+	return &tokenv1.GenerateTokensResponse{Results: results}, nil
+}
+
+// issueGRPCToken issues the token of a single token RPC, responding with the gRPC status of the error.
+// Rate limited calls carry the seconds until they may be retried in the "retry-after" trailer.
+func (s *Service) issueGRPCToken(ctx context.Context, tokenReq TokenRequest) (string, error) {
+	tokenReq, response, err := s.issueToken(ctx, tokenReq)
+	s.recordToken(ctx, tokenReq, routeGRPC, err)
+	if err != nil {
+		var limited *RateLimitError
+		if errors.As(err, &limited) {
+			grpc.SetTrailer(ctx, metadata.Pairs("retry-after", limited.retryAfterSeconds()))
+		}
+		return "", grpcStatus(err).Err()
+	}
+	s.auditResponse(ctx, tokenReq, routeGRPC, response)
+	return response.Token, nil
+}
+
+// grpcTokenResult generates the token of a single GenerateTokens item.
+func (s *Service) grpcTokenResult(ctx context.Context, item *tokenv1.TokenRequest) *tokenv1.TokenResult {
+	var tokenReq TokenRequest
+	switch request := item.GetRequest().(type) {
+	case *tokenv1.TokenRequest_Rtc:
+		tokenReq = rtcTokenRequest(request.Rtc)
+	case *tokenv1.TokenRequest_Rtm:
+		tokenReq = rtmTokenRequest(request.Rtm)
+	case *tokenv1.TokenRequest_Chat:
+		tokenReq = chatTokenRequest(request.Chat)
+	default:
+		return &tokenv1.TokenResult{
+			Code: uint32(codes.InvalidArgument), Reason: string(CodeUnsupportedTokenType), Error: "invalid: missing token request",
+		}
+	}
+
+	tokenReq, response, err := s.issueToken(ctx, tokenReq)
+	s.recordToken(ctx, tokenReq, routeGRPCBatch, err)
+	if err != nil {
+		response := NewErrorResponse(err)
+		return &tokenv1.TokenResult{
+			Code: uint32(grpcCode(response.Status)), Reason: string(response.Code), Error: response.Error,
+			Field: response.Field, Rule: response.Rule, RetryAfter: uint32(response.RetryAfter),
+		}
+	}
+	s.auditResponse(ctx, tokenReq, routeGRPCBatch, response)
+	return &tokenv1.TokenResult{Code: uint32(codes.OK), Token: response.Token}
+}
+
+// grpcStatus returns the gRPC status matching an error, with the error code of the REST API and the field
+// at fault in an ErrorInfo detail.
+func grpcStatus(err error) *status.Status {
+	response := NewErrorResponse(err)
+	st := status.New(grpcCode(response.Status), response.Error)
+	info := &errdetails.ErrorInfo{Reason: string(response.Code), Domain: grpcErrorDomain}
+	if response.Field != "" {
+		info.Metadata = map[string]string{"field": response.Field}
+	}
+	if detailed, detailsErr := st.WithDetails(info); detailsErr == nil {
+		return detailed
+	}
+	return st
+}
+
+// grpcCode returns the gRPC status code matching the HTTP status code of an error.
+func grpcCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	default:
+		return codes.Internal
+	}
+}
+
+// rtcTokenRequest converts a GenerateRtcToken request into the equivalent POST /getToken request.
+func rtcTokenRequest(req *tokenv1.GenerateRtcTokenRequest) TokenRequest {
+	tokenReq := TokenRequest{
+		TokenType:           "rtc",
+		Project:             req.GetProject(),
+		Channel:             req.GetChannel(),
+		Uid:                 req.GetUid(),
+		ExpirationSeconds:   int(req.GetExpire()),
+		JoinChannelExpire:   int(req.GetJoinChannelExpire()),
+		PubAudioExpire:      int(req.GetPubAudioExpire()),
+		PubVideoExpire:      int(req.GetPubVideoExpire()),
+		PubDataStreamExpire: int(req.GetPubDataStreamExpire()),
+	}
+	switch req.GetRole() {
+	case tokenv1.RtcRole_RTC_ROLE_PUBLISHER:
+		tokenReq.RtcRole = "publisher"
+	case tokenv1.RtcRole_RTC_ROLE_SUBSCRIBER:
+		tokenReq.RtcRole = "subscriber"
+	}
+	return tokenReq
+}
+
+// rtmTokenRequest converts a GenerateRtmToken request into the equivalent POST /getToken request.
+func rtmTokenRequest(req *tokenv1.GenerateRtmTokenRequest) TokenRequest {
+	return TokenRequest{
+		TokenType:         "rtm",
+		Project:           req.GetProject(),
+		Uid:               req.GetUid(),
+		Channel:           req.GetChannel(),
+		ExpirationSeconds: int(req.GetExpire()),
+	}
+}
+
+// chatTokenRequest converts a GenerateChatToken request into the equivalent POST /getToken request.
+func chatTokenRequest(req *tokenv1.GenerateChatTokenRequest) TokenRequest {
+	return TokenRequest{
+		TokenType:         "chat",
+		Project:           req.GetProject(),
+		Uid:               req.GetUid(),
+		ExpirationSeconds: int(req.GetExpire()),
+	}
+}
+
+// grpcSettingsInterceptor holds the settings for the duration of the call, like the settingsMiddleware.
+func (s *Service) grpcSettingsInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		return handler(ctx, req)
+	}
+}
+
+// grpcLoggingInterceptor assigns each call a request ID, taken from the x-request-id metadata or generated,
+// echoes it in the response header, and writes an access log entry once the call is served.
+func (s *Service) grpcLoggingInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var requestID string
+		if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get(grpcRequestIDKey)) > 0 {
+			requestID = md.Get(grpcRequestIDKey)[0]
+		}
+		if requestID == "" || len(requestID) > 128 {
+			requestID = newRequestID()
+		}
+		grpc.SetHeader(ctx, metadata.Pairs(grpcRequestIDKey, requestID))
+		ctx = context.WithValue(ctx, requestIDContextKey{}, requestID)
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			ctx = context.WithValue(ctx, clientIPContextKey{}, peerIP(p.Addr))
+		}
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		attrs := []any{
+			"method", "GRPC",
+			"route", info.FullMethod,
+			"status", status.Code(err).String(),
+			"latency", time.Since(start),
+		}
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			attrs = append(attrs, "client_ip", peerIP(p.Addr))
+		}
+		s.log(ctx).Info("request", attrs...)
+		return resp, err
+	}
+}
+
+// grpcRecoveryInterceptor recovers from panics in the handlers, logging them with the request ID, and responds
+// with the Internal status.
+func (s *Service) grpcRecoveryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				s.log(ctx).Error("panic recovered", "error", fmt.Sprint(recovered))
+				resp, err = nil, grpcStatus(&APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "internal error"}).Err()
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// grpcAuthInterceptor authenticates the calls with the authenticators of the REST endpoints, and enforces
+// the client rate limit. HMAC signatures cover the HTTP request body, so they are rejected over gRPC.
+func (s *Service) grpcAuthInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		client := ""
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			client = "ip:" + peerIP(p.Addr)
+		}
+		if len(s.authenticators) > 0 {
+			r := grpcHTTPRequest(ctx, info.FullMethod)
+			if r.Header.Get("X-Auth-Signature") != "" {
+				return nil, grpcStatus(&APIError{
+					Status: http.StatusUnauthorized, Code: CodeUnauthenticated, Message: "Unauthorized: HMAC signatures are not supported over gRPC",
+				}).Err()
+			}
+			principal, err := s.authenticate(r)
+			if err != nil {
+				return nil, grpcStatus(&APIError{
+					Status: http.StatusUnauthorized, Code: CodeUnauthenticated, Message: "Unauthorized: " + err.Error(),
+				}).Err()
+			}
+			ctx = context.WithValue(ctx, principalContextKey{}, principal)
+			client = principal.Method + ":" + principal.Subject
+		}
+
+		if s.rateLimits != nil {
+			if limited := s.takeRateLimit(ctx, "client", client, s.rateLimits.client); limited != nil {
+				s.recordToken(ctx, TokenRequest{}, routeGRPC, limited)
+				grpc.SetTrailer(ctx, metadata.Pairs("retry-after", limited.retryAfterSeconds()))
+				return nil, grpcStatus(limited).Err()
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// grpcHTTPRequest presents the metadata and TLS connection of a gRPC call as an HTTP request, so the
+// authenticators of the REST endpoints verify gRPC calls too.
+func grpcHTTPRequest(ctx context.Context, fullMethod string) *http.Request {
+	r := &http.Request{
+		Method: http.MethodPost,
+		URL:    &url.URL{Path: fullMethod},
+		Header: make(http.Header),
+		Body:   http.NoBody,
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for key, values := range md {
+		for _, value := range values {
+			r.Header.Add(key, value)
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			state := tlsInfo.State
+			r.TLS = &state
+		}
+		if p.Addr != nil {
+			r.RemoteAddr = p.Addr.String()
+		}
+	}
+	return r.WithContext(ctx)
+}
+
+// peerIP returns the IP address of a peer, or its full address when it has no port.
+func peerIP(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
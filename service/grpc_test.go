@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	tokenv1 "github.com/AgoraIO-Community/agora-token-service/proto/agora/token/v1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dialGRPC serves the gRPC TokenService of the service in memory and returns a client connected to it.
+func dialGRPC(t *testing.T, service *Service) tokenv1.TokenServiceClient {
+	listener := bufconn.Listen(1 << 20)
+	go service.GRPCServer.Serve(listener)
+	t.Cleanup(service.GRPCServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return tokenv1.NewTokenServiceClient(conn)
+}
+
+func TestGRPCTokenService(t *testing.T) {
+	t.Setenv("GRPC_PORT", "9090")
+	service := NewService()
+	client := dialGRPC(t, service)
+	ctx := context.Background()
+
+	var header metadata.MD
+	rtc, err := client.GenerateRtcToken(ctx, &tokenv1.GenerateRtcTokenRequest{
+		Channel: "room", Uid: "42", Role: tokenv1.RtcRole_RTC_ROLE_PUBLISHER, Expire: 600,
+	}, grpc.Header(&header))
+	if assert.NoError(t, err) {
+		info, err := service.InspectToken(rtc.Token)
+		if assert.NoError(t, err) {
+			assert.Equal(t, uint32(600), info.Expire)
+			assert.Equal(t, "room", info.Services[0].Channel)
+			assert.Len(t, info.Services[0].Privileges, 4)
+		}
+		assert.NotEmpty(t, header.Get(grpcRequestIDKey))
+	}
+
+	rtm, err := client.GenerateRtmToken(ctx, &tokenv1.GenerateRtmTokenRequest{Uid: "user"})
+	if assert.NoError(t, err) {
+		// Both transports share the token generation
+		expected, _ := service.GenRtmToken(TokenRequest{TokenType: "rtm", Uid: "user"})
+		assert.Equal(t, len(expected), len(rtm.Token))
+	}
+
+	chat, err := client.GenerateChatToken(ctx, &tokenv1.GenerateChatTokenRequest{})
+	if assert.NoError(t, err) {
+		assert.NotEmpty(t, chat.Token)
+	}
+
+	_, err = client.GenerateRtcToken(ctx, &tokenv1.GenerateRtcTokenRequest{Uid: "42"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.GenerateRtmToken(ctx, &tokenv1.GenerateRtmTokenRequest{Uid: "user", Project: "unknown"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	batch, err := client.GenerateTokens(ctx, &tokenv1.GenerateTokensRequest{Requests: []*tokenv1.TokenRequest{
+		{Request: &tokenv1.TokenRequest_Rtc{Rtc: &tokenv1.GenerateRtcTokenRequest{Channel: "room", Uid: "42"}}},
+		{Request: &tokenv1.TokenRequest_Rtm{Rtm: &tokenv1.GenerateRtmTokenRequest{}}},
+		{Request: &tokenv1.TokenRequest_Chat{Chat: &tokenv1.GenerateChatTokenRequest{Uid: "user"}}},
+		{},
+	}})
+	if assert.NoError(t, err) && assert.Len(t, batch.Results, 4) {
+		assert.Equal(t, uint32(codes.OK), batch.Results[0].Code)
+		assert.NotEmpty(t, batch.Results[0].Token)
+		assert.Equal(t, uint32(codes.InvalidArgument), batch.Results[1].Code)
+		assert.NotEmpty(t, batch.Results[1].Error)
+		assert.Equal(t, uint32(codes.OK), batch.Results[2].Code)
+		assert.Equal(t, uint32(codes.InvalidArgument), batch.Results[3].Code)
+	}
+
+	_, err = client.GenerateTokens(ctx, &tokenv1.GenerateTokensRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGRPCAuthAndPolicy(t *testing.T) {
+	t.Setenv("GRPC_PORT", "9090")
+	t.Setenv("AUTH_API_KEYS", "ci-runner:ci-key,backend:backend-key")
+	t.Setenv("AUTH_HMAC_KEYS", "provisioner:provisioner-secret")
+	t.Setenv("POLICY_FILE", writePolicy(t, testPolicy))
+	t.Setenv("RATE_LIMIT_CLIENT", "4/m")
+	client := dialGRPC(t, NewService())
+
+	withKey := func(apiKey string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", apiKey)
+	}
+	subscriber := &tokenv1.GenerateRtcTokenRequest{Channel: "test", Uid: "1"}
+	publisher := &tokenv1.GenerateRtcTokenRequest{Channel: "test", Uid: "1", Role: tokenv1.RtcRole_RTC_ROLE_PUBLISHER}
+
+	_, err := client.GenerateRtcToken(context.Background(), subscriber)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.GenerateRtcToken(withKey("wrong-key"), subscriber)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	signed := metadata.AppendToOutgoingContext(context.Background(), "x-auth-key-id", "provisioner", "x-auth-signature", "signature")
+	_, err = client.GenerateRtcToken(signed, subscriber)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.GenerateRtcToken(withKey("backend-key"), subscriber)
+	assert.NoError(t, err)
+	_, err = client.GenerateRtcToken(withKey("backend-key"), publisher)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	batch, err := client.GenerateTokens(withKey("backend-key"), &tokenv1.GenerateTokensRequest{Requests: []*tokenv1.TokenRequest{
+		{Request: &tokenv1.TokenRequest_Rtc{Rtc: publisher}},
+	}})
+	if assert.NoError(t, err) && assert.Len(t, batch.Results, 1) {
+		assert.Equal(t, uint32(codes.PermissionDenied), batch.Results[0].Code)
+		assert.NotEmpty(t, batch.Results[0].Rule)
+	}
+
+	// The client limit is shared by the calls of the principal
+	var trailer metadata.MD
+	for i := 0; i < 2; i++ {
+		_, err = client.GenerateRtcToken(withKey("backend-key"), subscriber, grpc.Trailer(&trailer))
+	}
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.NotEmpty(t, trailer.Get("retry-after"))
+	_, err = client.GenerateRtmToken(withKey("ci-key"), &tokenv1.GenerateRtmTokenRequest{Uid: "1", Expire: 600})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	rtctokenbuilder2 "github.com/AgoraIO-Community/go-tokenbuilder/rtctokenbuilder"
+	"github.com/gin-gonic/gin"
+)
+
+// Build information, injected at build time by goreleaser:
+//
+//	-X github.com/AgoraIO-Community/agora-token-service/service.Version={{.Version}}
+//
+// The VCS information recorded by the Go toolchain is used for the values that are not injected.
+var (
+	Version   = ""
+	Commit    = ""
+	BuildDate = ""
+)
+
+// readinessTimeout bounds the checks of the optional dependencies of GET /readyz.
+const readinessTimeout = 2 * time.Second
+
+// BuildInfo is the JSON response of GET /version.
+type BuildInfo struct {
+	Version   string `json:"version"`             // The release version, "dev" for development builds
+	Commit    string `json:"commit,omitempty"`    // The git commit the service was built from
+	BuildDate string `json:"buildDate,omitempty"` // When the service was built, or committed for development builds
+	GoVersion string `json:"goVersion"`           // The Go version the service was built with
+}
+
+// ReadinessCheck is the outcome of a single check of GET /readyz.
+type ReadinessCheck struct {
+	Status string `json:"status"`          // "ok" or "failed"
+	Error  string `json:"error,omitempty"` // Why the check failed
+}
+
+// ReadinessReport is the JSON response of GET /readyz.
+type ReadinessReport struct {
+	Status string                    `json:"status"` // "ready" or "not ready"
+	Checks map[string]ReadinessCheck `json:"checks"` // The checks, indexed by name
+}
+
+// RateLimitStorePinger is implemented by the rate limit stores that depend on an external server.
+type RateLimitStorePinger interface {
+	// Ping checks that the store is reachable.
+	Ping(ctx context.Context) error
+}
+
+// GetBuildInfo returns the build information of the service.
+func GetBuildInfo() BuildInfo {
+	info := BuildInfo{Version: Version, Commit: Commit, BuildDate: BuildDate, GoVersion: runtime.Version()}
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && buildInfo.Main.Version != "(devel)" {
+			info.Version = buildInfo.Main.Version
+		}
+		for _, setting := range buildInfo.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildDate == "":
+				info.BuildDate = setting.Value
+			}
+		}
+	}
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	return info
+}
+
+// Readiness runs the readiness checks of the service:
+//   - shutdown: the service is not shutting down.
+//   - credentials: every project has credentials, and signs a self-test token that verifies with its certificate.
+//   - rateLimitStore: the shared rate limit store is reachable, when it depends on an external server.
+//
+// Parameters:
+//   - ctx: context.Context - Bounds the checks of the external dependencies.
+//
+// Returns:
+//   - ReadinessReport: The outcome of every check. The service is ready if all of them passed.
+func (s *Service) Readiness(ctx context.Context) ReadinessReport {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.readiness(ctx)
+}
+
+// readiness runs the checks of Readiness. The caller must hold mu for reading.
+func (s *Service) readiness(ctx context.Context) ReadinessReport {
+	report := ReadinessReport{Status: "ready", Checks: make(map[string]ReadinessCheck)}
+	check := func(name string, err error) {
+		if err != nil {
+			report.Status = "not ready"
+			report.Checks[name] = ReadinessCheck{Status: "failed", Error: err.Error()}
+			return
+		}
+		report.Checks[name] = ReadinessCheck{Status: "ok"}
+	}
+
+	var shutdownErr error
+	if s.draining.Load() {
+		shutdownErr = errors.New("shutting down")
+	}
+	check("shutdown", shutdownErr)
+	check("credentials", s.checkCredentials())
+	if s.rateLimits != nil {
+		if pinger, ok := s.rateLimits.store.(RateLimitStorePinger); ok {
+			ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+			defer cancel()
+			check("rateLimitStore", pinger.Ping(ctx))
+		}
+	}
+	return report
+}
+
+// checkCredentials signs a self-test token with each project, and verifies its signature.
+func (s *Service) checkCredentials() error {
+	s.credentialsMu.RLock()
+	var projects []*Project
+	if s.appID != "" {
+		projects = append(projects, s.defaultProject())
+	}
+	for _, name := range sortedKeys(s.projects) {
+		projects = append(projects, s.projects[name])
+	}
+	s.credentialsMu.RUnlock()
+
+	if len(projects) == 0 {
+		return errors.New("no credentials loaded")
+	}
+	for _, project := range projects {
+		token, err := rtctokenbuilder2.BuildTokenWithUid(project.AppID, project.AppCertificate, "readyz", 0, rtctokenbuilder2.RoleSubscriber, 60)
+		if err == nil {
+			err = verifyTokenSignature(token, project.AppCertificate)
+		}
+		if err != nil {
+			return fmt.Errorf("project %q: self-test token failed: %s", project.Name, err)
+		}
+	}
+	return nil
+}
+
+// verifyTokenSignature checks that the token is signed with the certificate.
+func verifyTokenSignature(token, certificate string) error {
+	signature, content, err := decodeToken(token)
+	if err != nil {
+		return err
+	}
+	info, err := unpackTokenContent(content)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(signature, signTokenContent(certificate, info.IssueTs, info.Salt, content)) {
+		return errors.New("signature does not match the certificate")
+	}
+	return nil
+}
+
+// getHealthz responds 200 OK as long as the process serves requests, for liveness probes.
+func (s *Service) getHealthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// getReadyz responds with the readiness report: 200 OK when ready, 503 Service Unavailable otherwise.
+func (s *Service) getReadyz(c *gin.Context) {
+	report := s.readiness(c.Request.Context())
+	code := http.StatusOK
+	if report.Status != "ready" {
+		code = http.StatusServiceUnavailable
+	}
+	c.JSON(code, report)
+}
+
+// getVersion responds with the build information of the service.
+func (s *Service) getVersion(c *gin.Context) {
+	c.JSON(http.StatusOK, GetBuildInfo())
+}
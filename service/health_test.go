@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// getReport requests the readiness report of the service.
+func getReport(t *testing.T, service *Service) (int, ReadinessReport) {
+	req, _ := http.NewRequest(http.MethodGet, "/readyz", nil)
+	resp := httptest.NewRecorder()
+	service.Server.Handler.ServeHTTP(resp, req)
+	var report ReadinessReport
+	if err := json.Unmarshal(resp.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	return resp.Code, report
+}
+
+func TestHealthz(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "/healthz", nil)
+	resp := httptest.NewRecorder()
+	testService.Server.Handler.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, resp.Body.String())
+}
+
+func TestProbesWithoutOrigin(t *testing.T) {
+	s, err := New(Options{AppID: testAppID, AppCertificate: testAppCertificate, CORSAllowOrigin: "https://app.example.com"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, path := range []string{"/healthz", "/readyz", "/version"} {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		resp := httptest.NewRecorder()
+		s.Handler().ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code, path)
+	}
+
+	// The other endpoints still check the origin
+	req, _ := http.NewRequest(http.MethodGet, "/ping", nil)
+	resp := httptest.NewRecorder()
+	s.Handler().ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+}
+
+func TestReadyz(t *testing.T) {
+	server := miniredis.RunT(t)
+	t.Setenv("RATE_LIMIT_UID", "2/m")
+	t.Setenv("RATE_LIMIT_REDIS_URL", "redis://"+server.Addr())
+	service := createMultiProjectService(t)
+
+	code, report := getReport(t, service)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ready", report.Status)
+	assert.Equal(t, map[string]ReadinessCheck{
+		"shutdown":       {Status: "ok"},
+		"credentials":    {Status: "ok"},
+		"rateLimitStore": {Status: "ok"},
+	}, report.Checks)
+
+	server.Close()
+	code, report = getReport(t, service)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "not ready", report.Status)
+	assert.Equal(t, "failed", report.Checks["rateLimitStore"].Status)
+	assert.NotEmpty(t, report.Checks["rateLimitStore"].Error)
+	assert.Equal(t, "ok", report.Checks["credentials"].Status)
+}
+
+func TestReadyzCredentialsAndShutdown(t *testing.T) {
+	service := NewService()
+	_, report := getReport(t, service)
+	assert.NotContains(t, report.Checks, "rateLimitStore")
+
+	service.credentialsMu.Lock()
+	appID := service.appID
+	service.appID = ""
+	service.credentialsMu.Unlock()
+	code, report := getReport(t, service)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, ReadinessCheck{Status: "failed", Error: "no credentials loaded"}, report.Checks["credentials"])
+
+	service.credentialsMu.Lock()
+	service.appID = appID
+	service.credentialsMu.Unlock()
+
+	// A self-test token signed with another certificate fails the verification
+	token, err := service.GenRtcToken(TokenRequest{TokenType: "rtc", Channel: "room", Uid: "1", RtcRole: "publisher"})
+	if assert.NoError(t, err) {
+		assert.NoError(t, verifyTokenSignature(token, service.appCertificate))
+		assert.Error(t, verifyTokenSignature(token, "00000000000000000000000000000000"))
+	}
+	assert.NoError(t, service.Shutdown(context.Background()))
+	code, report = getReport(t, service)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "ok", report.Checks["credentials"].Status)
+	assert.Equal(t, ReadinessCheck{Status: "failed", Error: "shutting down"}, report.Checks["shutdown"])
+}
+
+func TestVersion(t *testing.T) {
+	defer func(version, commit string) { Version, Commit = version, commit }(Version, Commit)
+	Version, Commit = "1.2.3", "abc123"
+
+	req, _ := http.NewRequest(http.MethodGet, "/version", nil)
+	resp := httptest.NewRecorder()
+	testService.Server.Handler.ServeHTTP(resp, req)
+
+	var info BuildInfo
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &info))
+	assert.Equal(t, "1.2.3", info.Version)
+	assert.Equal(t, "abc123", info.Commit)
+	assert.NotEmpty(t, info.GoVersion)
+
+	Version = ""
+	assert.NotEmpty(t, GetBuildInfo().Version)
+}
@@ -1,42 +1,53 @@
 package service
 
 import (
-	"fmt"
-	"log"
+	"context"
 	"net/http"
 	"strings"
 
+	rtctokenbuilder2 "github.com/AgoraIO-Community/go-tokenbuilder/rtctokenbuilder"
 	rtmtokenbuilder2 "github.com/AgoraIO-Community/go-tokenbuilder/rtmtokenbuilder"
 
 	"github.com/gin-gonic/gin"
 )
 
 func (s *Service) getRtcToken(c *gin.Context) {
-	log.Println("Generating RTC token")
 	// get param values
 	channelName, tokenType, uidStr, _, role, expire, err := s.parseRtcParams(c)
+	project, projectErr := s.lookupProject(c.Param("project"))
+	if err == nil {
+		err = projectErr
+	}
 
 	if err != nil {
-		c.Error(err)
-		c.AbortWithStatusJSON(400, gin.H{
-			"message": "Error Generating RTC token: " + err.Error(),
-			"status":  400,
-		})
+		s.recordToken(c.Request.Context(), TokenRequest{TokenType: "rtc"}, routeLegacy, err)
+		abortWithError(c, err)
+		return
+	}
+
+	rtcRequest := TokenRequest{
+		TokenType: "rtc", Channel: channelName, RtcRole: roleName(role), Uid: uidStr,
+		ExpirationSeconds: int(expire), Project: c.Param("project"),
+	}
+	if violation := s.authorize(c.Request.Context(), rtcRequest); violation != nil {
+		s.recordToken(c.Request.Context(), rtcRequest, routeLegacy, violation)
+		abortWithError(c, violation)
+		return
+	}
+	if limited := s.rateLimit(c.Request.Context(), rtcRequest); limited != nil {
+		s.recordToken(c.Request.Context(), rtcRequest, routeLegacy, limited)
+		abortWithError(c, limited)
 		return
 	}
 
-	rtcToken, tokenErr := s.generateRtcToken(channelName, uidStr, tokenType, role, expire)
+	rtcToken, tokenErr := s.generateRtcToken(project, channelName, uidStr, tokenType, role, expire)
 
 	if tokenErr != nil {
-		log.Println(tokenErr) // token failed to generate
-		c.Error(tokenErr)
-		errMsg := "Error Generating RTC token - " + tokenErr.Error()
-		c.AbortWithStatusJSON(400, gin.H{
-			"status": 400,
-			"error":  errMsg,
-		})
+		s.recordToken(c.Request.Context(), rtcRequest, routeLegacy, tokenErr)
+		abortWithError(c, tokenErr)
 	} else {
-		log.Println("RTC Token generated")
+		s.recordToken(c.Request.Context(), rtcRequest, routeLegacy, nil)
+		s.auditTokens(c.Request.Context(), rtcRequest, routeLegacy, rtcToken)
 		c.JSON(200, gin.H{
 			"rtcToken": rtcToken,
 		})
@@ -44,30 +55,41 @@ func (s *Service) getRtcToken(c *gin.Context) {
 }
 
 func (s *Service) getRtmToken(c *gin.Context) {
-	log.Println("Generating RTM token")
 	// get param values
 	uidStr, expire, err := s.parseRtmParams(c)
+	project, projectErr := s.lookupProject(c.Param("project"))
+	if err == nil {
+		err = projectErr
+	}
 
 	if err != nil {
-		c.Error(err)
-		c.AbortWithStatusJSON(400, gin.H{
-			"message": "Error Generating RTM token: " + err.Error(),
-			"status":  400,
-		})
+		s.recordToken(c.Request.Context(), TokenRequest{TokenType: "rtm"}, routeLegacy, err)
+		abortWithError(c, err)
+		return
+	}
+
+	rtmRequest := TokenRequest{
+		TokenType: "rtm", Uid: uidStr, ExpirationSeconds: int(expire), Project: c.Param("project"),
+	}
+	if violation := s.authorize(c.Request.Context(), rtmRequest); violation != nil {
+		s.recordToken(c.Request.Context(), rtmRequest, routeLegacy, violation)
+		abortWithError(c, violation)
+		return
+	}
+	if limited := s.rateLimit(c.Request.Context(), rtmRequest); limited != nil {
+		s.recordToken(c.Request.Context(), rtmRequest, routeLegacy, limited)
+		abortWithError(c, limited)
 		return
 	}
 
-	rtmToken, tokenErr := rtmtokenbuilder2.BuildToken(s.appID, s.appCertificate, uidStr, expire, "")
+	rtmToken, tokenErr := rtmtokenbuilder2.BuildToken(project.AppID, project.AppCertificate, uidStr, expire, "")
 
 	if tokenErr != nil {
-		c.Error(tokenErr)
-		errMsg := "Error Generating RTM token: " + tokenErr.Error()
-		c.AbortWithStatusJSON(400, gin.H{
-			"error":  errMsg,
-			"status": 400,
-		})
+		s.recordToken(c.Request.Context(), rtmRequest, routeLegacy, tokenErr)
+		abortWithError(c, tokenErr)
 	} else {
-		log.Println("RTM Token generated")
+		s.recordToken(c.Request.Context(), rtmRequest, routeLegacy, nil)
+		s.auditTokens(c.Request.Context(), rtmRequest, routeLegacy, rtmToken)
 		c.JSON(200, gin.H{
 			"rtmToken": rtmToken,
 		})
@@ -75,29 +97,41 @@ func (s *Service) getRtmToken(c *gin.Context) {
 }
 
 func (s *Service) getChatToken(c *gin.Context) {
-	log.Println("Generating Chat token")
 	// get param values
 	uidStr, tokenType, expireTimestamp, err := s.parseChatParams(c)
+	project, projectErr := s.lookupProject(c.Param("project"))
+	if err == nil {
+		err = projectErr
+	}
 
 	if err != nil {
-		c.AbortWithStatusJSON(400, gin.H{
-			"message": "Error Generating Chat token: " + err.Error(),
-			"status":  400,
-		})
+		s.recordToken(c.Request.Context(), TokenRequest{TokenType: "chat"}, routeLegacy, err)
+		abortWithError(c, err)
+		return
+	}
+
+	chatRequest := TokenRequest{
+		TokenType: "chat", Uid: uidStr, ExpirationSeconds: int(expireTimestamp), Project: c.Param("project"),
+	}
+	if violation := s.authorize(c.Request.Context(), chatRequest); violation != nil {
+		s.recordToken(c.Request.Context(), chatRequest, routeLegacy, violation)
+		abortWithError(c, violation)
+		return
+	}
+	if limited := s.rateLimit(c.Request.Context(), chatRequest); limited != nil {
+		s.recordToken(c.Request.Context(), chatRequest, routeLegacy, limited)
+		abortWithError(c, limited)
 		return
 	}
 
-	chatToken, tokenErr := s.generateChatToken(uidStr, tokenType, expireTimestamp)
+	chatToken, tokenErr := s.generateChatToken(project, uidStr, tokenType, expireTimestamp)
 
 	if tokenErr != nil {
-		c.Error(tokenErr)
-		errMsg := "Error Generating Chat token: " + tokenErr.Error()
-		c.AbortWithStatusJSON(400, gin.H{
-			"error":  errMsg,
-			"status": 400,
-		})
+		s.recordToken(c.Request.Context(), chatRequest, routeLegacy, tokenErr)
+		abortWithError(c, tokenErr)
 	} else {
-		log.Println("Chat Token generated")
+		s.recordToken(c.Request.Context(), chatRequest, routeLegacy, nil)
+		s.auditTokens(c.Request.Context(), chatRequest, routeLegacy, chatToken)
 		c.JSON(200, gin.H{
 			"chatToken": chatToken,
 		})
@@ -105,42 +139,57 @@ func (s *Service) getChatToken(c *gin.Context) {
 }
 
 func (s *Service) getRtcRtmToken(c *gin.Context) {
-	log.Println("Generating RTC and RTM tokens")
 	// get rtc param values
 	channelName, tokenType, uidStr, rtmuid, role, expire, rtcParamErr := s.parseRtcParams(c)
 
 	if rtcParamErr == nil && rtmuid == "" {
-		rtcParamErr = fmt.Errorf("failed to parse rtm user ID. Cannot be empty or \"0\"")
+		rtcParamErr = badRequest(CodeMissingUid, "rtmuid", "failed to parse rtm user ID. Cannot be empty or \"0\"")
+	}
+	project, projectErr := s.lookupProject(c.Param("project"))
+	if rtcParamErr == nil {
+		rtcParamErr = projectErr
 	}
 	if rtcParamErr != nil {
-		c.Error(rtcParamErr)
-		c.AbortWithStatusJSON(400, gin.H{
-			"message": "Error Generating RTC and RTM token: " + rtcParamErr.Error(),
-			"status":  400,
-		})
+		s.recordToken(c.Request.Context(), TokenRequest{TokenType: "rtc"}, routeLegacy, rtcParamErr)
+		abortWithError(c, rtcParamErr)
 		return
 	}
+	rtcRequest := TokenRequest{
+		TokenType: "rtc", Channel: channelName, RtcRole: roleName(role), Uid: uidStr,
+		ExpirationSeconds: int(expire), Project: c.Param("project"),
+	}
+	rtmRequest := TokenRequest{
+		TokenType: "rtm", Channel: channelName, Uid: rtmuid,
+		ExpirationSeconds: int(expire), Project: c.Param("project"),
+	}
+	for _, tokenRequest := range []TokenRequest{rtcRequest, rtmRequest} {
+		if violation := s.authorize(c.Request.Context(), tokenRequest); violation != nil {
+			s.recordToken(c.Request.Context(), tokenRequest, routeLegacy, violation)
+			abortWithError(c, violation)
+			return
+		}
+		if limited := s.rateLimit(c.Request.Context(), tokenRequest); limited != nil {
+			s.recordToken(c.Request.Context(), tokenRequest, routeLegacy, limited)
+			abortWithError(c, limited)
+			return
+		}
+	}
 	// generate the rtcToken
-	rtcToken, rtcTokenErr := s.generateRtcToken(channelName, uidStr, tokenType, role, expire)
+	rtcToken, rtcTokenErr := s.generateRtcToken(project, channelName, uidStr, tokenType, role, expire)
 	// generate rtmToken
-	rtmToken, rtmTokenErr := rtmtokenbuilder2.BuildToken(s.appID, s.appCertificate, rtmuid, expire, channelName)
+	rtmToken, rtmTokenErr := rtmtokenbuilder2.BuildToken(project.AppID, project.AppCertificate, rtmuid, expire, channelName)
 
 	if rtcTokenErr != nil {
-		c.Error(rtcTokenErr)
-		errMsg := "Error Generating RTC token - " + rtcTokenErr.Error()
-		c.AbortWithStatusJSON(400, gin.H{
-			"status": 400,
-			"error":  errMsg,
-		})
+		s.recordToken(c.Request.Context(), rtcRequest, routeLegacy, rtcTokenErr)
+		abortWithError(c, rtcTokenErr)
 	} else if rtmTokenErr != nil {
-		c.Error(rtmTokenErr)
-		errMsg := "Error Generating RTM token - " + rtmTokenErr.Error()
-		c.AbortWithStatusJSON(400, gin.H{
-			"status": 400,
-			"error":  errMsg,
-		})
+		s.recordToken(c.Request.Context(), rtmRequest, routeLegacy, rtmTokenErr)
+		abortWithError(c, rtmTokenErr)
 	} else {
-		log.Println("RTC and RTM Tokens generated")
+		s.recordToken(c.Request.Context(), rtcRequest, routeLegacy, nil)
+		s.recordToken(c.Request.Context(), rtmRequest, routeLegacy, nil)
+		s.auditTokens(c.Request.Context(), rtcRequest, routeLegacy, rtcToken)
+		s.auditTokens(c.Request.Context(), rtmRequest, routeLegacy, rtmToken)
 		c.JSON(200, gin.H{
 			"rtcToken": rtcToken,
 			"rtmToken": rtmToken,
@@ -149,6 +198,14 @@ func (s *Service) getRtcRtmToken(c *gin.Context) {
 
 }
 
+// roleName returns the TokenRequest role name of an RTC role.
+func roleName(role rtctokenbuilder2.Role) string {
+	if role == rtctokenbuilder2.RolePublisher {
+		return "publisher"
+	}
+	return "subscriber"
+}
+
 func (s *Service) nocache() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// set headers
@@ -158,21 +215,32 @@ func (s *Service) nocache() gin.HandlerFunc {
 	}
 }
 
+// projectMiddleware resolves the project from the /projects/:project route prefix.
+// Unknown projects are rejected, known ones are stored in the request context for the POST handlers.
+func (s *Service) projectMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("project")
+		if _, err := s.lookupProject(name); err != nil {
+			abortWithError(c, err)
+			return
+		}
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), projectContextKey{}, name))
+		c.Next()
+	}
+}
+
 // Add CORSMiddleware to handle CORS requests and set the necessary headers
 func (s *Service) CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		origin := c.Request.Header.Get("Origin")
 		if !s.isOriginAllowed(origin) {
-			c.Header("Content-Type", "application/json")
-			c.JSON(http.StatusForbidden, gin.H{
-				"error": "Origin not allowed",
-			})
-			c.Abort()
+			abortWithError(c, &APIError{Status: http.StatusForbidden, Code: CodeOriginNotAllowed, Message: "Origin not allowed"})
 			return
 		}
 		c.Header("Access-Control-Allow-Origin", origin)
 		c.Header("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization, X-API-Key, X-Auth-Key-Id, X-Auth-Timestamp, X-Auth-Signature, X-Request-ID")
+		c.Header("Access-Control-Expose-Headers", "Retry-After, X-Request-ID")
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(http.StatusNoContent)
 			return
@@ -1,14 +1,12 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
-	"strconv"
 
-	"github.com/AgoraIO-Community/go-tokenbuilder/chatTokenBuilder"
-	rtctokenbuilder2 "github.com/AgoraIO-Community/go-tokenbuilder/rtctokenbuilder"
-	rtmtokenbuilder2 "github.com/AgoraIO-Community/go-tokenbuilder/rtmtokenbuilder"
 	"github.com/gin-gonic/gin"
 )
 
@@ -17,12 +15,37 @@ import (
 // The "Channel", "RtcRole", "Uid", and "ExpirationSeconds" fields are used for specific token types.
 //
 // TokenType options: "rtc" for RTC token, "rtm" for RTM token, and "chat" for chat token.
+//
+// The "JoinChannelExpire", "PubAudioExpire", "PubVideoExpire" and "PubDataStreamExpire" fields are optional
+// and only used for RTC tokens. When any of them is set, each privilege gets its own expiration, falling back
+// to "ExpirationSeconds" for the privileges that are left unset.
+//
+// The "Services" field requests a single AccessToken2 embedding several services, e.g. ["rtc", "rtm"], instead of
+// the "TokenType". With "SeparateTokens" set, one token is returned for each of the services instead.
+//
+// The "Project" field selects which registered Agora project signs the token. It can also be set through
+// the /projects/:project route prefix, and defaults to the project configured with APP_ID and APP_CERTIFICATE.
 type TokenRequest struct {
-	TokenType         string `json:"tokenType"`         // The token type: "rtc", "rtm", or "chat"
-	Channel           string `json:"channel,omitempty"` // The channel name (used for RTC and RTM tokens)
-	RtcRole           string `json:"role,omitempty"`    // The role of the user for RTC tokens (publisher or subscriber)
-	Uid               string `json:"uid,omitempty"`     // The user ID or account (used for RTC, RTM, and some chat tokens)
-	ExpirationSeconds int    `json:"expire,omitempty"`  // The token expiration time in seconds (used for all token types)
+	TokenType           string   `json:"tokenType"`                     // The token type: "rtc", "rtm", or "chat"
+	Channel             string   `json:"channel,omitempty"`             // The channel name (used for RTC and RTM tokens)
+	RtcRole             string   `json:"role,omitempty"`                // The role of the user for RTC tokens (publisher or subscriber)
+	Uid                 string   `json:"uid,omitempty"`                 // The user ID or account (used for RTC, RTM, and some chat tokens)
+	ExpirationSeconds   int      `json:"expire,omitempty"`              // The token expiration time in seconds (used for all token types)
+	JoinChannelExpire   int      `json:"joinChannelExpire,omitempty"`   // The join channel privilege expiration in seconds (RTC only)
+	PubAudioExpire      int      `json:"pubAudioExpire,omitempty"`      // The publish audio privilege expiration in seconds (RTC publisher only)
+	PubVideoExpire      int      `json:"pubVideoExpire,omitempty"`      // The publish video privilege expiration in seconds (RTC publisher only)
+	PubDataStreamExpire int      `json:"pubDataStreamExpire,omitempty"` // The publish data stream privilege expiration in seconds (RTC publisher only)
+	Project             string   `json:"project,omitempty"`             // The registered project to sign the token for (default project if empty)
+	Services            []string `json:"services,omitempty"`            // The services to include in a single token: "rtc", "rtm" and/or "chat"
+	SeparateTokens      bool     `json:"separateTokens,omitempty"`      // Whether to return one token per service instead of a single token
+}
+
+// TokenResponse is the JSON response of a successful token request.
+// Requests for a single token are answered with "Token", requests for separate tokens with "Tokens",
+// which maps each requested service to its token.
+type TokenResponse struct {
+	Token  string            `json:"token,omitempty"`  // The generated token
+	Tokens map[string]string `json:"tokens,omitempty"` // The generated tokens, indexed by service
 }
 
 // getToken is a helper function that acts as a proxy to the GetToken method.
@@ -56,14 +79,19 @@ func (s *Service) getToken(c *gin.Context) {
 //
 // Behavior:
 //  1. Retrieves the tokenType from the query parameters. Error if invalid entry or not provided.
-//  2. Uses a switch statement to handle different tokenType cases:
+//  2. Evaluates the policy for the authenticated principal, responding with 403 Forbidden
+//     and the failing rule if the request is denied.
+//  3. Uses a switch statement to handle different tokenType cases:
 //     - "rtm": Calls the RtmToken method to generate the RTM token and sends it as a JSON response.
 //     - "chat": Calls the ChatToken method to generate the chat token and sends it as a JSON response.
 //     - Default: Calls the RtcToken method to generate the RTC token and sends it as a JSON response.
+//  4. When "services" is set instead of the tokenType, generates a single multi-service token with
+//     GenMultiServiceToken, or one token per service with GenServiceTokens if "separateTokens" is set.
 //
 // Notes:
 //   - The actual token generation methods (RtmToken, ChatToken, and RtcToken) are part of the Service struct.
 //   - The generated token is sent as a JSON response with appropriate HTTP status codes.
+//   - Errors are sent as an ErrorResponse, or as RFC 7807 problem details if the client accepts them.
 //
 // Example usage:
 //
@@ -73,196 +101,229 @@ func (s *Service) GetToken(w http.ResponseWriter, r *http.Request) {
 	// Parse the request body into a TokenRequest struct
 	err := json.NewDecoder(r.Body).Decode(&tokenReq)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		err = badRequest(CodeInvalidJSON, "", "%s", err)
+		s.recordToken(r.Context(), tokenReq, routePost, err)
+		writeError(w, r, err)
 		return
 	}
 
-	var token string
-	var tokenErr error
-
-	switch tokenReq.TokenType {
-	case "rtc":
-		token, tokenErr = s.GenRtcToken(tokenReq)
-	case "rtm":
-		token, tokenErr = s.GenRtmToken(tokenReq)
-	case "chat":
-		token, tokenErr = s.GenChatToken(tokenReq)
-	default:
-		http.Error(w, "Unsupported tokenType", http.StatusBadRequest)
-		return
-	}
+	tokenReq, response, tokenErr := s.issueToken(r.Context(), tokenReq)
+	s.recordToken(r.Context(), tokenReq, routePost, tokenErr)
 	if tokenErr != nil {
-		http.Error(w, tokenErr.Error(), http.StatusBadRequest)
+		writeError(w, r, tokenErr)
 		return
 	}
-
-	response := struct {
-		Token string `json:"token"`
-	}{Token: token}
+	s.auditResponse(r.Context(), tokenReq, routePost, response)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
 }
 
-// GenRtcToken generates an RTC token based on the provided TokenRequest and returns it.
-//
-// Parameters:
-//   - tokenRequest: TokenRequest - The TokenRequest struct containing the required information for RTC token generation.
-//
-// Returns:
-//   - string: The generated RTC token.
-//   - error: An error if there are any issues during token generation or validation.
-//
-// Behavior:
-//  1. Validates the required fields in the TokenRequest (channel and UID).
-//  2. Sets a default expiration time of 3600 seconds (1 hour) if not provided in the request.
-//  3. Determines the user's role (publisher or subscriber) based on the "Role" field in the request.
-//  4. Generates the RTC token using the rtctokenbuilder2 package.
-//
-// Notes:
-//   - The rtctokenbuilder2 package is used for generating RTC tokens.
-//   - The "Role" field can be "publisher" or "subscriber"; other values are considered invalid.
+var (
+	// errUnsupportedTokenType is returned for token requests with a tokenType other than "rtc", "rtm" or "chat".
+	errUnsupportedTokenType = badRequest(CodeUnsupportedTokenType, "tokenType", "Unsupported tokenType")
+
+	// errMissingChannel is returned for RTC token requests without a channel name.
+	errMissingChannel = badRequest(CodeMissingChannel, "channel", "invalid: missing channel name")
+
+	// errMissingUid is returned for RTC and RTM token requests without a user ID or account.
+	errMissingUid = badRequest(CodeMissingUid, "uid", "invalid: missing user ID or account")
+)
+
+// issueToken authorizes the token request against the policy and generates the token of the requested type.
+// It is shared by the POST endpoints, and applies the project selected through the route prefix. The token
+// request is returned resolved, with the route project and default expiration, so it is logged and audited as issued.
+func (s *Service) issueToken(ctx context.Context, tokenReq TokenRequest) (TokenRequest, TokenResponse, error) {
+	if project := routeProject(ctx); project != "" {
+		if tokenReq.Project != "" && tokenReq.Project != project {
+			return tokenReq, TokenResponse{}, badRequest(CodeConflictingFields, "project", "invalid: project does not match the route project")
+		}
+		tokenReq.Project = project
+	}
+	if tokenReq.ExpirationSeconds == 0 && !tokenReq.hasPrivilegeExpires() {
+		// Policies check the expiration the token is issued with
+		tokenReq.ExpirationSeconds = s.tokenGenerator().defaultExpire()
+	}
+	for _, serviceReq := range tokenReq.serviceRequests() {
+		if err := s.tokenGenerator().validateTokenRequest(serviceReq); err != nil {
+			return tokenReq, TokenResponse{}, err
+		}
+		if violation := s.authorize(ctx, serviceReq); violation != nil {
+			return tokenReq, TokenResponse{}, violation
+		}
+		if limited := s.rateLimit(ctx, serviceReq); limited != nil {
+			return tokenReq, TokenResponse{}, limited
+		}
+	}
+
+	var response TokenResponse
+	var err error
+	generator := s.tokenGenerator()
+	switch {
+	case len(tokenReq.Services) > 0 && tokenReq.SeparateTokens:
+		response.Tokens, err = generator.GenServiceTokens(tokenReq)
+	case len(tokenReq.Services) > 0:
+		response.Token, err = generator.GenMultiServiceToken(tokenReq)
+	case tokenReq.TokenType == "rtc":
+		response.Token, err = generator.GenRtcToken(tokenReq)
+	case tokenReq.TokenType == "rtm":
+		response.Token, err = generator.GenRtmToken(tokenReq)
+	case tokenReq.TokenType == "chat":
+		response.Token, err = generator.GenChatToken(tokenReq)
+	default:
+		err = errUnsupportedTokenType
+	}
+	return tokenReq, response, err
+}
+
+// InspectTokenRequest is a struct representing the JSON payload structure for token inspection requests.
+type InspectTokenRequest struct {
+	Token string `json:"token"` // The AccessToken2 token to decode and verify
+}
+
+// inspectToken handles the HTTP request to decode and verify a token.
+// It parses the InspectTokenRequest from the request body and responds with the TokenInfo returned by InspectToken.
+// Malformed tokens are rejected with 400 Bad Request, while tokens that fail verification are still
+// returned, with "signatureValid" set to false.
 //
 // Example usage:
 //
-//	tokenReq := TokenRequest{
-//	    TokenType:  "rtc",
-//	    Channel:    "my_channel",
-//	    Uid:        "user123",
-//	    Role:       "publisher",
-//	    ExpirationSeconds: 3600,
-//	}
-//	token, err := service.GenRtcToken(tokenReq)
-func (s *Service) GenRtcToken(tokenRequest TokenRequest) (string, error) {
-	if tokenRequest.Channel == "" {
-		return "", errors.New("invalid: missing channel name")
+//	router.POST("/inspectToken", service.inspectToken)
+func (s *Service) inspectToken(c *gin.Context) {
+	var inspectReq InspectTokenRequest
+	if err := decodeJSONBody(c.Writer, c.Request, maxInspectBodySize, &inspectReq); err != nil {
+		abortWithError(c, err)
+		return
 	}
-	if tokenRequest.Uid == "" {
-		return "", errors.New("invalid: missing user ID or account")
+	if inspectReq.Token == "" {
+		abortWithError(c, badRequest(CodeMissingToken, "token", "invalid: missing token"))
+		return
 	}
 
-	var userRole rtctokenbuilder2.Role
-	if tokenRequest.RtcRole == "publisher" {
-		userRole = rtctokenbuilder2.RolePublisher
-	} else {
-		userRole = rtctokenbuilder2.RoleSubscriber
+	info, err := s.InspectToken(inspectReq.Token)
+	if err != nil {
+		abortWithError(c, badRequest(CodeInvalidToken, "token", "%s", err))
+		return
 	}
 
-	if tokenRequest.ExpirationSeconds == 0 {
-		tokenRequest.ExpirationSeconds = 3600
+	c.JSON(http.StatusOK, info)
+}
+
+// maxInspectBodySize is the largest body of an inspectToken request, which holds a single token.
+const maxInspectBodySize = 16 << 10
+
+// maxRequestBodySize returns the largest body accepted by the token routes, the one of a full getTokens batch
+// or of an inspectToken request. The caller must hold mu for reading.
+func (s *Service) maxRequestBodySize() int64 {
+	return max(maxInspectBodySize, int64(s.batchMaxSize)*maxBatchItemSize)
+}
+
+// decodeJSONBody decodes the JSON request body into v. Bodies larger than maxSize are rejected with
+// 413 Request Entity Too Large, without being read past the limit.
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, maxSize int64, v any) error {
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSize)).Decode(v)
+	if tooLarge := bodyTooLarge(err, maxSize); tooLarge != nil {
+		return tooLarge
 	}
+	if err != nil {
+		return badRequest(CodeInvalidJSON, "", "%s", err)
+	}
+	return nil
+}
 
-	uid64, parseErr := strconv.ParseUint(tokenRequest.Uid, 10, 64)
-	if parseErr != nil {
-		return rtctokenbuilder2.BuildTokenWithAccount(
-			s.appID, s.appCertificate, tokenRequest.Channel,
-			tokenRequest.Uid, userRole, uint32(tokenRequest.ExpirationSeconds),
-		)
+// bodyTooLarge returns the BODY_TOO_LARGE APIError when err comes from reading a body past its maxSize limit,
+// and nil otherwise.
+func bodyTooLarge(err error, maxSize int64) *APIError {
+	var tooLarge *http.MaxBytesError
+	if !errors.As(err, &tooLarge) {
+		return nil
 	}
+	return &APIError{
+		Status: http.StatusRequestEntityTooLarge, Code: CodeBodyTooLarge,
+		Message: fmt.Sprintf("invalid: request body exceeds %d bytes", maxSize),
+	}
+}
 
-	return rtctokenbuilder2.BuildTokenWithUid(
-		s.appID, s.appCertificate, tokenRequest.Channel,
-		uint32(uid64), userRole, uint32(tokenRequest.ExpirationSeconds),
-	)
+// validateServices checks that the requested services are known and listed once, without a tokenType.
+func (tokenRequest TokenRequest) validateServices() error {
+	if len(tokenRequest.Services) == 0 {
+		return badRequest(CodeMissingServices, "services", "invalid: missing services")
+	}
+	if tokenRequest.TokenType != "" {
+		return badRequest(CodeConflictingFields, "tokenType", "invalid: tokenType can not be combined with services")
+	}
+	for i, service := range tokenRequest.Services {
+		if service != "rtc" && service != "rtm" && service != "chat" {
+			return badRequest(CodeUnsupportedService, "services", "invalid: unsupported service: %s", service)
+		}
+		if contains(tokenRequest.Services[:i], service) {
+			return badRequest(CodeDuplicateService, "services", "invalid: duplicate service: %s", service)
+		}
+	}
+	return nil
 }
 
-// GenRtmToken generates an RTM (Real-Time Messaging) token based on the provided TokenRequest and returns it.
-//
-// Parameters:
-//   - tokenRequest: TokenRequest - The TokenRequest struct containing the required information for RTM token generation.
-//
-// Returns:
-//   - string: The generated RTM token.
-//   - error: An error if there are any issues during token generation or validation.
-//
-// Behavior:
-//  1. Validates the required field in the TokenRequest (UID).
-//  2. Sets a default expiration time of 3600 seconds (1 hour) if not provided in the request.
-//  3. Generates the RTM token using the rtmtokenbuilder2 package.
-//
-// Notes:
-//   - The rtmtokenbuilder2 package is used for generating RTM tokens.
-//   - The "UID" field in TokenRequest is mandatory for RTM token generation.
-//
-// Example usage:
-//
-//	tokenReq := TokenRequest{
-//	    TokenType:  "rtm",
-//	    Uid:        "user123",
-//	    ExpirationSeconds: 3600,
-//	}
-//	token, err := service.GenRtmToken(tokenReq)
-func (s *Service) GenRtmToken(tokenRequest TokenRequest) (string, error) {
-	if tokenRequest.Uid == "" {
-		return "", errors.New("invalid: missing user ID or account")
+// serviceRequests splits a multi-service request into one single token request per service.
+// Requests for a single tokenType are returned as is.
+func (tokenRequest TokenRequest) serviceRequests() []TokenRequest {
+	if len(tokenRequest.Services) == 0 {
+		return []TokenRequest{tokenRequest}
 	}
-	if tokenRequest.ExpirationSeconds == 0 {
-		tokenRequest.ExpirationSeconds = 3600
+	serviceReqs := make([]TokenRequest, 0, len(tokenRequest.Services))
+	for _, service := range tokenRequest.Services {
+		serviceReq := tokenRequest
+		serviceReq.TokenType = service
+		serviceReq.Services = nil
+		serviceReq.SeparateTokens = false
+		serviceReqs = append(serviceReqs, serviceReq)
 	}
+	return serviceReqs
+}
 
-	return rtmtokenbuilder2.BuildToken(
-		s.appID, s.appCertificate,
-		tokenRequest.Uid,
-		uint32(tokenRequest.ExpirationSeconds),
-		tokenRequest.Channel,
-	)
+// tokenGenerator returns a TokenGenerator of the current projects and expiration range of the service.
+// Certificate rotations apply to it right away, as it looks the projects up on each token request.
+// The caller must hold mu for reading; the exported Gen methods take it themselves.
+func (s *Service) tokenGenerator() *TokenGenerator {
+	return &TokenGenerator{lookupProject: s.lookupProject, minExpire: s.minExpire, maxExpire: s.maxExpire}
 }
 
-// GenChatToken generates a chat token based on the provided TokenRequest and returns it.
-//
-// Parameters:
-//   - tokenRequest: TokenRequest - The TokenRequest struct containing the required information for chat token generation.
-//
-// Returns:
-//   - string: The generated chat token.
-//   - error: An error if there are any issues during token generation or validation.
-//
-// Behavior:
-//  1. Sets a default expiration time of 3600 seconds (1 hour) if not provided in the request.
-//  2. Determines whether to generate a chat app token or a chat user token based on the "UID" field in the request.
-//  3. Generates the chat token using the chatTokenBuilder package.
-//
-// Notes:
-//   - The chatTokenBuilder package is used for generating chat tokens.
-//   - If the "UID" field is empty, a chat app token is generated; otherwise, a chat user token is generated.
-//
-// Example usage:
-//
-//	// Generate a chat app token
-//	tokenReq := TokenRequest{
-//	    TokenType:  "chat",
-//	    ExpirationSeconds: 3600,
-//	}
-//	token, err := service.GenChatToken(tokenReq)
-//
-//	// Generate a chat user token
-//	tokenReq := TokenRequest{
-//	    TokenType:  "chat",
-//	    Uid:        "user123",
-//	    ExpirationSeconds: 3600,
-//	}
-//	token, err := service.GenChatToken(tokenReq)
-func (s *Service) GenChatToken(tokenRequest TokenRequest) (string, error) {
-	if tokenRequest.ExpirationSeconds == 0 {
-		tokenRequest.ExpirationSeconds = 3600
-	}
+// GenRtcToken generates an RTC token with the credentials and settings of the service.
+// See TokenGenerator.GenRtcToken.
+func (s *Service) GenRtcToken(tokenRequest TokenRequest) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tokenGenerator().GenRtcToken(tokenRequest)
+}
 
-	var chatToken string
-	var tokenErr error
+// GenRtmToken generates an RTM token with the credentials and settings of the service.
+// See TokenGenerator.GenRtmToken.
+func (s *Service) GenRtmToken(tokenRequest TokenRequest) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tokenGenerator().GenRtmToken(tokenRequest)
+}
 
-	if tokenRequest.Uid == "" {
-		chatToken, tokenErr = chatTokenBuilder.BuildChatAppToken(
-			s.appID, s.appCertificate, uint32(tokenRequest.ExpirationSeconds),
-		)
-	} else {
-		chatToken, tokenErr = chatTokenBuilder.BuildChatUserToken(
-			s.appID, s.appCertificate,
-			tokenRequest.Uid,
-			uint32(tokenRequest.ExpirationSeconds),
-		)
+// GenChatToken generates a chat token with the credentials and settings of the service.
+// See TokenGenerator.GenChatToken.
+func (s *Service) GenChatToken(tokenRequest TokenRequest) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tokenGenerator().GenChatToken(tokenRequest)
+}
 
-	}
+// GenMultiServiceToken generates a single token embedding several services with the credentials and settings
+// of the service. See TokenGenerator.GenMultiServiceToken.
+func (s *Service) GenMultiServiceToken(tokenRequest TokenRequest) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tokenGenerator().GenMultiServiceToken(tokenRequest)
+}
 
-	return chatToken, tokenErr
+// GenServiceTokens generates a separate token for each requested service with the credentials and settings
+// of the service. See TokenGenerator.GenServiceTokens.
+func (s *Service) GenServiceTokens(tokenRequest TokenRequest) (map[string]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tokenGenerator().GenServiceTokens(tokenRequest)
 }
@@ -4,6 +4,8 @@ package service
 import (
 	"os"
 	"testing"
+
+	"github.com/AgoraIO-Community/go-tokenbuilder/accesstoken"
 )
 
 func CreateTestService(t *testing.T) *Service {
@@ -79,6 +81,84 @@ func TestGenRtcToken(t *testing.T) {
 	}
 }
 
+// TestGenRtcTokenPrivilegeExpires tests GenRtcToken with per-privilege expirations.
+func TestGenRtcTokenPrivilegeExpires(t *testing.T) {
+	service := CreateTestService(t)
+
+	// Join for 2 hours, but publish only for 10 minutes
+	tokenReq := TokenRequest{
+		TokenType:         "rtc",
+		Channel:           "my_channel",
+		Uid:               "123",
+		RtcRole:           "publisher",
+		ExpirationSeconds: 7200,
+		PubAudioExpire:    600,
+		PubVideoExpire:    600,
+	}
+
+	token, err := service.GenRtcToken(tokenReq)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	parsed := accesstoken.CreateAccessToken()
+	if ok, err := parsed.Parse(token); !ok || err != nil {
+		t.Fatalf("Failed to parse token: %v", err)
+	}
+	if parsed.Expire != 7200 {
+		t.Errorf("Expected token expiration 7200, got %d", parsed.Expire)
+	}
+	privileges := parsed.Services[accesstoken.ServiceTypeRtc].(*accesstoken.ServiceRtc).Privileges
+	expected := map[uint16]uint32{
+		accesstoken.PrivilegeJoinChannel:        7200,
+		accesstoken.PrivilegePublishAudioStream: 600,
+		accesstoken.PrivilegePublishVideoStream: 600,
+		accesstoken.PrivilegePublishDataStream:  7200,
+	}
+	for privilege, expire := range expected {
+		if privileges[privilege] != expire {
+			t.Errorf("Expected privilege %d to expire in %d, got %d", privilege, expire, privileges[privilege])
+		}
+	}
+
+	// Token expiration defaults to the longest privilege, subscribers only get to join
+	subscriberReq := TokenRequest{
+		TokenType:         "rtc",
+		Channel:           "my_channel",
+		Uid:               "user123",
+		RtcRole:           "subscriber",
+		JoinChannelExpire: 900,
+	}
+	token, err = service.GenRtcToken(subscriberReq)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	parsed = accesstoken.CreateAccessToken()
+	if ok, err := parsed.Parse(token); !ok || err != nil {
+		t.Fatalf("Failed to parse token: %v", err)
+	}
+	if parsed.Expire != 900 {
+		t.Errorf("Expected token expiration 900, got %d", parsed.Expire)
+	}
+	privileges = parsed.Services[accesstoken.ServiceTypeRtc].(*accesstoken.ServiceRtc).Privileges
+	if len(privileges) != 1 || privileges[accesstoken.PrivilegeJoinChannel] != 900 {
+		t.Errorf("Expected only the join channel privilege, got %v", privileges)
+	}
+
+	invalidReqs := []TokenRequest{
+		// Publish privileges for a subscriber
+		{TokenType: "rtc", Channel: "my_channel", Uid: "123", RtcRole: "subscriber", PubAudioExpire: 600},
+		// Privilege outlives the token
+		{TokenType: "rtc", Channel: "my_channel", Uid: "123", RtcRole: "publisher", ExpirationSeconds: 600, JoinChannelExpire: 3600},
+		// Negative privilege expiration
+		{TokenType: "rtc", Channel: "my_channel", Uid: "123", RtcRole: "publisher", PubVideoExpire: -1},
+	}
+	for _, invalidReq := range invalidReqs {
+		if _, err := service.GenRtcToken(invalidReq); err == nil {
+			t.Errorf("Expected error for %+v, but got nil", invalidReq)
+		}
+	}
+}
+
 // TestGenRtmToken tests the genRtmToken function.
 func TestGenRtmToken(t *testing.T) {
 	service := CreateTestService(t)
@@ -179,3 +259,71 @@ func TestGenChatToken(t *testing.T) {
 		t.Errorf("Unexpected error: %v", err)
 	}
 }
+
+// TestGenMultiServiceToken tests the GenMultiServiceToken and GenServiceTokens functions.
+func TestGenMultiServiceToken(t *testing.T) {
+	service := CreateTestService(t)
+
+	tokenReq := TokenRequest{
+		Services:          []string{"rtc", "rtm", "chat"},
+		Channel:           "my_channel",
+		Uid:               "user123",
+		RtcRole:           "publisher",
+		ExpirationSeconds: 1800,
+	}
+	token, err := service.GenMultiServiceToken(tokenReq)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	parsed := accesstoken.CreateAccessToken()
+	if ok, err := parsed.Parse(token); !ok || err != nil {
+		t.Fatalf("Failed to parse token: %v", err)
+	}
+	if parsed.Expire != 1800 {
+		t.Errorf("Expected token expiration 1800, got %d", parsed.Expire)
+	}
+	rtc := parsed.Services[accesstoken.ServiceTypeRtc].(*accesstoken.ServiceRtc)
+	if rtc.ChannelName != "my_channel" || rtc.Uid != "user123" || len(rtc.Privileges) != 4 {
+		t.Errorf("Unexpected RTC service: %+v", rtc)
+	}
+	rtm := parsed.Services[accesstoken.ServiceTypeRtm].(*accesstoken.ServiceRtm)
+	if rtm.UserId != "user123" || rtm.Privileges[accesstoken.PrivilegeLogin] != 1800 {
+		t.Errorf("Unexpected RTM service: %+v", rtm)
+	}
+	chat := parsed.Services[accesstoken.ServiceTypeChat].(*accesstoken.ServiceChat)
+	if chat.UserId != "user123" || chat.Privileges[accesstoken.PrivilegeChatUser] != 1800 {
+		t.Errorf("Unexpected chat service: %+v", chat)
+	}
+
+	// Separate tokens, one per service
+	tokenReq.SeparateTokens = true
+	tokens, err := service.GenServiceTokens(tokenReq)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, serviceType := range tokenReq.Services {
+		if tokens[serviceType] == "" {
+			t.Errorf("Expected a non-empty %s token", serviceType)
+		}
+	}
+
+	invalidReqs := []TokenRequest{
+		// Unknown service
+		{Services: []string{"rtc", "video"}, Channel: "my_channel", Uid: "123"},
+		// Duplicate service
+		{Services: []string{"rtm", "rtm"}, Uid: "123"},
+		// TokenType and services
+		{TokenType: "rtc", Services: []string{"rtc", "rtm"}, Channel: "my_channel", Uid: "123"},
+		// RTC without channel
+		{Services: []string{"rtc", "rtm"}, Uid: "123"},
+		// RTM without uid
+		{Services: []string{"rtm", "chat"}},
+		// Privilege expirations without RTC
+		{Services: []string{"rtm"}, Uid: "123", PubAudioExpire: 600},
+	}
+	for _, invalidReq := range invalidReqs {
+		if _, err := service.GenMultiServiceToken(invalidReq); err == nil {
+			t.Errorf("Expected error for %+v, but got nil", invalidReq)
+		}
+	}
+}
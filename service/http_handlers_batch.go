@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultBatchMaxSize is the default maximum number of token requests in a single batch.
+	defaultBatchMaxSize = 500
+
+	// defaultBatchWorkers is the default number of tokens of a batch generated concurrently.
+	defaultBatchWorkers = 16
+
+	// maxBatchItemSize is the body size allowed per token request of a batch, far above the size of
+	// a valid token request, so oversized batches are rejected before they are decoded.
+	maxBatchItemSize = 2 << 10
+)
+
+// TokenResult is the outcome of a single token request of a batch.
+// Successful items carry the token, failed items the error, along with the HTTP status code and error code
+// the request would have received from POST /getToken.
+type TokenResult struct {
+	Status     int               `json:"status"`               // The status code of the item: 200 on success
+	Token      string            `json:"token,omitempty"`      // The generated token, if successful
+	Tokens     map[string]string `json:"tokens,omitempty"`     // The generated tokens, indexed by service, if separate tokens were requested
+	Code       ErrorCode         `json:"code,omitempty"`       // The machine-readable error code, if the token could not be generated
+	Error      string            `json:"error,omitempty"`      // The error message, if the token could not be generated
+	Field      string            `json:"field,omitempty"`      // The request field at fault, if any
+	Rule       string            `json:"rule,omitempty"`       // The policy rule that denied the item, if any
+	RetryAfter int               `json:"retryAfter,omitempty"` // The seconds until the item may be retried, if rate limited
+}
+
+// getTokens is a helper function that acts as a proxy to the GetTokens method.
+func (s *Service) getTokens(c *gin.Context) {
+	s.GetTokens(c.Writer, c.Request)
+}
+
+// GetTokens handles the HTTP request to generate a batch of tokens in a single round-trip.
+// The request body is a JSON array of TokenRequest items, and the response is a JSON array of
+// TokenResult in the same order.
+//
+// Parameters:
+//   - w: http.ResponseWriter - The HTTP response writer to send the response to the client.
+//   - r: *http.Request - The HTTP request received from the client.
+//
+// Behavior:
+//  1. Parses the request body into a list of TokenRequest, rejecting empty or oversized batches.
+//  2. Generates the tokens concurrently with a bounded pool of workers, applying the same
+//     project selection and policy as POST /getToken to every item.
+//  3. Responds with 200 OK and the per-item results, even if some of the items failed.
+//
+// Notes:
+//   - The batch size and number of workers are configured with BATCH_MAX_SIZE and BATCH_WORKERS.
+//   - Bodies larger than 2 KB per allowed token request are rejected with 413 before being decoded.
+//
+// Example usage:
+//
+//	router.POST("/getTokens", service.GetTokens)
+func (s *Service) GetTokens(w http.ResponseWriter, r *http.Request) {
+	var tokenReqs []TokenRequest
+	if err := decodeJSONBody(w, r, int64(s.batchMaxSize)*maxBatchItemSize, &tokenReqs); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if len(tokenReqs) == 0 {
+		writeError(w, r, errEmptyBatch)
+		return
+	}
+	if len(tokenReqs) > s.batchMaxSize {
+		writeError(w, r, s.errBatchTooLarge())
+		return
+	}
+
+	results := make([]TokenResult, len(tokenReqs))
+	s.generateBatch(len(tokenReqs), func(i int) {
+		results[i] = s.batchTokenResult(r.Context(), tokenReqs[i])
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(results)
+}
+
+// errEmptyBatch is returned for batches without any token request.
+var errEmptyBatch = badRequest(CodeEmptyBatch, "", "invalid: empty batch")
+
+// errBatchTooLarge returns the error of batches with more token requests than the configured maximum.
+func (s *Service) errBatchTooLarge() *APIError {
+	return badRequest(CodeBatchTooLarge, "", "invalid: batch exceeds the maximum of %d items", s.batchMaxSize)
+}
+
+// generateBatch calls generate for every item of a batch of the given size, concurrently with a bounded pool of
+// workers, and returns once every item is generated.
+func (s *Service) generateBatch(size int, generate func(i int)) {
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < s.batchWorkers && worker < size; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				generate(i)
+			}
+		}()
+	}
+	for i := 0; i < size; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// batchTokenResult generates the token of a single batch item.
+func (s *Service) batchTokenResult(ctx context.Context, tokenReq TokenRequest) TokenResult {
+	tokenReq, response, err := s.issueToken(ctx, tokenReq)
+	if err != nil {
+		s.recordToken(ctx, tokenReq, routeBatch, err)
+		response := NewErrorResponse(err)
+		return TokenResult{
+			Status: response.Status, Code: response.Code, Error: response.Error, Field: response.Field,
+			Rule: response.Rule, RetryAfter: response.RetryAfter,
+		}
+	}
+	s.recordToken(ctx, tokenReq, routeBatch, nil)
+	s.auditResponse(ctx, tokenReq, routeBatch, response)
+	return TokenResult{Status: http.StatusOK, Token: response.Token, Tokens: response.Tokens}
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTokens(t *testing.T) {
+	body := []byte(`[
+		{"tokenType": "rtc", "channel": "room", "role": "publisher", "uid": "1"},
+		{"tokenType": "rtc", "channel": "room", "uid": "user2"},
+		{"tokenType": "rtm", "uid": "user3"},
+		{"tokenType": "rtc", "uid": "4"},
+		{"tokenType": "invalid_type"},
+		{"tokenType": "chat", "project": "unknown"}
+	]`)
+	req, err := http.NewRequest(http.MethodPost, "/getTokens", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := httptest.NewRecorder()
+	testService.Server.Handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code, resp.Body)
+
+	var results []TokenResult
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		t.Fatal(err)
+	}
+	expectedStatus := []int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusBadRequest, http.StatusBadRequest, http.StatusNotFound}
+	if assert.Len(t, results, len(expectedStatus)) {
+		for i, result := range results {
+			assert.Equal(t, expectedStatus[i], result.Status, i)
+			if result.Status == http.StatusOK {
+				assert.NotEmpty(t, result.Token, i)
+				assert.Empty(t, result.Error, i)
+			} else {
+				assert.Empty(t, result.Token, i)
+				assert.NotEmpty(t, result.Error, i)
+			}
+		}
+	}
+
+	// Results keep the order of the requests
+	info, err := testService.InspectToken(results[1].Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "user2", info.Services[0].Uid)
+}
+
+func TestGetTokensLargeBatch(t *testing.T) {
+	items := make([]string, defaultBatchMaxSize)
+	for i := range items {
+		items[i] = fmt.Sprintf(`{"tokenType": "rtc", "channel": "room", "uid": "%d"}`, i+1)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "/getTokens", strings.NewReader("["+strings.Join(items, ",")+"]"))
+	resp := httptest.NewRecorder()
+	testService.Server.Handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var results []TokenResult
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, results, defaultBatchMaxSize) {
+		info, err := testService.InspectToken(results[defaultBatchMaxSize-1].Token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, fmt.Sprint(defaultBatchMaxSize), info.Services[0].Uid)
+	}
+
+	// Oversized, empty and malformed batches are rejected as a whole
+	tests := []UrlCodePair{
+		{"/getTokens", http.StatusBadRequest, []byte("[" + strings.Join(append(items, items[0]), ",") + "]")},
+		{"/getTokens", http.StatusBadRequest, []byte(`[]`)},
+		{"/getTokens", http.StatusBadRequest, []byte(`{"tokenType": "rtm", "uid": "user3"}`)},
+		// Bodies too large for any valid batch are not decoded
+		{"/getTokens", http.StatusRequestEntityTooLarge, []byte("[" + strings.Repeat(items[0]+",", defaultBatchMaxSize*maxBatchItemSize/len(items[0])) + items[0] + "]")},
+	}
+	for _, httpTest := range tests {
+		req, _ := http.NewRequest(http.MethodPost, httpTest.url, bytes.NewBuffer(httpTest.body))
+		resp := httptest.NewRecorder()
+		testService.Server.Handler.ServeHTTP(resp, req)
+		assert.Equal(t, httpTest.code, resp.Code, resp.Body)
+	}
+}
+
+func TestGetTokensPolicy(t *testing.T) {
+	t.Setenv("POLICY_FILE", writePolicy(t, testPolicy))
+	service := NewService()
+
+	body := []byte(`[
+		{"tokenType": "rtc", "channel": "room", "uid": "1"},
+		{"tokenType": "rtc", "channel": "room", "uid": "2", "role": "publisher"}
+	]`)
+	req, _ := http.NewRequest(http.MethodPost, "/getTokens", bytes.NewBuffer(body))
+	resp := httptest.NewRecorder()
+	service.Server.Handler.ServeHTTP(resp, req)
+
+	var results []TokenResult
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, http.StatusOK, results[0].Status)
+		assert.Equal(t, http.StatusForbidden, results[1].Status)
+		assert.Equal(t, "only-hosts-publish", results[1].Rule)
+	}
+}
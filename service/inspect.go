@@ -0,0 +1,283 @@
+package service
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/AgoraIO-Community/go-tokenbuilder/accesstoken"
+)
+
+// maxTokenContentSize is the largest decompressed content of a token, far above the content of the tokens
+// of every service, so decompressing a crafted token can not exhaust the memory.
+const maxTokenContentSize = 4 << 10
+
+// TokenInfo is the decoded content of an AccessToken2 ("007") token, as returned by InspectToken.
+type TokenInfo struct {
+	AppID          string        `json:"appId"`                // The App ID the token was issued for
+	IssueTs        uint32        `json:"issueTs"`              // The unix timestamp at which the token was issued
+	Salt           uint32        `json:"salt"`                 // The random salt used when signing the token
+	Expire         uint32        `json:"expire"`               // The token lifetime in seconds, counted from IssueTs
+	ExpiresAt      uint32        `json:"expiresAt"`            // The unix timestamp at which the token expires
+	Expired        bool          `json:"expired"`              // Whether the token has already expired
+	SignatureValid bool          `json:"signatureValid"`       // Whether the signature matches a certificate of the project with AppID
+	SignedWith     string        `json:"signedWith,omitempty"` // The matching certificate: "active" or "previous"
+	Project        string        `json:"project,omitempty"`    // The registered project with AppID, empty for the default project
+	Services       []ServiceInfo `json:"services"`             // The services contained in the token
+}
+
+// ServiceInfo describes a single service embedded in a token.
+type ServiceInfo struct {
+	Type       string          `json:"type"`              // The service type: "rtc", "rtm", "fpa", "chat" or "education"
+	Channel    string          `json:"channel,omitempty"` // The channel name (RTC only)
+	Uid        string          `json:"uid,omitempty"`     // The user ID or account (RTC, RTM and chat user tokens)
+	Privileges []PrivilegeInfo `json:"privileges"`        // The privileges granted for the service
+}
+
+// PrivilegeInfo describes a single privilege of a service and when it expires.
+type PrivilegeInfo struct {
+	Name      string `json:"name"`      // The privilege name, e.g. "joinChannel" or "publishAudioStream"
+	Expire    uint32 `json:"expire"`    // The privilege lifetime in seconds, counted from the token IssueTs
+	ExpiresAt uint32 `json:"expiresAt"` // The unix timestamp at which the privilege expires
+}
+
+var serviceTypeNames = map[uint16]string{
+	accesstoken.ServiceTypeRtc:       "rtc",
+	accesstoken.ServiceTypeRtm:       "rtm",
+	accesstoken.ServiceTypeFpa:       "fpa",
+	accesstoken.ServiceTypeChat:      "chat",
+	accesstoken.ServiceTypeEducation: "education",
+}
+
+var privilegeNames = map[uint16]map[uint16]string{
+	accesstoken.ServiceTypeRtc: {
+		accesstoken.PrivilegeJoinChannel:        "joinChannel",
+		accesstoken.PrivilegePublishAudioStream: "publishAudioStream",
+		accesstoken.PrivilegePublishVideoStream: "publishVideoStream",
+		accesstoken.PrivilegePublishDataStream:  "publishDataStream",
+	},
+	accesstoken.ServiceTypeRtm: {accesstoken.PrivilegeLogin: "login"},
+	accesstoken.ServiceTypeFpa: {accesstoken.PrivilegeLogin: "login"},
+	accesstoken.ServiceTypeChat: {
+		accesstoken.PrivilegeChatUser: "user",
+		accesstoken.PrivilegeChatApp:  "app",
+	},
+	accesstoken.ServiceTypeEducation: {
+		accesstoken.PrivilegeEducationRoomUser: "roomUser",
+		accesstoken.PrivilegeEducationUser:     "user",
+		accesstoken.PrivilegeEducationApp:      "app",
+	},
+}
+
+// InspectToken decodes an AccessToken2 ("007") token and verifies its signature against the project's app certificate.
+//
+// Parameters:
+//   - token: string - The token to inspect.
+//
+// Returns:
+//   - *TokenInfo: The decoded token content.
+//   - error: An error if the token is malformed and could not be decoded.
+//
+// Behavior:
+//  1. Checks the version prefix, then base64 and zlib decodes the token.
+//  2. Unpacks the app ID, issue timestamp, expiration, salt and the embedded services.
+//  3. Looks up the registered project with the token's App ID, recomputes the signature using
+//     its active app certificate, then each of its previous certificates, and compares it with the one in the token.
+//
+// Notes:
+//   - A token that decodes correctly but fails signature verification, has expired, or was issued for
+//     an App ID that is not registered, is not an error.
+//     Check the SignatureValid and Expired fields of the result instead.
+//
+// Example usage:
+//
+//	info, err := service.InspectToken("007eJxTYBBbsfRc...")
+func (s *Service) InspectToken(token string) (*TokenInfo, error) {
+	signature, content, err := decodeToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := unpackTokenContent(content)
+	if err != nil {
+		return nil, err
+	}
+	if project, found := s.projectForAppID(info.AppID); found {
+		info.Project = project.Name
+		for i, certificate := range project.certificates() {
+			if hmac.Equal(signature, signTokenContent(certificate, info.IssueTs, info.Salt, content)) {
+				info.SignatureValid = true
+				info.SignedWith = "active"
+				if i > 0 {
+					info.SignedWith = "previous"
+				}
+				break
+			}
+		}
+	}
+	info.Expired = time.Now().Unix() >= int64(info.ExpiresAt)
+
+	return info, nil
+}
+
+// decodeToken splits a token into its signature and the signed content.
+func decodeToken(token string) (signature []byte, content []byte, err error) {
+	if len(token) <= accesstoken.VersionLength || token[:accesstoken.VersionLength] != accesstoken.Version {
+		return nil, nil, fmt.Errorf("invalid: token does not start with version %s", accesstoken.Version)
+	}
+
+	compressed, err := base64.StdEncoding.DecodeString(token[accesstoken.VersionLength:])
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid: failed to base64 decode token: %s", err)
+	}
+	zlibReader, err := zlib.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid: failed to decompress token: %s", err)
+	}
+	defer zlibReader.Close()
+	decompressed, err := io.ReadAll(io.LimitReader(zlibReader, maxTokenContentSize+1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid: failed to decompress token: %s", err)
+	}
+	if len(decompressed) > maxTokenContentSize {
+		return nil, nil, fmt.Errorf("invalid: token content exceeds %d bytes", maxTokenContentSize)
+	}
+
+	reader := bytes.NewReader(decompressed)
+	signatureStr, err := readString(reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid: failed to read token signature: %s", err)
+	}
+
+	return []byte(signatureStr), decompressed[len(decompressed)-reader.Len():], nil
+}
+
+// unpackTokenContent reads the token fields and services from the signed content.
+func unpackTokenContent(content []byte) (*TokenInfo, error) {
+	reader := bytes.NewReader(content)
+	info := &TokenInfo{Services: []ServiceInfo{}}
+
+	var err error
+	if info.AppID, err = readString(reader); err != nil {
+		return nil, fmt.Errorf("invalid: failed to read app ID: %s", err)
+	}
+	for _, field := range []*uint32{&info.IssueTs, &info.Expire, &info.Salt} {
+		if err = binary.Read(reader, binary.LittleEndian, field); err != nil {
+			return nil, fmt.Errorf("invalid: failed to read token header: %s", err)
+		}
+	}
+	info.ExpiresAt = info.IssueTs + info.Expire
+
+	var serviceCount uint16
+	if err = binary.Read(reader, binary.LittleEndian, &serviceCount); err != nil {
+		return nil, fmt.Errorf("invalid: failed to read service count: %s", err)
+	}
+	for i := 0; i < int(serviceCount); i++ {
+		service, err := unpackService(reader, info.IssueTs)
+		if err != nil {
+			return nil, err
+		}
+		info.Services = append(info.Services, service)
+	}
+
+	return info, nil
+}
+
+// unpackService reads a single service, including its privileges and type specific fields.
+func unpackService(reader *bytes.Reader, issueTs uint32) (ServiceInfo, error) {
+	var service ServiceInfo
+
+	var serviceType uint16
+	if err := binary.Read(reader, binary.LittleEndian, &serviceType); err != nil {
+		return service, fmt.Errorf("invalid: failed to read service type: %s", err)
+	}
+	typeName, known := serviceTypeNames[serviceType]
+	if !known {
+		return service, fmt.Errorf("invalid: unknown service type %d", serviceType)
+	}
+	service.Type = typeName
+
+	var privilegeCount uint16
+	if err := binary.Read(reader, binary.LittleEndian, &privilegeCount); err != nil {
+		return service, fmt.Errorf("invalid: failed to read %s privileges: %s", typeName, err)
+	}
+	service.Privileges = make([]PrivilegeInfo, 0, privilegeCount)
+	for i := 0; i < int(privilegeCount); i++ {
+		var privilege uint16
+		var expire uint32
+		if err := binary.Read(reader, binary.LittleEndian, &privilege); err != nil {
+			return service, fmt.Errorf("invalid: failed to read %s privileges: %s", typeName, err)
+		}
+		if err := binary.Read(reader, binary.LittleEndian, &expire); err != nil {
+			return service, fmt.Errorf("invalid: failed to read %s privileges: %s", typeName, err)
+		}
+		name, known := privilegeNames[serviceType][privilege]
+		if !known {
+			name = fmt.Sprintf("unknown(%d)", privilege)
+		}
+		service.Privileges = append(service.Privileges, PrivilegeInfo{Name: name, Expire: expire, ExpiresAt: issueTs + expire})
+	}
+
+	var err error
+	switch serviceType {
+	case accesstoken.ServiceTypeRtc:
+		if service.Channel, err = readString(reader); err == nil {
+			service.Uid, err = readString(reader)
+		}
+	case accesstoken.ServiceTypeRtm, accesstoken.ServiceTypeChat:
+		service.Uid, err = readString(reader)
+	case accesstoken.ServiceTypeEducation:
+		// room uuid, user uuid and role
+		if service.Channel, err = readString(reader); err == nil {
+			if service.Uid, err = readString(reader); err == nil {
+				var role int16
+				err = binary.Read(reader, binary.LittleEndian, &role)
+			}
+		}
+	}
+	if err != nil {
+		return service, fmt.Errorf("invalid: failed to read %s service: %s", typeName, err)
+	}
+
+	return service, nil
+}
+
+// signTokenContent computes the AccessToken2 signature of the content for the given app certificate.
+func signTokenContent(appCertificate string, issueTs, salt uint32, content []byte) []byte {
+	issueTsBytes := make([]byte, 4)
+	binary.LittleEndian.PutUint32(issueTsBytes, issueTs)
+	hIssueTs := hmac.New(sha256.New, issueTsBytes)
+	hIssueTs.Write([]byte(appCertificate))
+
+	saltBytes := make([]byte, 4)
+	binary.LittleEndian.PutUint32(saltBytes, salt)
+	hSalt := hmac.New(sha256.New, saltBytes)
+	hSalt.Write(hIssueTs.Sum(nil))
+
+	hSign := hmac.New(sha256.New, hSalt.Sum(nil))
+	hSign.Write(content)
+	return hSign.Sum(nil)
+}
+
+// readString reads a uint16 length prefixed string, failing on short reads.
+func readString(reader *bytes.Reader) (string, error) {
+	var length uint16
+	if err := binary.Read(reader, binary.LittleEndian, &length); err != nil {
+		return "", err
+	}
+	if int(length) > reader.Len() {
+		return "", errors.New("unexpected end of token")
+	}
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(reader, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
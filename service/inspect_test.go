@@ -0,0 +1,163 @@
+package service
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestInspectToken tests that InspectToken decodes the tokens generated by the service.
+func TestInspectToken(t *testing.T) {
+	service := CreateTestService(t)
+
+	rtcToken, err := service.GenRtcToken(TokenRequest{
+		TokenType:         "rtc",
+		Channel:           "my_channel",
+		Uid:               "123",
+		RtcRole:           "publisher",
+		ExpirationSeconds: 7200,
+		PubAudioExpire:    600,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	info, err := service.InspectToken(rtcToken)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assert.Equal(t, service.appID, info.AppID)
+	assert.Equal(t, uint32(7200), info.Expire)
+	assert.Equal(t, info.IssueTs+7200, info.ExpiresAt)
+	assert.True(t, info.SignatureValid)
+	assert.False(t, info.Expired)
+	if assert.Len(t, info.Services, 1) {
+		rtc := info.Services[0]
+		assert.Equal(t, "rtc", rtc.Type)
+		assert.Equal(t, "my_channel", rtc.Channel)
+		assert.Equal(t, "123", rtc.Uid)
+		assert.Contains(t, rtc.Privileges, PrivilegeInfo{Name: "joinChannel", Expire: 7200, ExpiresAt: info.IssueTs + 7200})
+		assert.Contains(t, rtc.Privileges, PrivilegeInfo{Name: "publishAudioStream", Expire: 600, ExpiresAt: info.IssueTs + 600})
+	}
+
+	// RTM tokens with a channel contain both the RTC stream channel and RTM services
+	rtmToken, err := service.GenRtmToken(TokenRequest{TokenType: "rtm", Uid: "user123", Channel: "stream"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	info, err = service.InspectToken(rtmToken)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if assert.Len(t, info.Services, 2) {
+		assert.Equal(t, "rtc", info.Services[0].Type)
+		assert.Equal(t, "stream", info.Services[0].Channel)
+		assert.Equal(t, "rtm", info.Services[1].Type)
+		assert.Equal(t, "user123", info.Services[1].Uid)
+	}
+
+	chatToken, err := service.GenChatToken(TokenRequest{TokenType: "chat"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	info, err = service.InspectToken(chatToken)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if assert.Len(t, info.Services, 1) {
+		assert.Equal(t, "chat", info.Services[0].Type)
+		assert.Equal(t, "app", info.Services[0].Privileges[0].Name)
+	}
+
+	// A token signed with another certificate decodes, but fails verification
+	otherService := &Service{appID: service.appID, appCertificate: "0123456789abcdef0123456789abcdef"}
+	otherToken, err := otherService.GenRtmToken(TokenRequest{TokenType: "rtm", Uid: "user123"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	info, err = service.InspectToken(otherToken)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assert.False(t, info.SignatureValid)
+
+	// Malformed tokens
+	for _, token := range []string{"", "006abc", "007", "007not-base64!", "007" + "eJxLTEoGAAJNASg=", rtcToken[:len(rtcToken)-12]} {
+		_, err := service.InspectToken(token)
+		assert.Error(t, err, token)
+	}
+
+	// Tokens decompressing beyond the content of any token are rejected without being decompressed
+	var compressed bytes.Buffer
+	writer := zlib.NewWriter(&compressed)
+	writer.Write(make([]byte, 64<<20))
+	writer.Close()
+	_, err = service.InspectToken("007" + base64.StdEncoding.EncodeToString(compressed.Bytes()))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "token content exceeds")
+	}
+}
+
+func TestInspectTokenEndpoint(t *testing.T) {
+	token, err := testService.GenRtcToken(TokenRequest{TokenType: "rtc", Channel: "channel123", Uid: "user123"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	body, _ := json.Marshal(InspectTokenRequest{Token: token})
+
+	tests := []UrlCodePair{
+		{"/inspectToken", http.StatusOK, body},
+		{"/inspectToken", http.StatusBadRequest, []byte(`{"token": "007invalid"}`)},
+		{"/inspectToken", http.StatusBadRequest, []byte(`{}`)},
+		{"/inspectToken", http.StatusBadRequest, []byte(``)},
+		{"/inspectToken", http.StatusRequestEntityTooLarge, []byte(`{"token": "007` + strings.Repeat("A", maxInspectBodySize) + `"}`)},
+	}
+	for _, httpTest := range tests {
+		testApi, err := http.NewRequest(http.MethodPost, httpTest.url, bytes.NewBuffer(httpTest.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := httptest.NewRecorder()
+		testService.Server.Handler.ServeHTTP(resp, testApi)
+		assert.Equal(t, httpTest.code, resp.Code, resp.Body)
+	}
+}
+
+func TestInspectTokenAuthAndRateLimit(t *testing.T) {
+	config := DefaultConfig()
+	config.Auth.APIKeys = map[string]string{"backend": "backend-key"}
+	config.RateLimits.Client = "1/m"
+	s, err := New(Options{AppID: testAppID, AppCertificate: testAppCertificate, Config: config})
+	if !assert.NoError(t, err) {
+		return
+	}
+	token, err := s.GenRtmToken(TokenRequest{Uid: "user123"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	body, _ := json.Marshal(InspectTokenRequest{Token: token})
+
+	for _, test := range []struct {
+		key  string
+		code int
+	}{
+		{"", http.StatusUnauthorized},
+		{"backend-key", http.StatusOK},
+		{"backend-key", http.StatusTooManyRequests},
+	} {
+		req, _ := http.NewRequest(http.MethodPost, "/inspectToken", bytes.NewBuffer(body))
+		if test.key != "" {
+			req.Header.Set("X-API-Key", test.key)
+		}
+		resp := httptest.NewRecorder()
+		s.Handler().ServeHTTP(resp, req)
+		assert.Equal(t, test.code, resp.Code, resp.Body.String())
+	}
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// defaultShutdownTimeout is the default time allowed to drain the in-flight requests and run the shutdown hooks.
+const defaultShutdownTimeout = "20s"
+
+// ShutdownHook releases a resource when the service shuts down, such as a database connection.
+// The context is done once the shutdown timeout expires.
+type ShutdownHook func(ctx context.Context) error
+
+// OnShutdown registers a hook run by Shutdown once the servers are drained. Hooks run in reverse
+// registration order, and all of them run even if some fail.
+//
+// Example usage:
+//
+//	service.OnShutdown(func(ctx context.Context) error {
+//	    return db.Close()
+//	})
+func (s *Service) OnShutdown(hook ShutdownHook) {
+	s.hooksMu.Lock()
+	defer s.hooksMu.Unlock()
+	s.shutdownHooks = append(s.shutdownHooks, hook)
+}
+
+// Ready reports whether the service accepts traffic: from the time Start listens until Shutdown begins.
+func (s *Service) Ready() bool {
+	return s.ready.Load()
+}
+
+// Start runs the service by listening to the specified port, over HTTPS when a TLS certificate is configured,
+// and to the gRPC port when configured. Until shutdown, the configuration is reloaded whenever the process
+// receives SIGHUP, and the certificates of the secret providers are refreshed periodically.
+//
+// Returns:
+//   - error: An error if a listener can not be opened or a server fails. Nil once Shutdown completes.
+//
+// Notes:
+//   - Start blocks until the service is shut down. Use Run to also handle SIGINT and SIGTERM.
+//
+// Example usage:
+//
+//	go func() {
+//	    if err := service.Start(); err != nil {
+//	        log.Fatal(err)
+//	    }
+//	}()
+func (s *Service) Start() error {
+	httpListener, err := net.Listen("tcp", s.Server.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen to %s: %w", s.Server.Addr, err)
+	}
+	var grpcListener net.Listener
+	if s.GRPCServer != nil {
+		if grpcListener, err = net.Listen("tcp", s.grpcAddr); err != nil {
+			httpListener.Close()
+			return fmt.Errorf("failed to listen to %s: %w", s.grpcAddr, err)
+		}
+	}
+
+	s.mu.RLock()
+	s.log(context.Background()).Info("listening", "addr", s.Server.Addr, "tls", s.tls != nil)
+	if grpcListener != nil {
+		s.log(context.Background()).Info("listening", "addr", s.grpcAddr, "protocol", "grpc")
+	}
+	s.mu.RUnlock()
+	go s.reloadOnSignal(s.background)
+	go s.refreshSecretsPeriodically(s.background)
+
+	serveErrs := make(chan error, 2)
+	go func() {
+		if s.tls != nil {
+			// The certificate is served by the TLS config, which reloads it when its files change
+			serveErrs <- s.Server.ServeTLS(httpListener, "", "")
+		} else {
+			serveErrs <- s.Server.Serve(httpListener)
+		}
+	}()
+	servers := 1
+	if grpcListener != nil {
+		servers++
+		go func() { serveErrs <- s.GRPCServer.Serve(grpcListener) }()
+	}
+	s.ready.Store(true)
+
+	for i := 0; i < servers; i++ {
+		if err := <-serveErrs; err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.ready.Store(false)
+			return err
+		}
+	}
+	// The servers return as soon as the shutdown begins, wait for the requests to drain
+	<-s.shutdownDone
+	return nil
+}
+
+// Run starts the service and shuts it down gracefully on SIGINT, SIGTERM or once the context is done,
+// within the configured shutdown timeout.
+//
+// Parameters:
+//   - ctx: context.Context - Shuts the service down when done.
+//
+// Returns:
+//   - error: The errors of Start and Shutdown, joined. Nil if the service started and shut down cleanly.
+//
+// Example usage:
+//
+//	if err := service.NewService().Run(context.Background()); err != nil {
+//	    os.Exit(1)
+//	}
+func (s *Service) Run(ctx context.Context) error {
+	signal.Notify(s.Sigint, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(s.Sigint)
+
+	started := make(chan error, 1)
+	go func() { started <- s.Start() }()
+
+	var startErr error
+	startReturned := false
+	select {
+	case startErr = <-started:
+		startReturned = true
+	case sig := <-s.Sigint:
+		s.mu.RLock()
+		s.log(context.Background()).Info("signal received", "signal", sig.String())
+		s.mu.RUnlock()
+	case <-ctx.Done():
+	}
+
+	shutdownErr := s.shutdownWithTimeout()
+	if !startReturned {
+		startErr = <-started
+	}
+	err := errors.Join(startErr, shutdownErr)
+	if err != nil {
+		s.mu.RLock()
+		s.log(context.Background()).Error("service stopped with errors", "error", err)
+		s.mu.RUnlock()
+	}
+	return err
+}
+
+// Stop waits for SIGINT or SIGTERM, then shuts the service down gracefully within the configured shutdown timeout.
+//
+// Deprecated: Use Run, which also returns the errors of Start and of the shutdown.
+func (s *Service) Stop() {
+	signal.Notify(s.Sigint, os.Interrupt, syscall.SIGTERM)
+	<-s.Sigint
+	if err := s.shutdownWithTimeout(); err != nil {
+		s.mu.RLock()
+		s.log(context.Background()).Error("shutdown failed", "error", err)
+		s.mu.RUnlock()
+	}
+}
+
+// shutdownWithTimeout shuts the service down within the configured shutdown timeout.
+func (s *Service) shutdownWithTimeout() error {
+	s.mu.RLock()
+	timeout := s.shutdownTimeout
+	s.mu.RUnlock()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return s.Shutdown(ctx)
+}
+
+// Shutdown stops the service gracefully:
+//  1. Reports the service as not ready, and keeps serving for the configured shutdown delay, so load
+//     balancers stop routing new requests to it.
+//  2. Closes the listeners and waits for the in-flight HTTP requests and gRPC calls to complete.
+//  3. Stops the configuration reloads and secret refreshes.
+//  4. Runs the shutdown hooks, in reverse registration order.
+//
+// Parameters:
+//   - ctx: context.Context - Bounds the whole shutdown. Once done, the remaining connections are closed.
+//
+// Returns:
+//   - error: The errors of the servers and hooks, joined. Nil if every request completed in time.
+//
+// Notes:
+//   - Only the first call shuts the service down, later calls wait for it and return the same error.
+func (s *Service) Shutdown(ctx context.Context) error {
+	s.shutdownOnce.Do(func() {
+		s.shutdownErr = s.shutdown(ctx)
+		close(s.shutdownDone)
+	})
+	<-s.shutdownDone
+	return s.shutdownErr
+}
+
+// shutdown implements Shutdown.
+func (s *Service) shutdown(ctx context.Context) error {
+	s.ready.Store(false)
+	s.draining.Store(true)
+	s.mu.RLock()
+	delay := s.shutdownDelay
+	s.log(ctx).Info("shutting down", "delay", delay)
+	s.mu.RUnlock()
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+	}
+
+	var errs []error
+	grpcStopped := make(chan struct{})
+	if s.GRPCServer != nil {
+		go func() {
+			s.GRPCServer.GracefulStop()
+			close(grpcStopped)
+		}()
+	}
+	if err := s.Server.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("HTTP server shutdown: %w", err))
+		s.Server.Close()
+	}
+	if s.GRPCServer != nil {
+		select {
+		case <-grpcStopped:
+		case <-ctx.Done():
+			errs = append(errs, fmt.Errorf("gRPC server shutdown: %w", ctx.Err()))
+			s.GRPCServer.Stop()
+		}
+	}
+	s.stopBackground()
+
+	s.mu.RLock()
+	if err := closeAuditSinks(s.ownedAuditSinks); err != nil {
+		errs = append(errs, fmt.Errorf("audit sinks: %w", err))
+	}
+	if err := s.rateLimits.close(nil); err != nil {
+		errs = append(errs, fmt.Errorf("rate limit store: %w", err))
+	}
+	s.mu.RUnlock()
+
+	s.hooksMu.Lock()
+	hooks := append([]ShutdownHook(nil), s.shutdownHooks...)
+	s.hooksMu.Unlock()
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i](ctx); err != nil {
+			errs = append(errs, fmt.Errorf("shutdown hook: %w", err))
+		}
+	}
+
+	s.mu.RLock()
+	s.log(ctx).Info("shutdown complete")
+	s.mu.RUnlock()
+	return errors.Join(errs...)
+}
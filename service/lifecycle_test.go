@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// freePort returns a TCP port that is free at the time of the call.
+func freePort(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+}
+
+// serveSlowly makes the /slow path of the service block until release is closed, signaling started first.
+func serveSlowly(service *Service) (started, release chan struct{}) {
+	started, release = make(chan struct{}), make(chan struct{})
+	handler := service.Server.Handler
+	service.Server.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(started)
+			<-release
+		}
+		handler.ServeHTTP(w, r)
+	})
+	return started, release
+}
+
+func TestRunDrainsRequests(t *testing.T) {
+	port := freePort(t)
+	t.Setenv("SERVER_PORT", port)
+	t.Setenv("SHUTDOWN_DELAY", "50ms")
+	service := NewService()
+	started, release := serveSlowly(service)
+	var hooks []string
+	service.OnShutdown(func(context.Context) error { hooks = append(hooks, "first"); return nil })
+	service.OnShutdown(func(context.Context) error { hooks = append(hooks, "second"); return nil })
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- service.Run(context.Background()) }()
+	assert.Eventually(t, service.Ready, time.Second, 10*time.Millisecond)
+
+	status := make(chan int, 1)
+	go func() {
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%s/slow", port))
+		if err != nil {
+			status <- 0
+			return
+		}
+		resp.Body.Close()
+		status <- resp.StatusCode
+	}()
+	<-started
+
+	// SIGTERM flips the readiness first, then waits for the in-flight request
+	service.Sigint <- syscall.SIGTERM
+	assert.Eventually(t, func() bool { return !service.Ready() }, time.Second, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	select {
+	case err := <-runErr:
+		t.Fatalf("Run returned before the request completed: %v", err)
+	default:
+	}
+	assert.Empty(t, hooks)
+
+	close(release)
+	assert.Equal(t, http.StatusNotFound, <-status)
+	assert.NoError(t, <-runErr)
+	assert.Equal(t, []string{"second", "first"}, hooks)
+
+	// Shutdown is only run once
+	assert.NoError(t, service.Shutdown(context.Background()))
+	assert.Len(t, hooks, 2)
+}
+
+func TestRunShutdownTimeout(t *testing.T) {
+	port := freePort(t)
+	t.Setenv("SERVER_PORT", port)
+	t.Setenv("SHUTDOWN_TIMEOUT", "100ms")
+	service := NewService()
+	started, release := serveSlowly(service)
+	defer close(release)
+	hookErr := errors.New("failed to close")
+	hookCalled := false
+	service.OnShutdown(func(context.Context) error { hookCalled = true; return hookErr })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- service.Run(ctx) }()
+	assert.Eventually(t, service.Ready, time.Second, 10*time.Millisecond)
+	go http.Get(fmt.Sprintf("http://127.0.0.1:%s/slow", port))
+	<-started
+
+	// The request outlives the timeout, but the hooks still run
+	cancel()
+	select {
+	case err := <-runErr:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.ErrorIs(t, err, hookErr)
+		assert.True(t, hookCalled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return once the shutdown timed out")
+	}
+}
+
+func TestStartListenError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	service := NewService()
+	service.Server.Addr = listener.Addr().String()
+
+	assert.Error(t, service.Start())
+	assert.False(t, service.Ready())
+	assert.Error(t, service.Run(context.Background()))
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// requestIDHeader carries the request ID, either received from the caller or generated by the service.
+	requestIDHeader = "X-Request-ID"
+
+	// redactNone logs uids and channels as they are.
+	redactNone = "none"
+
+	// redactHash logs an HMAC-SHA256 of uids and channels, so requests of the same user can still be correlated.
+	// Unlike a plain digest, it can not be reversed by hashing every likely uid without the key.
+	redactHash = "hash"
+
+	// minRedactKeySize is the minimum length of the HMAC key of the hash redaction.
+	minRedactKeySize = 16
+
+	// redactMask replaces uids and channels with a fixed placeholder.
+	redactMask = "redact"
+)
+
+// requestIDContextKey is the request context key of the request ID.
+type requestIDContextKey struct{}
+
+// RequestIDFromContext returns the ID of the request, as set by the logging middleware.
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey{}).(string)
+	return requestID
+}
+
+// clientIPContextKey is the request context key of the IP address of the caller.
+type clientIPContextKey struct{}
+
+// clientIPFromContext returns the IP address of the caller, as set by the logging middleware.
+func clientIPFromContext(ctx context.Context) string {
+	clientIP, _ := ctx.Value(clientIPContextKey{}).(string)
+	return clientIP
+}
+
+// newLogger creates the service logger with the configured level ("debug", "info", "warn" or "error")
+// and format ("json" or "text").
+func newLogger(out io.Writer, config LogConfig) (*slog.Logger, error) {
+	level, err := parseLogLevel(config.Level)
+	if err != nil {
+		return nil, err
+	}
+	options := &slog.HandlerOptions{Level: level}
+
+	switch strings.ToLower(config.Format) {
+	case "", "json":
+		return slog.New(slog.NewJSONHandler(out, options)), nil
+	case "text":
+		return slog.New(slog.NewTextHandler(out, options)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q, expected json or text", config.Format)
+	}
+}
+
+// parseLogLevel parses a log level name, defaulting to info.
+func parseLogLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if name != "" {
+		if err := level.UnmarshalText([]byte(name)); err != nil {
+			return level, fmt.Errorf("invalid log level %q, expected debug, info, warn or error", name)
+		}
+	}
+	return level, nil
+}
+
+// logRedaction returns how uids and channels are logged: "none" (default), "hash" or "redact".
+func logRedaction(config LogConfig) string {
+	if config.Redact == "" {
+		return redactNone
+	}
+	return strings.ToLower(config.Redact)
+}
+
+// redact returns the uid or channel as it should appear in the logs.
+func (s *Service) redact(value string) string {
+	if value == "" {
+		return value
+	}
+	switch s.logRedaction {
+	case redactHash:
+		mac := hmac.New(sha256.New, s.logRedactKey)
+		mac.Write([]byte(value))
+		return "hmac:" + hex.EncodeToString(mac.Sum(nil)[:8])
+	case redactMask:
+		return "[redacted]"
+	default:
+		return value
+	}
+}
+
+// log returns the logger of the request, annotated with its request ID.
+func (s *Service) log(ctx context.Context) *slog.Logger {
+	logger := s.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		logger = logger.With("request_id", requestID)
+	}
+	return logger
+}
+
+// loggingMiddleware assigns each request an ID, taken from the X-Request-ID header or generated,
+// echoes it in the response, and writes an access log entry once the request is served.
+// The route template is logged instead of the path, as legacy paths contain uids and channels.
+func (s *Service) loggingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(requestIDHeader)
+		if requestID == "" || len(requestID) > 128 {
+			requestID = newRequestID()
+		}
+		c.Header(requestIDHeader, requestID)
+		ctx := context.WithValue(c.Request.Context(), requestIDContextKey{}, requestID)
+		c.Request = c.Request.WithContext(context.WithValue(ctx, clientIPContextKey{}, c.ClientIP()))
+
+		start := time.Now()
+		c.Next()
+
+		attrs := []any{
+			"method", c.Request.Method,
+			"route", c.FullPath(),
+			"status", c.Writer.Status(),
+			"latency", time.Since(start),
+			"client_ip", c.ClientIP(),
+		}
+		if s.logRedaction == redactNone {
+			attrs = append(attrs, "path", c.Request.URL.Path)
+		}
+		s.log(c.Request.Context()).Info("request", attrs...)
+	}
+}
+
+// recoveryMiddleware recovers from panics in the handlers, logging them with the request ID, and responds
+// with 500 Internal Server Error and the INTERNAL_ERROR code.
+func (s *Service) recoveryMiddleware() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+		s.log(c.Request.Context()).Error("panic recovered", "error", fmt.Sprint(recovered))
+		abortWithError(c, &APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "internal server error"})
+	})
+}
+
+// recordToken logs the outcome of a token request and updates the token metrics.
+// A nil err records an issued token.
+func (s *Service) recordToken(ctx context.Context, tokenReq TokenRequest, route string, err error) {
+	attrs := []any{
+		"route", route,
+		"token_type", tokenReq.TokenType,
+		"channel", s.redact(tokenReq.Channel),
+		"uid", s.redact(tokenReq.Uid),
+		"expire", tokenReq.ExpirationSeconds,
+	}
+	if len(tokenReq.Services) > 0 {
+		attrs = append(attrs, "services", tokenReq.Services)
+	}
+	if tokenReq.TokenType == "rtc" || len(tokenReq.Services) > 0 {
+		attrs = append(attrs, "role", requestedRole(tokenReq))
+	}
+	if tokenReq.Project != "" {
+		attrs = append(attrs, "project", tokenReq.Project)
+	}
+
+	logger := s.log(ctx)
+	if err != nil {
+		s.metrics.tokenFailed(tokenReq, route, err)
+		attrs = append(attrs, "outcome", errorReason(err))
+		if s.logRedaction == redactNone {
+			// Error messages may quote the uid or channel
+			attrs = append(attrs, "error", err.Error())
+		}
+		logger.Warn("token request failed", attrs...)
+		return
+	}
+	s.metrics.tokenIssued(tokenReq, route)
+	logger.Info("token issued", append(attrs, "outcome", "issued")...)
+}
+
+// newRequestID returns a random 128-bit request ID.
+func newRequestID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Sprint(time.Now().UnixNano())
+	}
+	return hex.EncodeToString(id)
+}
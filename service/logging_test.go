@@ -0,0 +1,106 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLogger(t *testing.T) {
+	var out bytes.Buffer
+	logger, err := newLogger(&out, LogConfig{Level: "warn", Format: "text"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	logger.Info("hidden")
+	logger.Warn("shown", "key", "value")
+	assert.NotContains(t, out.String(), "hidden")
+	assert.Contains(t, out.String(), "level=WARN msg=shown key=value")
+
+	_, err = newLogger(&out, LogConfig{Format: "xml"})
+	assert.Error(t, err)
+
+	_, err = newLogger(&out, LogConfig{Level: "verbose"})
+	assert.Error(t, err)
+
+	assert.Equal(t, redactNone, logRedaction(LogConfig{}))
+	assert.Equal(t, redactHash, logRedaction(LogConfig{Redact: "HASH"}))
+}
+
+func TestRedactHash(t *testing.T) {
+	service := &Service{logRedaction: redactHash, logRedactKey: []byte("0123456789abcdef")}
+	other := &Service{logRedaction: redactHash, logRedactKey: []byte("fedcba9876543210")}
+
+	// Values can be correlated, but not matched without the key
+	assert.Equal(t, service.redact("42"), service.redact("42"))
+	assert.NotEqual(t, service.redact("42"), service.redact("43"))
+	assert.NotEqual(t, service.redact("42"), other.redact("42"))
+	assert.Len(t, service.redact("42"), len("hmac:")+16)
+}
+
+func TestRecordTokenRedaction(t *testing.T) {
+	tokenReq := TokenRequest{TokenType: "rtc", Channel: "secret-room", Uid: "42", RtcRole: "publisher", ExpirationSeconds: 600}
+	ctx := context.WithValue(context.Background(), requestIDContextKey{}, "req-1")
+
+	tests := []struct {
+		redaction string
+		channel   string
+		uid       string
+	}{
+		{redactNone, "secret-room", "42"},
+		{redactHash, "hmac:", "hmac:"},
+		{redactMask, "[redacted]", "[redacted]"},
+	}
+	for _, test := range tests {
+		var out bytes.Buffer
+		logger, _ := newLogger(&out, LogConfig{})
+		service := &Service{logger: logger, logRedaction: test.redaction, logRedactKey: []byte("0123456789abcdef")}
+		service.recordToken(ctx, tokenReq, routePost, nil)
+		service.recordToken(ctx, tokenReq, routePost, errors.New("failed to parse uid 42"))
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if !assert.Len(t, lines, 2, test.redaction) {
+			continue
+		}
+		var issued, failed map[string]interface{}
+		json.Unmarshal([]byte(lines[0]), &issued)
+		json.Unmarshal([]byte(lines[1]), &failed)
+
+		assert.Equal(t, "req-1", issued["request_id"])
+		assert.Equal(t, "issued", issued["outcome"])
+		assert.Equal(t, "rtc", issued["token_type"])
+		assert.Equal(t, "publisher", issued["role"])
+		assert.Equal(t, float64(600), issued["expire"])
+		assert.True(t, strings.HasPrefix(issued["channel"].(string), test.channel), test.redaction)
+		assert.True(t, strings.HasPrefix(issued["uid"].(string), test.uid), test.redaction)
+		assert.Equal(t, "WARN", failed["level"])
+		assert.Equal(t, "invalid_request", failed["outcome"])
+		if test.redaction == redactNone {
+			assert.Equal(t, "failed to parse uid 42", failed["error"])
+		} else {
+			assert.Nil(t, failed["error"])
+			assert.NotContains(t, lines[1], `"42"`)
+			assert.NotContains(t, lines[1], "secret-room")
+		}
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "/ping", nil)
+	resp := httptest.NewRecorder()
+	testService.Server.Handler.ServeHTTP(resp, req)
+	assert.Len(t, resp.Header().Get(requestIDHeader), 32)
+
+	req, _ = http.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set(requestIDHeader, "caller-request-id")
+	resp = httptest.NewRecorder()
+	testService.Server.Handler.ServeHTTP(resp, req)
+	assert.Equal(t, "caller-request-id", resp.Header().Get(requestIDHeader))
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// routeLegacy labels the metrics of tokens requested through the legacy GET endpoints.
+	routeLegacy = "legacy"
+
+	// routePost labels the metrics of tokens requested through POST /getToken.
+	routePost = "post"
+
+	// routeBatch labels the metrics of tokens requested through POST /getTokens.
+	routeBatch = "batch"
+
+	// routeGRPC labels the metrics of tokens requested through the single token RPCs of the gRPC TokenService.
+	routeGRPC = "grpc"
+
+	// routeGRPCBatch labels the metrics of tokens requested through the GenerateTokens RPC.
+	routeGRPCBatch = "grpc_batch"
+
+	// routeInspect labels the metrics of the inspectToken requests rejected by the rate limits.
+	routeInspect = "inspect"
+)
+
+// metrics holds the Prometheus collectors of the service, registered on a dedicated registry.
+// A nil *metrics is valid and records nothing.
+type metrics struct {
+	registry *prometheus.Registry
+
+	tokensIssued     *prometheus.CounterVec
+	tokenErrors      *prometheus.CounterVec
+	requestDuration  *prometheus.HistogramVec
+	requestsInFlight prometheus.Gauge
+	grpcDuration     *prometheus.HistogramVec
+}
+
+// newMetrics creates the service collectors, along with the Go runtime and process collectors.
+func newMetrics() *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		tokensIssued: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "agora_token_service",
+			Name:      "tokens_issued_total",
+			Help:      "Number of tokens issued, by token type, RTC role and route.",
+		}, []string{"type", "role", "route"}),
+		tokenErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "agora_token_service",
+			Name:      "token_errors_total",
+			Help:      "Number of token requests that failed, by token type, route and reason.",
+		}, []string{"type", "route", "reason"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "agora_token_service",
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of the HTTP requests, by method, route and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		requestsInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "agora_token_service",
+			Name:      "http_requests_in_flight",
+			Help:      "Number of HTTP requests currently being served.",
+		}),
+		grpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "agora_token_service",
+			Name:      "grpc_request_duration_seconds",
+			Help:      "Latency of the gRPC calls, by method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "code"}),
+	}
+	m.registry.MustRegister(
+		m.tokensIssued, m.tokenErrors, m.requestDuration, m.requestsInFlight, m.grpcDuration,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// handler serves the collected metrics in the Prometheus exposition format.
+func (m *metrics) handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+}
+
+// middleware tracks the number of in-flight requests and the latency of every request.
+func (m *metrics) middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if m == nil {
+			c.Next()
+			return
+		}
+		m.requestsInFlight.Inc()
+		defer m.requestsInFlight.Dec()
+
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		m.requestDuration.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Observe(time.Since(start).Seconds())
+	}
+}
+
+// unaryInterceptor records the latency of every gRPC call.
+func (m *metrics) unaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if m == nil {
+			return handler(ctx, req)
+		}
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		m.grpcDuration.WithLabelValues(info.FullMethod, status.Code(err).String()).Observe(time.Since(start).Seconds())
+		return resp, err
+	}
+}
+
+// tokenIssued counts a successfully issued token. Multi-service requests count each of their services.
+func (m *metrics) tokenIssued(tokenReq TokenRequest, route string) {
+	if m == nil {
+		return
+	}
+	for _, serviceReq := range tokenReq.serviceRequests() {
+		role := ""
+		if serviceReq.TokenType == "rtc" {
+			role = requestedRole(serviceReq)
+		}
+		m.tokensIssued.WithLabelValues(serviceReq.TokenType, role, route).Inc()
+	}
+}
+
+// tokenFailed counts a token request that failed with the error.
+func (m *metrics) tokenFailed(tokenReq TokenRequest, route string, err error) {
+	if m == nil {
+		return
+	}
+	tokenType := tokenReq.TokenType
+	if len(tokenReq.Services) > 0 {
+		tokenType = "multi"
+	}
+	if tokenType != "rtc" && tokenType != "rtm" && tokenType != "chat" && tokenType != "multi" {
+		tokenType = "unknown"
+	}
+	m.tokenErrors.WithLabelValues(tokenType, route, errorReason(err)).Inc()
+}
+
+// errorReason returns the metrics label describing why a token request failed.
+func errorReason(err error) string {
+	var violation *PolicyViolation
+	var limited *RateLimitError
+	switch {
+	case errors.As(err, &violation):
+		return "policy_denied"
+	case errors.As(err, &limited):
+		return "rate_limited"
+	case errors.Is(err, errUnknownProject):
+		return "unknown_project"
+	case errors.Is(err, errUnsupportedTokenType):
+		return "unsupported_token_type"
+	default:
+		return "invalid_request"
+	}
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	service := NewService()
+
+	tests := []UrlCodePair{
+		{"/rtc/test/publisher/uid/1/", http.StatusOK, nil},
+		{"/rtm/username/", http.StatusOK, nil},
+		{"/getToken", http.StatusOK, []byte(`{"tokenType": "rtc", "channel": "test", "uid": "1"}`)},
+		{"/getToken", http.StatusOK, []byte(`{"services": ["rtc", "chat"], "channel": "test", "uid": "1"}`)},
+		{"/getToken", http.StatusBadRequest, []byte(`{"tokenType": "invalid_type"}`)},
+		{"/getTokens", http.StatusOK, []byte(`[{"tokenType": "rtm", "uid": "1"}, {"tokenType": "chat", "project": "unknown"}]`)},
+	}
+	for _, httpTest := range tests {
+		method := http.MethodGet
+		if httpTest.body != nil {
+			method = http.MethodPost
+		}
+		req, _ := http.NewRequest(method, httpTest.url, bytes.NewBuffer(httpTest.body))
+		resp := httptest.NewRecorder()
+		service.Server.Handler.ServeHTTP(resp, req)
+		assert.Equal(t, httpTest.code, resp.Code, httpTest.url)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/metrics", nil)
+	resp := httptest.NewRecorder()
+	service.Server.Handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	body, _ := io.ReadAll(resp.Body)
+	metrics := string(body)
+
+	expected := []string{
+		`agora_token_service_tokens_issued_total{role="publisher",route="legacy",type="rtc"} 1`,
+		`agora_token_service_tokens_issued_total{role="",route="legacy",type="rtm"} 1`,
+		`agora_token_service_tokens_issued_total{role="subscriber",route="post",type="rtc"} 2`,
+		`agora_token_service_tokens_issued_total{role="",route="post",type="chat"} 1`,
+		`agora_token_service_tokens_issued_total{role="",route="batch",type="rtm"} 1`,
+		`agora_token_service_token_errors_total{reason="unsupported_token_type",route="post",type="unknown"} 1`,
+		`agora_token_service_token_errors_total{reason="unknown_project",route="batch",type="chat"} 1`,
+		`agora_token_service_http_request_duration_seconds_count{method="GET",route="/rtc/:channelName/:role/:tokenType/:rtcuid/",status="200"} 1`,
+		`agora_token_service_http_request_duration_seconds_count{method="POST",route="/getToken",status="400"} 1`,
+		`agora_token_service_http_requests_in_flight 1`,
+	}
+	for _, line := range expected {
+		assert.True(t, strings.Contains(metrics, line), line)
+	}
+}
+
+func TestMetricsWithoutOrigin(t *testing.T) {
+	s, err := New(Options{AppID: testAppID, AppCertificate: testAppCertificate, CORSAllowOrigin: "https://app.example.com"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	req, _ := http.NewRequest(http.MethodGet, "/metrics", nil)
+	resp := httptest.NewRecorder()
+	s.Handler().ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
@@ -0,0 +1,486 @@
+package service
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/openapi3gen"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gin-gonic/gin"
+)
+
+// docsPage is the Swagger UI page served at /docs, which renders the specification served at /openapi.json.
+//
+//go:embed openapi_docs.html
+var docsPage []byte
+
+// apiOperation documents a route of the REST API in the OpenAPI specification.
+type apiOperation struct {
+	method        string
+	path          string // The route, in the syntax of gin
+	id            string
+	summary       string
+	tag           string
+	request       string // The component schema of the JSON request body, if any
+	response      string // The component schema of the JSON response body
+	contentType   string // The media type of the response, application/json if empty
+	errorResponse string // The component schema of the error responses, ErrorResponse if empty
+	expiry        bool   // Whether the expiry query parameter is accepted
+	auditQuery    bool   // Whether the audit search query parameters are accepted
+	authenticated bool   // Whether the route is behind the AuthMiddleware
+	deprecated    bool
+}
+
+// tokenOperations document the routes of registerTokenRoutes, served both at the root and under /projects/:project.
+var tokenOperations = []apiOperation{
+	{method: http.MethodPost, path: "/getToken", id: "getToken", summary: "Generate a token", tag: "tokens",
+		request: "TokenRequest", response: "TokenResponse", authenticated: true},
+	{method: http.MethodPost, path: "/getTokens", id: "getTokens", summary: "Generate a batch of tokens", tag: "tokens",
+		request: "TokenBatch", response: "TokenResults", authenticated: true},
+	{method: http.MethodGet, path: "/rtc/:channelName/:role/:tokenType/:rtcuid/", id: "getRtcToken", summary: "Generate an RTC token",
+		tag: "deprecated", response: "RtcTokenResponse", expiry: true, authenticated: true, deprecated: true},
+	{method: http.MethodGet, path: "/rtm/:rtmuid/", id: "getRtmToken", summary: "Generate an RTM token",
+		tag: "deprecated", response: "RtmTokenResponse", expiry: true, authenticated: true, deprecated: true},
+	{method: http.MethodGet, path: "/rte/:channelName/:role/:tokenType/:rtcuid/", id: "getRteToken", summary: "Generate RTC and RTM tokens",
+		tag: "deprecated", response: "RteTokenResponse", expiry: true, authenticated: true, deprecated: true},
+	{method: http.MethodGet, path: "/rte/:channelName/:role/:tokenType/:rtcuid/:rtmuid/", id: "getRteTokenWithRtmUid",
+		summary: "Generate RTC and RTM tokens for distinct users", tag: "deprecated", response: "RteTokenResponse", expiry: true,
+		authenticated: true, deprecated: true},
+	{method: http.MethodGet, path: "/chat/app/", id: "getChatAppToken", summary: "Generate a chat app token",
+		tag: "deprecated", response: "ChatTokenResponse", expiry: true, authenticated: true, deprecated: true},
+	{method: http.MethodGet, path: "/chat/account/:chatid/", id: "getChatUserToken", summary: "Generate a chat user token",
+		tag: "deprecated", response: "ChatTokenResponse", expiry: true, authenticated: true, deprecated: true},
+}
+
+// serviceOperations document the other routes of the service.
+var serviceOperations = []apiOperation{
+	{method: http.MethodPost, path: "/inspectToken", id: "inspectToken", summary: "Decode and verify a token", tag: "tokens",
+		request: "InspectTokenRequest", response: "TokenInfo", authenticated: true},
+	{method: http.MethodGet, path: "/ping", id: "ping", summary: "Check the service responds", tag: "health", response: "PingResponse"},
+	{method: http.MethodGet, path: "/healthz", id: "getHealthz", summary: "Liveness probe", tag: "health", response: "HealthResponse"},
+	{method: http.MethodGet, path: "/readyz", id: "getReadyz", summary: "Readiness probe", tag: "health",
+		response: "ReadinessReport", errorResponse: "ReadinessReport"},
+	{method: http.MethodGet, path: "/version", id: "getVersion", summary: "Build information", tag: "health", response: "BuildInfo"},
+	{method: http.MethodGet, path: "/metrics", id: "getMetrics", summary: "Prometheus metrics", tag: "health", contentType: "text/plain"},
+	{method: http.MethodGet, path: "/openapi.json", id: "getOpenAPI", summary: "This OpenAPI specification", tag: "docs",
+		response: "OpenAPI"},
+	{method: http.MethodGet, path: "/docs", id: "getDocs", summary: "Swagger UI rendering this specification", tag: "docs",
+		contentType: "text/html"},
+	{method: http.MethodGet, path: "/admin/certificates", id: "getCertificates", summary: "List the certificates of every project",
+		tag: "admin", response: "CertificatesResponse", authenticated: true},
+	{method: http.MethodPost, path: "/admin/certificates/rotate", id: "rotateCertificate", summary: "Switch the active certificate of a project",
+		tag: "admin", request: "RotateCertificateRequest", response: "CertificateStatus", authenticated: true},
+	{method: http.MethodGet, path: "/admin/audit", id: "getAudit", summary: "Search the audit records of the issued tokens",
+		tag: "admin", response: "AuditPage", auditQuery: true, authenticated: true},
+}
+
+// tokenRequestFields describes the fields of TokenRequest.
+var tokenRequestFields = map[string]string{
+	"tokenType":           "The token type, unless services are listed.",
+	"channel":             "The channel name, required for RTC tokens.",
+	"role":                "The role of the user for RTC tokens.",
+	"uid":                 "The user ID or account, required for RTC and RTM tokens. Numeric RTC uids are user IDs.",
+	"expire":              "The token expiration in seconds, one hour by default.",
+	"joinChannelExpire":   "The join channel privilege expiration in seconds (RTC only).",
+	"pubAudioExpire":      "The publish audio privilege expiration in seconds (RTC publisher only).",
+	"pubVideoExpire":      "The publish video privilege expiration in seconds (RTC publisher only).",
+	"pubDataStreamExpire": "The publish data stream privilege expiration in seconds (RTC publisher only).",
+	"project":             "The registered project to sign the token for, the default project if empty.",
+	"services":            "The services to include in a single token, instead of a tokenType.",
+	"separateTokens":      "Whether to return one token per service instead of a single token.",
+}
+
+// pathParameters describes the path parameters of the routes, by name.
+var pathParameters = map[string]string{
+	"project":     "The registered project to sign the token for.",
+	"channelName": "The channel name.",
+	"role":        "The role of the user.",
+	"tokenType":   "Whether rtcuid is a numeric user ID or a user account.",
+	"rtcuid":      "The RTC user ID or account, any user if 0.",
+	"rtmuid":      "The RTM user ID, the RTC one by default.",
+	"chatid":      "The chat user ID.",
+}
+
+// auditQueryParameters describes the query parameters of the audit search, by name.
+var auditQueryParameters = map[string]string{
+	"channel": "The channel name of the tokens.",
+	"uid":     "The user ID or account of the tokens.",
+	"type":    "A service of the tokens, multi-service tokens included.",
+	"from":    "The tokens issued at or after this RFC 3339 time.",
+	"to":      "The tokens issued before this RFC 3339 time.",
+	"limit":   "The number of records of the page.",
+	"cursor":  "The nextCursor of the previous page.",
+	"format":  "The format of the response, json unless the Accept header asks for text/csv.",
+}
+
+// ginParameter matches the parameters of gin routes.
+var ginParameter = regexp.MustCompile(`:(\w+)`)
+
+// openAPIPath returns the OpenAPI path template of a gin route.
+func openAPIPath(route string) string {
+	return ginParameter.ReplaceAllString(route, "{$1}")
+}
+
+// newOpenAPISpec builds the OpenAPI 3 specification of the REST API. The schemas are generated from the Go types
+// of the requests and responses, and constrained with the validation rules of the configuration, so the
+// specification stays in lockstep with the service.
+//
+// Parameters:
+//   - config: *Config - The configuration, whose expiration range, batch size and authentication are described.
+//
+// Returns:
+//   - *openapi3.T: The specification.
+//   - error: An error if a schema can not be generated or the specification is not valid.
+//
+// Example usage:
+//
+//	spec, err := newOpenAPISpec(config)
+//	if err != nil {
+//	    return fmt.Errorf("OpenAPI specification not properly built: %w", err)
+//	}
+func newOpenAPISpec(config *Config) (*openapi3.T, error) {
+	schemas, err := openAPISchemas(config)
+	if err != nil {
+		return nil, err
+	}
+	spec := &openapi3.T{
+		OpenAPI: "3.0.3",
+		Info: &openapi3.Info{
+			Title:       "Agora Token Service",
+			Description: "Generates and inspects Agora RTC, RTM and chat tokens.",
+			Version:     GetBuildInfo().Version,
+		},
+		Paths:      openapi3.NewPaths(),
+		Components: &openapi3.Components{Schemas: schemas, SecuritySchemes: openAPISecuritySchemes(config.Auth)},
+	}
+	if config.basePath != "" {
+		// The service is mounted under a path prefix of another server
+		spec.Servers = openapi3.Servers{{URL: config.basePath}}
+	}
+	var security *openapi3.SecurityRequirements
+	if len(spec.Components.SecuritySchemes) > 0 {
+		// Any of the authentication methods is accepted
+		names := make([]string, 0, len(spec.Components.SecuritySchemes))
+		for name := range spec.Components.SecuritySchemes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		security = openapi3.NewSecurityRequirements()
+		for _, name := range names {
+			security.With(openapi3.NewSecurityRequirement().Authenticate(name))
+		}
+	}
+
+	add := func(path string, operation apiOperation) {
+		pathItem := spec.Paths.Value(openAPIPath(path))
+		if pathItem == nil {
+			pathItem = &openapi3.PathItem{}
+			spec.Paths.Set(openAPIPath(path), pathItem)
+		}
+		op := operation.build(path, schemas)
+		if operation.authenticated {
+			op.Security = security
+		}
+		if strings.HasPrefix(path, "/projects/") {
+			op.OperationID += "ForProject"
+		}
+		pathItem.SetOperation(operation.method, op)
+	}
+	for _, operation := range tokenOperations {
+		add(operation.path, operation)
+	}
+	for _, operation := range tokenOperations {
+		add("/projects/:project"+operation.path, operation)
+	}
+	for _, operation := range serviceOperations {
+		add(operation.path, operation)
+	}
+
+	if err := spec.Validate(context.Background()); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// build returns the OpenAPI operation of the route, served at path.
+func (operation apiOperation) build(path string, schemas openapi3.Schemas) *openapi3.Operation {
+	op := openapi3.NewOperation()
+	op.OperationID = operation.id
+	op.Summary = operation.summary
+	op.Tags = []string{operation.tag}
+	op.Deprecated = operation.deprecated
+
+	for _, match := range ginParameter.FindAllStringSubmatch(path, -1) {
+		name := match[1]
+		op.AddParameter(openapi3.NewPathParameter(name).WithDescription(pathParameters[name]).WithSchema(pathParameterSchema(name, schemas)))
+	}
+	if operation.expiry {
+		expiry := *schemas["TokenRequest"].Value.Properties["expire"].Value
+		expiry.Description = ""
+		op.AddParameter(openapi3.NewQueryParameter("expiry").WithDescription("The token expiration in seconds, one hour by default.").
+			WithSchema(&expiry))
+	}
+	if operation.auditQuery {
+		for _, name := range sortedKeys(auditQueryParameters) {
+			op.AddParameter(openapi3.NewQueryParameter(name).WithDescription(auditQueryParameters[name]).
+				WithSchema(auditQueryParameterSchema(name)))
+		}
+	}
+	if operation.request != "" {
+		op.RequestBody = &openapi3.RequestBodyRef{Value: openapi3.NewRequestBody().WithRequired(true).
+			WithJSONSchemaRef(componentRef(schemas, operation.request))}
+	}
+
+	success := openapi3.NewResponse().WithDescription(http.StatusText(http.StatusOK))
+	switch {
+	case operation.contentType != "":
+		success.WithContent(openapi3.NewContentWithSchema(openapi3.NewStringSchema(), []string{operation.contentType}))
+	default:
+		success.WithJSONSchemaRef(componentRef(schemas, operation.response))
+	}
+	if operation.auditQuery {
+		success.Content["text/csv"] = openapi3.NewMediaType().WithSchema(openapi3.NewStringSchema())
+	}
+	failure := openapi3.NewResponse().WithDescription("Error")
+	if operation.errorResponse != "" {
+		failure.WithJSONSchemaRef(componentRef(schemas, operation.errorResponse))
+	} else {
+		failure.WithContent(openapi3.Content{
+			"application/json": openapi3.NewMediaType().WithSchemaRef(componentRef(schemas, "ErrorResponse")),
+			problemContentType: openapi3.NewMediaType().WithSchemaRef(componentRef(schemas, "ProblemDetails")),
+		})
+	}
+	op.Responses = openapi3.NewResponses(
+		openapi3.WithStatus(http.StatusOK, &openapi3.ResponseRef{Value: success}),
+		openapi3.WithName("default", failure),
+	)
+	return op
+}
+
+// componentRef returns a reference to the component schema, holding its value for validation.
+func componentRef(schemas openapi3.Schemas, name string) *openapi3.SchemaRef {
+	return openapi3.NewSchemaRef("#/components/schemas/"+name, schemas[name].Value)
+}
+
+// pathParameterSchema returns the schema of the path parameter, constrained like the TokenRequest field it maps to.
+func pathParameterSchema(name string, schemas openapi3.Schemas) *openapi3.Schema {
+	tokenRequest := schemas["TokenRequest"].Value
+	switch name {
+	case "channelName":
+		return tokenRequest.Properties["channel"].Value
+	case "role":
+		return tokenRequest.Properties["role"].Value
+	case "tokenType":
+		return openapi3.NewStringSchema().WithEnum("uid", "userAccount")
+	case "rtcuid", "rtmuid", "chatid":
+		return openapi3.NewStringSchema().WithMinLength(1).WithMaxLength(maxAccountLength)
+	}
+	return openapi3.NewStringSchema()
+}
+
+// auditQueryParameterSchema returns the schema of the audit search query parameter.
+func auditQueryParameterSchema(name string) *openapi3.Schema {
+	switch name {
+	case "type":
+		return openapi3.NewStringSchema().WithEnum("rtc", "rtm", "chat")
+	case "from", "to":
+		return openapi3.NewDateTimeSchema()
+	case "limit":
+		return openapi3.NewIntegerSchema().WithMin(1).WithMax(maxAuditPageSize)
+	case "format":
+		return openapi3.NewStringSchema().WithEnum("json", "csv")
+	}
+	return openapi3.NewStringSchema()
+}
+
+// openAPISchemas returns the component schemas of the specification, generated from the Go types.
+func openAPISchemas(config *Config) (openapi3.Schemas, error) {
+	schemas := openapi3.Schemas{}
+	for name, value := range map[string]any{
+		"TokenRequest":             TokenRequest{},
+		"TokenResponse":            TokenResponse{},
+		"TokenResult":              TokenResult{},
+		"InspectTokenRequest":      InspectTokenRequest{},
+		"TokenInfo":                TokenInfo{},
+		"ErrorResponse":            ErrorResponse{},
+		"ProblemDetails":           ProblemDetails{},
+		"ReadinessReport":          ReadinessReport{},
+		"BuildInfo":                BuildInfo{},
+		"CertificateStatus":        CertificateStatus{},
+		"RotateCertificateRequest": RotateCertificateRequest{},
+		"AuditPage":                AuditPage{},
+	} {
+		schema, err := openapi3gen.NewSchemaRefForValue(value, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		schemas[name] = schema
+	}
+
+	tokenRequest := schemas["TokenRequest"].Value
+	for name, property := range tokenRequest.Properties {
+		// The generated schemas of a type are shared, copy them before adding constraints
+		value := *property.Value
+		if value.Items != nil {
+			items := *value.Items.Value
+			value.Items = &openapi3.SchemaRef{Value: &items}
+		}
+		tokenRequest.Properties[name] = &openapi3.SchemaRef{Value: &value}
+	}
+	for name, description := range tokenRequestFields {
+		property, exists := tokenRequest.Properties[name]
+		if !exists {
+			return nil, fmt.Errorf("TokenRequest: no %s field", name)
+		}
+		property.Value.Description = description
+	}
+	tokenRequest.Properties["tokenType"].Value.WithEnum("rtc", "rtm", "chat")
+	tokenRequest.Properties["channel"].Value.WithMaxLength(maxChannelLength).WithPattern(channelPattern.String())
+	tokenRequest.Properties["role"].Value.WithEnum("publisher", "subscriber").WithDefault("subscriber")
+	tokenRequest.Properties["uid"].Value.WithMaxLength(maxAccountLength)
+	tokenRequest.Properties["services"].Value.WithUniqueItems(true).Items.Value.WithEnum("rtc", "rtm", "chat")
+	for _, name := range []string{"expire", "joinChannelExpire", "pubAudioExpire", "pubVideoExpire", "pubDataStreamExpire"} {
+		// 0 stands for the default expiration
+		tokenRequest.Properties[name].Value.WithMin(0).WithMax(float64(min(config.Expire.Max, math.MaxUint32)))
+	}
+
+	batch := openapi3.NewArraySchema().WithMinItems(1).WithMaxItems(int64(config.Batch.MaxSize))
+	batch.Items = componentRef(schemas, "TokenRequest")
+	results := openapi3.NewArraySchema()
+	results.Items = componentRef(schemas, "TokenResult")
+	certificates := openapi3.NewObjectSchema()
+	certificates.WithPropertyRef("certificates", &openapi3.SchemaRef{Value: openapi3.NewArraySchema()})
+	certificates.Properties["certificates"].Value.Items = componentRef(schemas, "CertificateStatus")
+	for name, schema := range map[string]*openapi3.Schema{
+		"TokenBatch":           batch,
+		"TokenResults":         results,
+		"CertificatesResponse": certificates,
+		"RtcTokenResponse":     openapi3.NewObjectSchema().WithProperty("rtcToken", openapi3.NewStringSchema()),
+		"RtmTokenResponse":     openapi3.NewObjectSchema().WithProperty("rtmToken", openapi3.NewStringSchema()),
+		"RteTokenResponse": openapi3.NewObjectSchema().WithProperty("rtcToken", openapi3.NewStringSchema()).
+			WithProperty("rtmToken", openapi3.NewStringSchema()),
+		"ChatTokenResponse": openapi3.NewObjectSchema().WithProperty("chatToken", openapi3.NewStringSchema()),
+		"PingResponse":      openapi3.NewObjectSchema().WithProperty("message", openapi3.NewStringSchema()),
+		"HealthResponse":    openapi3.NewObjectSchema().WithProperty("status", openapi3.NewStringSchema()),
+		"OpenAPI":           openapi3.NewObjectSchema().WithAnyAdditionalProperties(),
+	} {
+		schemas[name] = &openapi3.SchemaRef{Value: schema}
+	}
+	return schemas, nil
+}
+
+// openAPISecuritySchemes describes the authentication methods enabled by the configuration.
+func openAPISecuritySchemes(config AuthConfig) openapi3.SecuritySchemes {
+	schemes := openapi3.SecuritySchemes{}
+	if len(config.APIKeys) > 0 {
+		schemes["apiKey"] = &openapi3.SecuritySchemeRef{Value: openapi3.NewSecurityScheme().WithType("apiKey").
+			WithIn("header").WithName("X-API-Key")}
+	}
+	if len(config.HMACKeys) > 0 {
+		schemes["hmac"] = &openapi3.SecuritySchemeRef{Value: openapi3.NewSecurityScheme().WithType("apiKey").
+			WithIn("header").WithName("X-Auth-Signature").
+			WithDescription("The HMAC-SHA256 signature of the request, along with the X-Auth-Key-Id and X-Auth-Timestamp headers.")}
+	}
+	if config.JWKSFile != "" {
+		schemes["bearer"] = &openapi3.SecuritySchemeRef{Value: openapi3.NewJWTSecurityScheme()}
+	}
+	return schemes
+}
+
+// getOpenAPI responds with the OpenAPI specification of the REST API.
+func (s *Service) getOpenAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, s.openAPI)
+}
+
+// getDocs responds with the Swagger UI page, when enabled.
+func (s *Service) getDocs(c *gin.Context) {
+	if !s.openAPIDocs {
+		abortWithError(c, &APIError{Status: http.StatusNotFound, Code: CodeNotFound, Message: "not found: " + c.Request.URL.Path})
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", docsPage)
+}
+
+// openAPIValidationMiddleware rejects the requests whose parameters or JSON body do not match the OpenAPI
+// specification with 400 Bad Request, when request validation is enabled. Requests to routes missing from
+// the specification are left to the handlers. It follows AuthMiddleware, so only authenticated callers get
+// the validation errors, and bodies larger than the largest token request are rejected before being read.
+func (s *Service) openAPIValidationMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !s.openAPIValidation || c.FullPath() == "" {
+			c.Next()
+			return
+		}
+		path := openAPIPath(c.FullPath())
+		pathItem := s.openAPI.Paths.Value(path)
+		if pathItem == nil || pathItem.GetOperation(c.Request.Method) == nil {
+			c.Next()
+			return
+		}
+
+		pathParams := make(map[string]string, len(c.Params))
+		for _, param := range c.Params {
+			pathParams[param.Key] = param.Value
+		}
+		maxSize := s.maxRequestBodySize()
+		if c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize)
+		}
+		request := c.Request
+		if request.ContentLength != 0 && request.Header.Get("Content-Type") == "" {
+			// The handlers decode every body as JSON
+			request = request.Clone(request.Context())
+			request.Header.Set("Content-Type", "application/json")
+		}
+		err := openapi3filter.ValidateRequest(c.Request.Context(), &openapi3filter.RequestValidationInput{
+			Request:    request,
+			PathParams: pathParams,
+			Route: &routers.Route{
+				Spec: s.openAPI, Path: path, PathItem: pathItem,
+				Method: c.Request.Method, Operation: pathItem.GetOperation(c.Request.Method),
+			},
+			Options: &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc},
+		})
+		if tooLarge := bodyTooLarge(err, maxSize); tooLarge != nil {
+			abortWithError(c, tooLarge)
+			return
+		}
+		if err != nil {
+			abortWithError(c, invalidRequest(err))
+			return
+		}
+		// The validation reads the body and replaces it with a copy
+		c.Request.Body = request.Body
+		c.Next()
+	}
+}
+
+// invalidRequest returns the INVALID_REQUEST APIError of a request validation error, about the parameter or
+// the body field at fault.
+func invalidRequest(err error) *APIError {
+	var requestErr *openapi3filter.RequestError
+	if !errors.As(err, &requestErr) {
+		return badRequest(CodeInvalidRequest, "", "invalid: %s", err)
+	}
+	field, reason := "", requestErr.Error()
+	if requestErr.Parameter != nil {
+		field = requestErr.Parameter.Name
+	}
+	var schemaErr *openapi3.SchemaError
+	if errors.As(requestErr.Err, &schemaErr) {
+		reason = schemaErr.Reason
+		if requestErr.RequestBody != nil {
+			field = strings.Join(schemaErr.JSONPointer(), ".")
+		}
+	}
+	return badRequest(CodeInvalidRequest, field, "invalid: %s", reason)
+}
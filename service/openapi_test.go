@@ -0,0 +1,108 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenAPISpec(t *testing.T) {
+	t.Setenv("AUTH_API_KEYS", "backend:backend-key")
+	t.Setenv("EXPIRE_MAX", "7200")
+	service := NewService()
+
+	req, _ := http.NewRequest(http.MethodGet, "/openapi.json", nil)
+	resp := httptest.NewRecorder()
+	service.Server.Handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	spec, err := openapi3.NewLoader().LoadFromData(resp.Body.Bytes())
+	if !assert.NoError(t, err) || !assert.NoError(t, spec.Validate(req.Context())) {
+		return
+	}
+	// Every route of the service is documented
+	for _, route := range service.Server.Handler.(*gin.Engine).Routes() {
+		pathItem := spec.Paths.Value(openAPIPath(route.Path))
+		if assert.NotNil(t, pathItem, route.Path) {
+			assert.NotNil(t, pathItem.GetOperation(route.Method), route.Method, route.Path)
+		}
+	}
+
+	tokenRequest := spec.Components.Schemas["TokenRequest"].Value
+	assert.Equal(t, float64(7200), *tokenRequest.Properties["expire"].Value.Max)
+	assert.Equal(t, uint64(maxChannelLength), *tokenRequest.Properties["channel"].Value.MaxLength)
+	for name, property := range tokenRequest.Properties {
+		assert.NotEmpty(t, property.Value.Description, name)
+	}
+	assert.Contains(t, spec.Components.SecuritySchemes, "apiKey")
+	assert.Len(t, *spec.Paths.Value("/getToken").Post.Security, 1)
+	assert.Nil(t, spec.Paths.Value("/healthz").Get.Security)
+}
+
+func TestDocs(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "/docs", nil)
+	resp := httptest.NewRecorder()
+	testService.Server.Handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+
+	t.Setenv("OPENAPI_DOCS", "true")
+	resp = httptest.NewRecorder()
+	NewService().Server.Handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, resp.Body.String(), `url: "openapi.json"`)
+}
+
+func TestOpenAPIValidation(t *testing.T) {
+	t.Setenv("OPENAPI_VALIDATE_REQUESTS", "true")
+	service := NewService()
+
+	tests := []struct {
+		method string
+		url    string
+		body   string
+		status int
+		field  string
+	}{
+		{http.MethodPost, "/getToken", `{"tokenType": "rtc", "channel": "room", "uid": "1", "role": "publisher"}`, http.StatusOK, ""},
+		{http.MethodPost, "/projects/default/getToken", `{"tokenType": "rtm", "uid": "1"}`, http.StatusNotFound, "project"},
+		{http.MethodPost, "/getToken", `{"tokenType": "video", "uid": "1"}`, http.StatusBadRequest, "tokenType"},
+		{http.MethodPost, "/getToken", `{"tokenType": "rtc", "channel": "` + strings.Repeat("a", 65) + `", "uid": "1"}`, http.StatusBadRequest, "channel"},
+		{http.MethodPost, "/getToken", `{"tokenType": "rtm", "uid": "1", "expire": "1h"}`, http.StatusBadRequest, "expire"},
+		{http.MethodPost, "/getTokens", `[{"tokenType": "rtm", "uid": "1"}, {"services": ["rtm", "rtm"], "uid": "1"}]`, http.StatusBadRequest, "1.services"},
+		{http.MethodGet, "/rtc/room/host/uid/1/", "", http.StatusBadRequest, "role"},
+		{http.MethodGet, "/rtm/1/?expiry=-1", "", http.StatusBadRequest, "expiry"},
+		{http.MethodGet, "/rtm/1/?expiry=600", "", http.StatusOK, ""},
+		{http.MethodPost, "/getToken", `{"tokenType": "rtm", "uid": "1"}` + strings.Repeat(" ", int(service.maxRequestBodySize())), http.StatusRequestEntityTooLarge, ""},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest(test.method, test.url, bytes.NewBufferString(test.body))
+		resp := httptest.NewRecorder()
+		service.Server.Handler.ServeHTTP(resp, req)
+		assert.Equal(t, test.status, resp.Code, test.url, test.body, resp.Body.String())
+		if test.status != http.StatusBadRequest {
+			continue
+		}
+
+		var response ErrorResponse
+		if assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response)) {
+			assert.Equal(t, CodeInvalidRequest, response.Code, test.body)
+			assert.Equal(t, test.field, response.Field, test.body)
+		}
+	}
+	// Requests are authenticated before being validated
+	t.Setenv("AUTH_API_KEYS", "backend:backend-key")
+	service = NewService()
+	req, _ := http.NewRequest(http.MethodPost, "/getToken", bytes.NewBufferString(`{"tokenType": "video", "uid": "1"}`))
+	resp := httptest.NewRecorder()
+	service.Server.Handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.NotContains(t, resp.Body.String(), "tokenType")
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// Options configures a Service embedded in another Go application with New.
+type Options struct {
+	// AppID and AppCertificate are the credentials of the default project.
+	AppID          string
+	AppCertificate string
+
+	// Projects are additional projects, indexed by name, selected through the "project" request field or the
+	// /projects/:project route prefix.
+	Projects map[string]*Project
+
+	// CORSAllowOrigin is "*" or a comma separated list of the origins allowed to call the endpoints from browsers.
+	CORSAllowOrigin string
+
+	// Logger writes the logs of the service. Logs are written to stdout, as configured by Config.Log, when nil.
+	Logger *slog.Logger
+
+	// Middlewares wrap the handler of the service, the first one being the outermost, such as the tracing
+	// or authentication middlewares of the application.
+	Middlewares []func(http.Handler) http.Handler
+
+	// AuditSinks record each issued token, along with the sinks of Config.Audit. They are closed by the
+	// application, once the service is shut down.
+	AuditSinks []AuditSink
+
+	// BasePath is the path prefix the handler is mounted under, such as "/tokens": the endpoints are then
+	// served at /tokens/getToken, /tokens/healthz, and so on.
+	BasePath string
+
+	// Config holds the other settings, such as authentication, policies and rate limits, as returned by
+	// DefaultConfig or LoadConfig. DefaultConfig is used when nil. The fields above replace the ones of
+	// the Config when set.
+	Config *Config
+}
+
+// New returns a Service with the settings of the options, to mount its Handler in an existing HTTP server,
+// or to Start it on its own.
+//
+// Parameters:
+//   - options: Options - The credentials, CORS origins, logger, middlewares, base path and other settings.
+//
+// Returns:
+//   - *Service: The service.
+//   - error: An error listing the invalid settings, if any.
+//
+// Behavior:
+//  1. Replaces the settings of the Config, or of DefaultConfig, with the ones set in the options, and validates them.
+//  2. Builds the service as NewServiceFromConfig does.
+//  3. Strips the base path from the requests, and wraps the handler with the middlewares.
+//
+// Notes:
+//   - Unlike NewService, New reads neither the environment nor the .env file, and reports errors instead of
+//     exiting. Reload, and SIGHUP once started, apply the same options again.
+//   - Settings read from files, such as the projects file and certificate files, are resolved by LoadConfig.
+//
+// Example usage:
+//
+//	tokens, err := service.New(service.Options{
+//	    AppID:          appID,
+//	    AppCertificate: appCertificate,
+//	    Logger:         logger,
+//	    BasePath:       "/tokens",
+//	})
+//	if err != nil {
+//	    return err
+//	}
+//	mux.Handle("/tokens/", tokens.Handler())
+func New(options Options) (*Service, error) {
+	config := DefaultConfig()
+	if options.Config != nil {
+		copied := *options.Config
+		config = &copied
+	}
+	if options.AppID != "" || options.AppCertificate != "" {
+		config.AppID, config.AppCertificate = options.AppID, options.AppCertificate
+	}
+	if options.Projects != nil {
+		config.Projects = options.Projects
+	}
+	if options.CORSAllowOrigin != "" {
+		config.CORSAllowOrigin = options.CORSAllowOrigin
+	}
+	config.logger = options.Logger
+	config.auditSinks = options.AuditSinks
+	config.basePath = strings.TrimSuffix("/"+strings.Trim(options.BasePath, "/"), "/")
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	s, err := NewServiceFromConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	s.loadConfig = func() (*Config, error) {
+		return config, nil
+	}
+	handler := s.Server.Handler
+	if config.basePath != "" {
+		handler = http.StripPrefix(config.basePath, handler)
+	}
+	for i := len(options.Middlewares) - 1; i >= 0; i-- {
+		handler = options.Middlewares[i](handler)
+	}
+	s.Server.Handler = handler
+	return s, nil
+}
+
+// Handler returns the HTTP handler of the endpoints of the service, as served by Start.
+func (s *Service) Handler() http.Handler {
+	return s.Server.Handler
+}
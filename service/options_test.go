@@ -0,0 +1,118 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testAppID          = "6ce46dd303d54056a52f9a34c13c547e"
+	testAppCertificate = "77be7e16f7482cef9fe796205b85831e"
+)
+
+func TestTokenGenerator(t *testing.T) {
+	generator, err := NewTokenGenerator(GeneratorOptions{
+		AppID: testAppID, AppCertificate: testAppCertificate,
+		Projects: map[string]*Project{"staging": {AppID: testAppID, AppCertificate: testAppCertificate}},
+		Expire:   ExpireConfig{Min: 60, Max: 600},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// Tokens are verified with the same credentials, whatever the environment of the tests
+	inspector, err := New(Options{AppID: testAppID, AppCertificate: testAppCertificate})
+	if !assert.NoError(t, err) {
+		return
+	}
+	token, err := generator.GenRtcToken(TokenRequest{Channel: "room", Uid: "42", RtcRole: "publisher"})
+	if assert.NoError(t, err) {
+		info, err := inspector.InspectToken(token)
+		if assert.NoError(t, err) {
+			assert.True(t, info.SignatureValid)
+			// The default expiration is capped by the expiration range
+			assert.Equal(t, uint32(600), info.Expire)
+		}
+	}
+	_, err = generator.GenRtmToken(TokenRequest{Uid: "user", Project: "staging"})
+	assert.NoError(t, err)
+	_, err = generator.GenChatToken(TokenRequest{ExpirationSeconds: 601})
+	assert.Equal(t, CodeExpiryTooLong, NewErrorResponse(err).Code)
+	_, err = generator.GenRtmToken(TokenRequest{Uid: "user", Project: "unknown"})
+	assert.Equal(t, CodeUnknownProject, NewErrorResponse(err).Code)
+	tokens, err := generator.GenServiceTokens(TokenRequest{Services: []string{"rtc", "rtm"}, Channel: "room", Uid: "42"})
+	if assert.NoError(t, err) {
+		assert.Len(t, tokens, 2)
+	}
+
+	for _, options := range []GeneratorOptions{
+		{},
+		{AppID: testAppID, AppCertificate: "invalid"},
+		{AppID: testAppID, AppCertificate: testAppCertificate, Expire: ExpireConfig{Min: 600, Max: 60}},
+	} {
+		_, err := NewTokenGenerator(options)
+		assert.Error(t, err, options)
+	}
+}
+
+func TestNew(t *testing.T) {
+	var logs bytes.Buffer
+	config := DefaultConfig()
+	config.Auth.APIKeys = map[string]string{"backend": "backend-key"}
+	var wrapped bool
+	s, err := New(Options{
+		AppID: testAppID, AppCertificate: testAppCertificate,
+		Logger: slog.New(slog.NewJSONHandler(&logs, nil)),
+		Middlewares: []func(http.Handler) http.Handler{func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				wrapped = true
+				next.ServeHTTP(w, r)
+			})
+		}},
+		BasePath: "/tokens/",
+		Config:   config,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/tokens/", s.Handler())
+
+	serve := func(method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("X-API-Key", "backend-key")
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := serve(http.MethodPost, "/tokens/getToken", `{"tokenType": "rtm", "uid": "user"}`)
+	if assert.Equal(t, http.StatusOK, resp.Code, resp.Body.String()) {
+		var response TokenResponse
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+		assert.NotEmpty(t, response.Token)
+	}
+	assert.True(t, wrapped)
+	assert.Contains(t, logs.String(), `"path":"/getToken"`)
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/tokens/healthz", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/getToken", "").Code)
+
+	resp = serve(http.MethodGet, "/tokens/openapi.json", "")
+	assert.Contains(t, resp.Body.String(), `"servers":[{"url":"/tokens"}]`)
+
+	// Reload applies the options again rather than the environment
+	t.Setenv("APP_ID", "")
+	assert.NoError(t, s.Reload())
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/tokens/getToken", `{"tokenType": "rtm", "uid": "user"}`).Code)
+
+	_, err = New(Options{AppID: testAppID})
+	assert.Error(t, err)
+	_, err = New(Options{AppID: testAppID, AppCertificate: testAppCertificate, CORSAllowOrigin: "example.com"})
+	assert.Error(t, err)
+}
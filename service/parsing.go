@@ -1,7 +1,6 @@
 package service
 
 import (
-	"fmt"
 	"strconv"
 	"strings"
 
@@ -30,9 +29,11 @@ import (
 //  1. Retrieves the values of channelName, roleStr, tokenType, rtcuid, and rtmuid from the Gin context.
 //  2. Sets uidStr to "0" if it is empty, implying that any user ID is allowed.
 //  3. If rtmuid is empty and uidStr is not "0", it sets rtmuid to uidStr.
-//  4. Determines the role based on the value of roleStr. "publisher" maps to RolePublisher, else RoleSubscriber.
-//  5. Parses the expiry time from the query parameter "expiry" and converts it to uint32.
-//  6. If string conversion fails for the expiry time, sets err to an error with the failure information.
+//  4. Validates the channel name, and the user ID or account depending on the tokenType.
+//  5. Determines the role based on the value of roleStr. "publisher" maps to RolePublisher, "subscriber" to
+//     RoleSubscriber, and any other value is rejected.
+//  6. Parses the expiry time from the query parameter "expiry" and checks it against the configured range.
+//  7. If a parameter is invalid, sets err to an error with the failure information.
 //
 // Notes:
 //   - The `rtctokenbuilder2.Role` type represents the role of a user in the video conferencing token.
@@ -58,37 +59,39 @@ func (s *Service) parseRtcParams(c *gin.Context) (channelName, tokenType, uidStr
 		rtmuid = uidStr
 	}
 
-	if roleStr == "publisher" {
-		role = rtctokenbuilder2.RolePublisher
+	if err = validateChannel("channelName", channelName); err != nil {
+		return channelName, tokenType, uidStr, rtmuid, role, expire, err
+	}
+	// non-numeric user IDs are rejected when generating the token
+	if tokenType == "userAccount" {
+		err = validateUid("rtcuid", uidStr, false)
 	} else {
-		// Making an assumption that !publisher == subscriber
-		role = rtctokenbuilder2.RoleSubscriber
+		err = validateUid("rtcuid", uidStr, true)
+	}
+	if err == nil && rtmuid != "" {
+		err = validateUid("rtmuid", rtmuid, false)
+	}
+	if err != nil {
+		return channelName, tokenType, uidStr, rtmuid, role, expire, err
 	}
 
-	expireTime := c.DefaultQuery("expiry", "3600")
-	expireTime64, parseErr := strconv.ParseUint(expireTime, 10, 64)
-	if parseErr != nil {
-		// if string conversion fails return an error
-		err = fmt.Errorf("failed to parse expireTime: %s, causing error: %s", expireTime, parseErr)
+	if role, err = parseRole("role", roleStr); err != nil {
+		return channelName, tokenType, uidStr, rtmuid, role, expire, err
 	}
-	expire = uint32(expireTime64)
 
+	expire, err = s.parseExpiry(c)
 	return channelName, tokenType, uidStr, rtmuid, role, expire, err
 }
 
 func (s *Service) parseRtmParams(c *gin.Context) (uidStr string, expire uint32, err error) {
 	// get param values
 	uidStr = c.Param("rtmuid")
-	expireTime := c.DefaultQuery("expiry", "3600")
-	expireTime64, parseErr := strconv.ParseUint(expireTime, 10, 64)
-	if parseErr != nil {
-		// if string conversion fails return an error
-		err = fmt.Errorf("failed to parse expireTime: %s, causing error: %s", expireTime, parseErr)
-	}
-	expire = uint32(expireTime64)
+	expire, err = s.parseExpiry(c)
 
 	if uidStr == "" || uidStr == "0" {
-		err = fmt.Errorf("invalid RTM User ID: \"%s\"", uidStr)
+		err = badRequest(CodeInvalidUid, "rtmuid", "invalid RTM User ID: \"%s\"", uidStr)
+	} else if uidErr := validateUid("rtmuid", uidStr, false); uidErr != nil {
+		err = uidErr
 	}
 
 	// check if string conversion fails
@@ -105,21 +108,39 @@ func (s *Service) parseChatParams(c *gin.Context) (uidStr string, tokenType stri
 			break
 		}
 	}
-	expireTime := c.DefaultQuery("expiry", "3600")
-	expireTime64, parseErr := strconv.ParseUint(expireTime, 10, 64)
-	if parseErr != nil {
-		// if string conversion fails return an error
-		err = fmt.Errorf("failed to parse expireTime: %s, causing error: %s", expireTime, parseErr)
-	}
-	expire = uint32(expireTime64)
+	expire, err = s.parseExpiry(c)
 	if tokenType == "account" {
 		tokenType = "userAccount"
 	}
 	if uidStr == "" && tokenType != "app" {
-		err = fmt.Errorf("userAccount type requires chat ID")
+		err = badRequest(CodeMissingUid, "chatid", "userAccount type requires chat ID")
 		return uidStr, tokenType, expire, err
 	}
+	if uidErr := validateUid("chatid", uidStr, false); uidErr != nil {
+		err = uidErr
+	}
 
 	// check if string conversion fails
 	return uidStr, tokenType, expire, err
 }
+
+// parseExpiry parses the "expiry" query parameter, in seconds, and checks it against the configured range.
+// A missing or zero expiry stands for the default expiration.
+func (s *Service) parseExpiry(c *gin.Context) (expire uint32, err error) {
+	expireTime := c.Query("expiry")
+	if expireTime == "" {
+		return uint32(s.tokenGenerator().defaultExpire()), nil
+	}
+	expireTime32, parseErr := strconv.ParseUint(expireTime, 10, 32)
+	if parseErr != nil {
+		// if string conversion fails return an error
+		return 0, badRequest(CodeInvalidExpiry, "expiry", "failed to parse expireTime: %s, causing error: %s", expireTime, parseErr)
+	}
+	if expireTime32 == 0 {
+		return uint32(s.tokenGenerator().defaultExpire()), nil
+	}
+	if err = s.tokenGenerator().validateExpire("expiry", int(expireTime32)); err != nil {
+		return 0, err
+	}
+	return uint32(expireTime32), nil
+}
@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Policy is a declarative set of rules restricting which tokens each principal may request.
+// Rules only restrict: a request is allowed unless one of the rules that apply to it is not satisfied.
+//
+// Policies are loaded from a YAML or JSON file:
+//
+//	rules:
+//	  - name: viewers-use-own-uid
+//	    when:
+//	      claims: {role: viewer}
+//	    require:
+//	      uid: "{subject}"
+//	      roles: [subscriber]
+//	  - name: only-hosts-publish
+//	    when:
+//	      roles: [publisher]
+//	    require:
+//	      claims: {role: host}
+//	  - name: tenant-channels
+//	    require:
+//	      channelPrefix: "team-{tenant}-"
+//	      maxExpire: 3600
+//
+// The uid and channelPrefix requirements are templates: "{subject}" is replaced with the principal
+// subject and "{name}" with the value of the principal's "name" claim.
+type Policy struct {
+	Rules []PolicyRule `yaml:"rules" json:"rules"`
+}
+
+// PolicyRule is a single named rule of a Policy.
+type PolicyRule struct {
+	// Name identifies the rule in denial responses.
+	Name string `yaml:"name" json:"name"`
+
+	// When selects the requests the rule applies to. An empty condition applies to every request.
+	When PolicyCondition `yaml:"when" json:"when"`
+
+	// Require lists the constraints a selected request must satisfy.
+	Require PolicyRequirement `yaml:"require" json:"require"`
+}
+
+// PolicyCondition selects requests by their principal and requested token. Empty fields match anything.
+type PolicyCondition struct {
+	Subjects   []string          `yaml:"subjects" json:"subjects"`     // Principal subjects, a trailing "*" matches any suffix
+	Methods    []string          `yaml:"methods" json:"methods"`       // Authentication methods: "apiKey", "hmac", "jwt" or "mtls"
+	Claims     map[string]string `yaml:"claims" json:"claims"`         // Principal claims and the value they must have
+	TokenTypes []string          `yaml:"tokenTypes" json:"tokenTypes"` // Requested token types: "rtc", "rtm" or "chat"
+	Roles      []string          `yaml:"roles" json:"roles"`           // Requested RTC roles: "publisher" or "subscriber"
+}
+
+// PolicyRequirement lists the constraints of a rule. Empty fields are not enforced.
+type PolicyRequirement struct {
+	Claims        map[string]string `yaml:"claims" json:"claims"`               // Principal claims and the value they must have
+	Uid           string            `yaml:"uid" json:"uid"`                     // Template the requested uid must be equal to
+	Roles         []string          `yaml:"roles" json:"roles"`                 // RTC roles that may be requested
+	ChannelPrefix string            `yaml:"channelPrefix" json:"channelPrefix"` // Template the requested channel must start with
+	MaxExpire     int               `yaml:"maxExpire" json:"maxExpire"`         // Longest token and privilege expiration in seconds
+}
+
+// PolicyViolation is returned when a request does not satisfy a policy rule.
+type PolicyViolation struct {
+	// Rule is the name of the rule that was not satisfied.
+	Rule string
+
+	// Reason describes the requirement that failed.
+	Reason string
+}
+
+// Error implements error.
+func (v *PolicyViolation) Error() string {
+	return fmt.Sprintf("denied by policy rule %q: %s", v.Rule, v.Reason)
+}
+
+// LoadPolicy reads and parses a YAML or JSON policy file.
+func LoadPolicy(policyFile string) (*Policy, error) {
+	content, err := os.ReadFile(policyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %s", err)
+	}
+	policy := &Policy{}
+	if err := yaml.Unmarshal(content, policy); err != nil {
+		return nil, fmt.Errorf("failed to parse policy file %s: %s", policyFile, err)
+	}
+	for i, rule := range policy.Rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("policy rule %d is missing a name", i)
+		}
+	}
+	return policy, nil
+}
+
+// Evaluate checks the token request of the principal against every rule.
+// It returns the PolicyViolation of the first rule that applies to the request and is not satisfied,
+// or nil if the request is allowed. A nil principal stands for an unauthenticated caller.
+func (p *Policy) Evaluate(principal *Principal, tokenRequest TokenRequest) *PolicyViolation {
+	if p == nil {
+		return nil
+	}
+	if principal == nil {
+		principal = &Principal{}
+	}
+
+	for _, rule := range p.Rules {
+		if !rule.When.matches(principal, tokenRequest) {
+			continue
+		}
+		if reason := rule.Require.check(principal, tokenRequest); reason != "" {
+			return &PolicyViolation{Rule: rule.Name, Reason: reason}
+		}
+	}
+	return nil
+}
+
+// authorize evaluates the service policy for the token request of the principal stored in the request context.
+// Requests without an expiration are checked with the default one the token is issued with, within the
+// configured range. The caller must hold mu for reading.
+func (s *Service) authorize(ctx context.Context, tokenRequest TokenRequest) *PolicyViolation {
+	if tokenRequest.ExpirationSeconds == 0 && !tokenRequest.hasPrivilegeExpires() {
+		tokenRequest.ExpirationSeconds = s.tokenGenerator().defaultExpire()
+	}
+	principal, _ := PrincipalFromContext(ctx)
+	return s.policy.Evaluate(principal, tokenRequest)
+}
+
+// matches reports whether the condition selects the request.
+func (c PolicyCondition) matches(principal *Principal, tokenRequest TokenRequest) bool {
+	if len(c.Subjects) > 0 && !matchesAny(c.Subjects, principal.Subject) {
+		return false
+	}
+	if len(c.Methods) > 0 && !contains(c.Methods, principal.Method) {
+		return false
+	}
+	for claim, value := range c.Claims {
+		if !principal.hasClaim(claim, value) {
+			return false
+		}
+	}
+	if len(c.TokenTypes) > 0 && !contains(c.TokenTypes, tokenRequest.TokenType) {
+		return false
+	}
+	if len(c.Roles) > 0 && (tokenRequest.TokenType != "rtc" || !contains(c.Roles, requestedRole(tokenRequest))) {
+		return false
+	}
+	return true
+}
+
+// check returns why the request does not satisfy the requirement, or an empty string if it does.
+func (r PolicyRequirement) check(principal *Principal, tokenRequest TokenRequest) string {
+	for claim, value := range r.Claims {
+		if !principal.hasClaim(claim, value) {
+			return fmt.Sprintf("claim %q must be %q", claim, value)
+		}
+	}
+
+	if r.Uid != "" {
+		uid, ok := expandTemplate(r.Uid, principal)
+		if !ok || tokenRequest.Uid != uid {
+			return fmt.Sprintf("uid must be %q", r.Uid)
+		}
+	}
+
+	if len(r.Roles) > 0 && tokenRequest.TokenType == "rtc" && !contains(r.Roles, requestedRole(tokenRequest)) {
+		return fmt.Sprintf("role must be one of %s", strings.Join(r.Roles, ", "))
+	}
+
+	if r.ChannelPrefix != "" && tokenRequest.Channel != "" {
+		prefix, ok := expandTemplate(r.ChannelPrefix, principal)
+		if !ok || !strings.HasPrefix(tokenRequest.Channel, prefix) {
+			return fmt.Sprintf("channel must start with %q", r.ChannelPrefix)
+		}
+	}
+
+	if r.MaxExpire > 0 {
+		expires := []int{
+			tokenRequest.ExpirationSeconds, tokenRequest.JoinChannelExpire, tokenRequest.PubAudioExpire,
+			tokenRequest.PubVideoExpire, tokenRequest.PubDataStreamExpire,
+		}
+		if tokenRequest.ExpirationSeconds == 0 && !tokenRequest.hasPrivilegeExpires() {
+			// The service resolves the expiration before authorizing; others get the default of the unconfigured range
+			expires[0] = defaultTokenExpire
+		}
+		for _, expire := range expires {
+			if expire > r.MaxExpire {
+				return fmt.Sprintf("expiration must not exceed %d seconds", r.MaxExpire)
+			}
+		}
+	}
+
+	return ""
+}
+
+// hasClaim reports whether the principal claim has the value, or contains it when the claim is a list.
+func (p *Principal) hasClaim(claim, value string) bool {
+	switch claimValue := p.Claims[claim].(type) {
+	case nil:
+		return false
+	case []interface{}:
+		for _, item := range claimValue {
+			if fmt.Sprint(item) == value {
+				return true
+			}
+		}
+		return false
+	default:
+		return fmt.Sprint(claimValue) == value
+	}
+}
+
+// expandTemplate replaces "{subject}" with the principal subject and "{name}" with the principal's claim "name".
+// It returns false when the template references a claim the principal does not have.
+func expandTemplate(template string, principal *Principal) (string, bool) {
+	var expanded strings.Builder
+	for {
+		start := strings.Index(template, "{")
+		end := strings.Index(template, "}")
+		if start < 0 || end < start {
+			expanded.WriteString(template)
+			return expanded.String(), true
+		}
+		expanded.WriteString(template[:start])
+
+		name := template[start+1 : end]
+		var value string
+		if name == "subject" {
+			value = principal.Subject
+		} else if claim, exists := principal.Claims[name]; exists {
+			if _, isList := claim.([]interface{}); !isList {
+				value = fmt.Sprint(claim)
+			}
+		}
+		if value == "" {
+			return "", false
+		}
+		expanded.WriteString(value)
+		template = template[end+1:]
+	}
+}
+
+// requestedRole returns the RTC role of the request, treating anything but "publisher" as "subscriber".
+func requestedRole(tokenRequest TokenRequest) string {
+	if tokenRequest.RtcRole == "publisher" {
+		return "publisher"
+	}
+	return "subscriber"
+}
+
+// matchesAny reports whether the value matches one of the patterns, where a trailing "*" matches any suffix.
+func matchesAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "*") && strings.HasPrefix(value, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+		if pattern == value {
+			return true
+		}
+	}
+	return false
+}
+
+// contains reports whether the values include the value.
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testPolicy = `
+rules:
+  - name: viewers-use-own-uid
+    when:
+      claims: {role: viewer}
+    require:
+      uid: "{subject}"
+      roles: [subscriber]
+  - name: only-hosts-publish
+    when:
+      roles: [publisher]
+    require:
+      claims: {role: host}
+  - name: tenant-channels
+    when:
+      methods: [jwt]
+    require:
+      channelPrefix: "team-{tenant}-"
+  - name: short-lived-tokens
+    when:
+      subjects: ["ci-*"]
+    require:
+      maxExpire: 600
+`
+
+// writePolicy writes the policy to a temporary file and returns its path.
+func writePolicy(t *testing.T, policy string) string {
+	policyFile := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(policyFile, []byte(policy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return policyFile
+}
+
+func TestPolicyEvaluate(t *testing.T) {
+	policy, err := LoadPolicy(writePolicy(t, testPolicy))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	viewer := &Principal{Subject: "42", Method: "jwt", Claims: map[string]interface{}{"role": "viewer", "tenant": "acme"}}
+	host := &Principal{Subject: "7", Method: "jwt", Claims: map[string]interface{}{"role": []interface{}{"host", "moderator"}, "tenant": "acme"}}
+	noTenant := &Principal{Subject: "8", Method: "jwt", Claims: map[string]interface{}{"role": "host"}}
+	ci := &Principal{Subject: "ci-runner", Method: "apiKey"}
+
+	tests := []struct {
+		name      string
+		principal *Principal
+		request   TokenRequest
+		rule      string
+	}{
+		{"viewer with own uid", viewer, TokenRequest{TokenType: "rtc", Channel: "team-acme-standup", Uid: "42"}, ""},
+		{"viewer with other uid", viewer, TokenRequest{TokenType: "rtc", Channel: "team-acme-standup", Uid: "43"}, "viewers-use-own-uid"},
+		{"viewer requesting publisher", viewer, TokenRequest{TokenType: "rtc", Channel: "team-acme-standup", Uid: "42", RtcRole: "publisher"}, "viewers-use-own-uid"},
+		{"host publishing", host, TokenRequest{TokenType: "rtc", Channel: "team-acme-standup", Uid: "1", RtcRole: "publisher"}, ""},
+		{"host outside of tenant", host, TokenRequest{TokenType: "rtc", Channel: "team-other-standup", Uid: "1"}, "tenant-channels"},
+		{"principal without tenant claim", noTenant, TokenRequest{TokenType: "rtc", Channel: "team--standup", Uid: "1"}, "tenant-channels"},
+		{"RTM without channel", noTenant, TokenRequest{TokenType: "rtm", Uid: "1"}, ""},
+		{"API key publishing", ci, TokenRequest{TokenType: "rtc", Channel: "any", Uid: "1", RtcRole: "publisher"}, "only-hosts-publish"},
+		{"unauthenticated publishing", nil, TokenRequest{TokenType: "rtc", Channel: "any", Uid: "1", RtcRole: "publisher"}, "only-hosts-publish"},
+		{"unauthenticated subscribing", nil, TokenRequest{TokenType: "rtc", Channel: "any", Uid: "1"}, ""},
+		{"CI with short expiry", ci, TokenRequest{TokenType: "rtm", Uid: "1", ExpirationSeconds: 600}, ""},
+		{"CI with default expiry", ci, TokenRequest{TokenType: "rtm", Uid: "1"}, "short-lived-tokens"},
+		{"CI with long privilege", ci, TokenRequest{TokenType: "rtc", Channel: "any", Uid: "1", JoinChannelExpire: 3600}, "short-lived-tokens"},
+	}
+	for _, test := range tests {
+		violation := policy.Evaluate(test.principal, test.request)
+		if test.rule == "" {
+			assert.Nil(t, violation, test.name)
+		} else if assert.NotNil(t, violation, test.name) {
+			assert.Equal(t, test.rule, violation.Rule, test.name)
+		}
+	}
+
+	// A nil policy allows everything
+	var noPolicy *Policy
+	assert.Nil(t, noPolicy.Evaluate(nil, TokenRequest{TokenType: "rtc", RtcRole: "publisher"}))
+
+	// Rules must be named
+	_, err = LoadPolicy(writePolicy(t, `{"rules": [{"require": {"maxExpire": 60}}]}`))
+	assert.Error(t, err)
+}
+
+func TestPolicyDefaultExpire(t *testing.T) {
+	t.Setenv("POLICY_FILE", writePolicy(t, `{"rules": [{"name": "hourly", "require": {"maxExpire": 3600}}]}`))
+	t.Setenv("EXPIRE_MIN", "7200")
+	service := NewService()
+
+	// The default expiration is raised to the configured minimum, beyond the policy maximum
+	violation := service.authorize(context.Background(), TokenRequest{TokenType: "rtm", Uid: "1"})
+	if assert.NotNil(t, violation) {
+		assert.Equal(t, "hourly", violation.Rule)
+	}
+	for _, test := range []struct {
+		method string
+		url    string
+		body   string
+	}{
+		{http.MethodPost, "/getToken", `{"tokenType": "rtm", "uid": "1"}`},
+		{http.MethodGet, "/rtm/username/", ""},
+	} {
+		req, _ := http.NewRequest(test.method, test.url, bytes.NewBufferString(test.body))
+		resp := httptest.NewRecorder()
+		service.Server.Handler.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusForbidden, resp.Code, test.url)
+	}
+}
+
+func TestPolicyEndpoints(t *testing.T) {
+	t.Setenv("AUTH_API_KEYS", "ci-runner:ci-key,backend:backend-key")
+	t.Setenv("POLICY_FILE", writePolicy(t, testPolicy))
+	service := NewService()
+
+	tests := []struct {
+		method string
+		url    string
+		apiKey string
+		body   []byte
+		code   int
+	}{
+		{http.MethodPost, "/getToken", "backend-key", []byte(`{"tokenType": "rtc", "channel": "test", "uid": "1"}`), http.StatusOK},
+		{http.MethodPost, "/getToken", "backend-key", []byte(`{"tokenType": "rtc", "channel": "test", "uid": "1", "role": "publisher"}`), http.StatusForbidden},
+		{http.MethodPost, "/getToken", "ci-key", []byte(`{"tokenType": "rtm", "uid": "1", "expire": 600}`), http.StatusOK},
+		{http.MethodPost, "/getToken", "ci-key", []byte(`{"tokenType": "rtm", "uid": "1", "expire": 3600}`), http.StatusForbidden},
+		{http.MethodGet, "/rtc/test/subscriber/uid/1/", "backend-key", nil, http.StatusOK},
+		{http.MethodGet, "/rtc/test/publisher/uid/1/", "backend-key", nil, http.StatusForbidden},
+		{http.MethodGet, "/rte/test/publisher/uid/1/", "backend-key", nil, http.StatusForbidden},
+		{http.MethodGet, "/rtm/username/?expiry=600", "ci-key", nil, http.StatusOK},
+		{http.MethodGet, "/rtm/username/", "ci-key", nil, http.StatusForbidden},
+		{http.MethodGet, "/chat/app/", "ci-key", nil, http.StatusForbidden},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(test.method, test.url, bytes.NewBuffer(test.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("X-API-Key", test.apiKey)
+		resp := httptest.NewRecorder()
+		service.Server.Handler.ServeHTTP(resp, req)
+		assert.Equal(t, test.code, resp.Code, test.url, resp.Body)
+
+		if test.code == http.StatusForbidden {
+			var response map[string]interface{}
+			if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+			assert.NotEmpty(t, response["rule"], test.url)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Project holds the Agora credentials of a single project the service can issue tokens for.
+type Project struct {
+	// Name is the identifier used to select the project in requests. Empty for the default project.
+	Name string `json:"-" yaml:"-" toml:"-"`
+
+	// AppID is the App ID of the Agora project.
+	AppID string `json:"appId" yaml:"appId" toml:"appId"`
+
+	// AppCertificate is the active certificate, used to sign tokens for the Agora project.
+	AppCertificate string `json:"appCertificate" yaml:"appCertificate" toml:"appCertificate"`
+
+	// AppCertificateFile is a file holding the active certificate, such as a mounted Docker or Kubernetes secret.
+	// It replaces AppCertificate when loading the configuration.
+	AppCertificateFile string `json:"appCertificateFile,omitempty" yaml:"appCertificateFile" toml:"appCertificateFile"`
+
+	// PreviousCertificates are the certificates the project was rotated away from. Tokens signed with them
+	// are still accepted by the verification features, but new tokens are signed with AppCertificate only.
+	PreviousCertificates []string `json:"previousCertificates,omitempty" yaml:"previousCertificates" toml:"previousCertificates"`
+}
+
+// certificates returns the active certificate followed by the previous ones.
+func (p *Project) certificates() []string {
+	return append([]string{p.AppCertificate}, p.PreviousCertificates...)
+}
+
+var (
+	// errUnknownProject is returned when a request references a project that is not registered.
+	errUnknownProject = &APIError{Status: http.StatusNotFound, Code: CodeUnknownProject, Field: "project", Message: "invalid: unknown project"}
+
+	// errMissingProject is returned when a request selects no project and no default project is configured.
+	errMissingProject = badRequest(CodeMissingProject, "project", "invalid: missing project")
+)
+
+// projectContextKey is the request context key holding the project selected through the route prefix.
+type projectContextKey struct{}
+
+// loadProjects builds the project registry from the projects of the configuration, which include the projects
+// file and the PROJECT_<NAME>_APP_ID / PROJECT_<NAME>_APP_CERTIFICATE environment variables.
+func loadProjects(config *Config) (map[string]*Project, error) {
+	projects := make(map[string]*Project)
+	for name, project := range config.Projects {
+		if project == nil || !credentialPattern.MatchString(project.AppID) || !credentialPattern.MatchString(project.AppCertificate) {
+			return nil, fmt.Errorf("project %s is missing a valid appId or appCertificate", name)
+		}
+		for _, certificate := range project.PreviousCertificates {
+			if !credentialPattern.MatchString(certificate) {
+				return nil, fmt.Errorf("project %s has an invalid previous certificate", name)
+			}
+		}
+		projects[name] = &Project{
+			Name:                 name,
+			AppID:                project.AppID,
+			AppCertificate:       project.AppCertificate,
+			PreviousCertificates: project.PreviousCertificates,
+		}
+	}
+	return projects, nil
+}
+
+// lookupProject returns the credentials of the named project.
+// An empty name selects the default project configured with APP_ID and APP_CERTIFICATE.
+func (s *Service) lookupProject(name string) (*Project, error) {
+	s.credentialsMu.RLock()
+	defer s.credentialsMu.RUnlock()
+	if name == "" {
+		if s.appID == "" {
+			return nil, errMissingProject
+		}
+		return s.defaultProject(), nil
+	}
+
+	project, exists := s.projects[name]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", errUnknownProject, name)
+	}
+	return project, nil
+}
+
+// projectForAppID returns the registered project, default project included, with the given App ID.
+func (s *Service) projectForAppID(appID string) (*Project, bool) {
+	s.credentialsMu.RLock()
+	defer s.credentialsMu.RUnlock()
+	if s.appID != "" && s.appID == appID {
+		return s.defaultProject(), true
+	}
+	for _, project := range s.projects {
+		if project.AppID == appID {
+			return project, true
+		}
+	}
+	return nil, false
+}
+
+// defaultProject returns the project configured with APP_ID and APP_CERTIFICATE.
+// The caller must hold credentialsMu.
+func (s *Service) defaultProject() *Project {
+	return &Project{AppID: s.appID, AppCertificate: s.appCertificate, PreviousCertificates: s.previousCertificates}
+}
+
+// routeProject returns the project selected through the /projects/:project route prefix, if any.
+func routeProject(ctx context.Context) string {
+	project, _ := ctx.Value(projectContextKey{}).(string)
+	return project
+}
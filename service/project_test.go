@@ -0,0 +1,126 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	stagingAppID = "0123456789abcdef0123456789abcdef"
+	stagingCert  = "fedcba9876543210fedcba9876543210"
+	acmeAppID    = "11112222333344445555666677778888"
+	acmeCert     = "88887777666655554444333322221111"
+)
+
+// createMultiProjectService creates a service with "staging" registered through the environment
+// and "acme" registered through the projects file.
+func createMultiProjectService(t *testing.T) *Service {
+	projectsFile := filepath.Join(t.TempDir(), "projects.json")
+	content := []byte(`{"acme": {"appId": "` + acmeAppID + `", "appCertificate": "` + acmeCert + `"}}`)
+	if err := os.WriteFile(projectsFile, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PROJECTS_FILE", projectsFile)
+	t.Setenv("PROJECT_STAGING_APP_ID", stagingAppID)
+	t.Setenv("PROJECT_STAGING_APP_CERTIFICATE", stagingCert)
+	return NewService()
+}
+
+func TestLoadProjects(t *testing.T) {
+	createMultiProjectService(t)
+
+	config, err := LoadConfig("")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	projects, err := loadProjects(config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assert.Equal(t, map[string]*Project{
+		"staging": {Name: "staging", AppID: stagingAppID, AppCertificate: stagingCert},
+		"acme":    {Name: "acme", AppID: acmeAppID, AppCertificate: acmeCert},
+	}, projects)
+
+	// Projects without a certificate are rejected
+	t.Setenv("PROJECT_BROKEN_APP_ID", stagingAppID)
+	_, err = LoadConfig("")
+	assert.Error(t, err)
+}
+
+func TestGenTokenForProject(t *testing.T) {
+	service := createMultiProjectService(t)
+
+	for project, appID := range map[string]string{"": service.appID, "staging": stagingAppID, "acme": acmeAppID} {
+		token, err := service.GenRtcToken(TokenRequest{TokenType: "rtc", Channel: "my_channel", Uid: "123", Project: project})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		info, err := service.InspectToken(token)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		assert.Equal(t, appID, info.AppID)
+		assert.Equal(t, project, info.Project)
+		assert.True(t, info.SignatureValid)
+	}
+
+	_, err := service.GenRtmToken(TokenRequest{TokenType: "rtm", Uid: "user123", Project: "unknown"})
+	assert.ErrorIs(t, err, errUnknownProject)
+	_, err = service.GenChatToken(TokenRequest{TokenType: "chat", Project: "unknown"})
+	assert.ErrorIs(t, err, errUnknownProject)
+}
+
+func TestProjectEndpoints(t *testing.T) {
+	service := createMultiProjectService(t)
+
+	tests := []UrlCodePair{
+		{"/projects/staging/rtc/fsda/publisher/uid/0/", http.StatusOK, nil},
+		{"/projects/acme/rtm/username/", http.StatusOK, nil},
+		{"/projects/acme/chat/app/", http.StatusOK, nil},
+		{"/projects/staging/rte/channelName/publisher/uid/2345/", http.StatusOK, nil},
+		{"/projects/unknown/rtc/fsda/publisher/uid/0/", http.StatusNotFound, nil},
+		{"/projects/staging/getToken", http.StatusOK, []byte(`{"tokenType": "rtc", "channel": "channel123", "uid": "123"}`)},
+		{"/projects/staging/getToken", http.StatusOK, []byte(`{"tokenType": "rtm", "uid": "user123", "project": "staging"}`)},
+		{"/projects/staging/getToken", http.StatusBadRequest, []byte(`{"tokenType": "rtm", "uid": "user123", "project": "acme"}`)},
+		{"/projects/unknown/getToken", http.StatusNotFound, []byte(`{"tokenType": "rtm", "uid": "user123"}`)},
+		{"/getToken", http.StatusOK, []byte(`{"tokenType": "chat", "project": "acme"}`)},
+		{"/getToken", http.StatusNotFound, []byte(`{"tokenType": "chat", "project": "unknown"}`)},
+	}
+	for _, httpTest := range tests {
+		method := http.MethodGet
+		if httpTest.body != nil {
+			method = http.MethodPost
+		}
+		testApi, err := http.NewRequest(method, httpTest.url, bytes.NewBuffer(httpTest.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := httptest.NewRecorder()
+		service.Server.Handler.ServeHTTP(resp, testApi)
+		assert.Equal(t, httpTest.code, resp.Code, httpTest.url, resp.Body)
+	}
+
+	// Tokens requested through the route prefix are signed for the project
+	testApi, _ := http.NewRequest(http.MethodPost, "/projects/acme/getToken", bytes.NewBufferString(`{"tokenType": "rtm", "uid": "user123"}`))
+	resp := httptest.NewRecorder()
+	service.Server.Handler.ServeHTTP(resp, testApi)
+	var response struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	info, err := service.InspectToken(response.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, acmeAppID, info.AppID)
+}
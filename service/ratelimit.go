@@ -0,0 +1,355 @@
+package service
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// RateLimit is a token bucket holding up to Burst tokens, refilled at Rate tokens per second.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// ParseRateLimit parses a rate limit in the "<count>/<period>" format, where the period is "s", "m" or "h",
+// such as "100/m". The bucket holds up to count tokens, refilled over the period.
+func ParseRateLimit(value string) (RateLimit, error) {
+	countStr, period, found := strings.Cut(value, "/")
+	count, err := strconv.Atoi(strings.TrimSpace(countStr))
+	if !found || err != nil || count <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q, expected <count>/<s|m|h>", value)
+	}
+	periods := map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour}
+	duration, ok := periods[strings.TrimSpace(period)]
+	if !ok {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q, expected <count>/<s|m|h>", value)
+	}
+	return RateLimit{Rate: float64(count) / duration.Seconds(), Burst: count}, nil
+}
+
+// RateLimitStore keeps the token buckets of the rate limits.
+// Implementations must be safe for concurrent use, and are shared by all the limits of a service.
+type RateLimitStore interface {
+	// Take removes a token from the bucket of the key. When the bucket is empty, it returns false and
+	// the time until a token is available.
+	Take(ctx context.Context, key string, limit RateLimit) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// RateLimitError is returned when a token request exceeds one of the rate limits.
+type RateLimitError struct {
+	Scope      string        // The exceeded limit: "client", "uid" or "channel"
+	RetryAfter time.Duration // The time until the request may be retried
+}
+
+// Error implements the error interface.
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limit exceeded for %s, retry in %s", e.Scope, e.retryAfterSeconds())
+}
+
+// retryAfterSeconds returns the Retry-After header value, rounded up to the next second.
+func (e *RateLimitError) retryAfterSeconds() string {
+	return strconv.Itoa(int(math.Ceil(e.RetryAfter.Seconds())))
+}
+
+// rateLimits holds the limits of the service. A nil limit disables the corresponding scope.
+type rateLimits struct {
+	store       RateLimitStore
+	redisURL    string
+	redisClient *redis.Client // The client of the Redis store, closed when the store is replaced
+	client      *RateLimit
+	uid         *RateLimit
+	channel     *RateLimit
+}
+
+// loadRateLimits builds the client, uid and channel limits of the configuration.
+// The buckets are kept in memory, or in the Redis server of the redisUrl setting when set.
+// The store of the previous limits is kept when its backend is unchanged, so a reload does not reset the buckets.
+// It returns nil when no limit is configured.
+func loadRateLimits(config RateLimitConfig, previous *rateLimits) (*rateLimits, error) {
+	limits := &rateLimits{redisURL: config.RedisURL}
+	for _, limit := range []struct {
+		value string
+		field **RateLimit
+	}{
+		{config.Client, &limits.client},
+		{config.Uid, &limits.uid},
+		{config.Channel, &limits.channel},
+	} {
+		if limit.value == "" {
+			continue
+		}
+		parsed, err := ParseRateLimit(limit.value)
+		if err != nil {
+			return nil, err
+		}
+		*limit.field = &parsed
+	}
+	if limits.client == nil && limits.uid == nil && limits.channel == nil {
+		return nil, nil
+	}
+
+	switch {
+	case previous != nil && previous.redisURL == config.RedisURL:
+		limits.store, limits.redisClient = previous.store, previous.redisClient
+	case config.RedisURL != "":
+		options, err := redis.ParseURL(config.RedisURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redisUrl: %w", err)
+		}
+		limits.redisClient = redis.NewClient(options)
+		limits.store = NewRedisRateLimitStore(limits.redisClient)
+	default:
+		limits.store = NewMemoryRateLimitStore()
+	}
+	return limits, nil
+}
+
+// close closes the Redis client of the limits, unless the next limits still use it. It is a no-op on nil limits.
+func (l *rateLimits) close(next *rateLimits) error {
+	if l == nil || l.redisClient == nil || (next != nil && next.redisClient == l.redisClient) {
+		return nil
+	}
+	return l.redisClient.Close()
+}
+
+// takeRateLimit checks the limit of the key, if the limit is enabled. Store failures are logged and let the request through.
+func (s *Service) takeRateLimit(ctx context.Context, scope, key string, limit *RateLimit) *RateLimitError {
+	if limit == nil {
+		return nil
+	}
+	allowed, retryAfter, err := s.rateLimits.store.Take(ctx, scope+":"+key, *limit)
+	if err != nil {
+		s.log(ctx).Warn("rate limit store unavailable", "scope", scope, "error", err)
+		return nil
+	}
+	if !allowed {
+		return &RateLimitError{Scope: scope, RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// rateLimit checks the uid and channel limits of the token request.
+func (s *Service) rateLimit(ctx context.Context, tokenRequest TokenRequest) *RateLimitError {
+	if s.rateLimits == nil {
+		return nil
+	}
+	project := tokenRequest.Project
+	if project == "" {
+		project = routeProject(ctx)
+	}
+	if tokenRequest.Uid != "" {
+		if err := s.takeRateLimit(ctx, "uid", project+":"+tokenRequest.Uid, s.rateLimits.uid); err != nil {
+			return err
+		}
+	}
+	if tokenRequest.Channel != "" {
+		if err := s.takeRateLimit(ctx, "channel", project+":"+tokenRequest.Channel, s.rateLimits.channel); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rateLimitMiddleware enforces the client limit on every request. Clients are identified by the authenticated
+// principal, or by their IP address when authentication is disabled.
+func (s *Service) rateLimitMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.rateLimits == nil {
+			c.Next()
+			return
+		}
+		client := "ip:" + c.ClientIP()
+		if principal, ok := PrincipalFromContext(c.Request.Context()); ok {
+			client = principal.Method + ":" + principal.Subject
+		}
+		if limited := s.takeRateLimit(c.Request.Context(), "client", client, s.rateLimits.client); limited != nil {
+			s.recordToken(c.Request.Context(), TokenRequest{}, routeFromPath(c.FullPath()), limited)
+			abortWithError(c, limited)
+			return
+		}
+		c.Next()
+	}
+}
+
+// routeFromPath returns the metrics route label of a token endpoint.
+func routeFromPath(path string) string {
+	switch {
+	case strings.HasSuffix(path, "/getToken"):
+		return routePost
+	case strings.HasSuffix(path, "/getTokens"):
+		return routeBatch
+	case path == "/inspectToken":
+		return routeInspect
+	default:
+		return routeLegacy
+	}
+}
+
+// defaultMemoryRateLimitBuckets is the number of buckets kept by a MemoryRateLimitStore.
+const defaultMemoryRateLimitBuckets = 100000
+
+// MemoryRateLimitStore keeps the token buckets in memory. Limits are not shared between instances of the service.
+// The buckets are ordered by the time they will have refilled completely, so the number of buckets is bounded
+// whatever the number of keys, such as uids or channels rotated by a caller.
+type MemoryRateLimitStore struct {
+	mu         sync.Mutex
+	buckets    map[string]*memoryBucket
+	byFullAt   memoryBuckets // The buckets, in a heap ordered by fullAt
+	maxBuckets int
+	now        func() time.Time
+}
+
+// memoryBucket is the state of a token bucket.
+type memoryBucket struct {
+	key     string
+	tokens  float64
+	updated time.Time
+	limit   RateLimit
+	fullAt  time.Time // When the bucket will have refilled completely
+	index   int       // The index of the bucket in the heap
+}
+
+// NewMemoryRateLimitStore returns an empty in-memory store.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets:    make(map[string]*memoryBucket),
+		maxBuckets: defaultMemoryRateLimitBuckets,
+		now:        time.Now,
+	}
+}
+
+// Take implements RateLimitStore.
+func (m *MemoryRateLimitStore) Take(_ context.Context, key string, limit RateLimit) (bool, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	bucket, ok := m.buckets[key]
+	if !ok {
+		m.evict(now)
+		bucket = &memoryBucket{key: key, tokens: float64(limit.Burst), updated: now, limit: limit}
+		m.buckets[key] = bucket
+		heap.Push(&m.byFullAt, bucket)
+	}
+	bucket.refill(now)
+	if bucket.limit != limit {
+		// The limit changed on a reload: the tokens accumulated so far are kept, up to the new burst
+		bucket.limit = limit
+		bucket.tokens = math.Min(bucket.tokens, float64(limit.Burst))
+	}
+	defer func() {
+		bucket.fullAt = now.Add(time.Duration((float64(bucket.limit.Burst) - bucket.tokens) / bucket.limit.Rate * float64(time.Second)))
+		heap.Fix(&m.byFullAt, bucket.index)
+	}()
+
+	if bucket.tokens < 1 {
+		return false, time.Duration((1 - bucket.tokens) / limit.Rate * float64(time.Second)), nil
+	}
+	bucket.tokens--
+	return true, 0, nil
+}
+
+// evict makes room for a new bucket. The buckets that have refilled completely are removed, as they are
+// equivalent to missing ones. When the store is still full, the bucket closest to refilling is removed,
+// so the store never grows beyond maxBuckets.
+func (m *MemoryRateLimitStore) evict(now time.Time) {
+	for len(m.byFullAt) > 0 && (!m.byFullAt[0].fullAt.After(now) || len(m.byFullAt) >= m.maxBuckets) {
+		bucket := heap.Pop(&m.byFullAt).(*memoryBucket)
+		delete(m.buckets, bucket.key)
+	}
+}
+
+// refill adds the tokens accumulated since the last update.
+func (b *memoryBucket) refill(now time.Time) {
+	b.tokens = math.Min(float64(b.limit.Burst), b.tokens+now.Sub(b.updated).Seconds()*b.limit.Rate)
+	b.updated = now
+}
+
+// memoryBuckets implements heap.Interface, ordering the buckets by the time they will have refilled.
+type memoryBuckets []*memoryBucket
+
+func (h memoryBuckets) Len() int           { return len(h) }
+func (h memoryBuckets) Less(i, j int) bool { return h[i].fullAt.Before(h[j].fullAt) }
+
+func (h memoryBuckets) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+
+func (h *memoryBuckets) Push(x any) {
+	bucket := x.(*memoryBucket)
+	bucket.index = len(*h)
+	*h = append(*h, bucket)
+}
+
+func (h *memoryBuckets) Pop() any {
+	old := *h
+	bucket := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return bucket
+}
+
+// redisTakeScript atomically refills and takes a token from the bucket stored in a hash.
+// It returns 1 and 0 when allowed, or 0 and the milliseconds until a token is available.
+var redisTakeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "updated")
+local tokens = tonumber(bucket[1]) or burst
+local updated = tonumber(bucket[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - updated) / 1000 * rate)
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  wait = math.ceil((1 - tokens) / rate * 1000)
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "updated", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst / rate * 1000))
+return {allowed, wait}
+`)
+
+// RedisRateLimitStore keeps the token buckets in a Redis compatible server, sharing the limits between
+// all the instances of the service.
+type RedisRateLimitStore struct {
+	client redis.Scripter
+	prefix string
+}
+
+// NewRedisRateLimitStore returns a store keeping the buckets under the "agora-token-service:ratelimit:" prefix.
+func NewRedisRateLimitStore(client redis.Scripter) *RedisRateLimitStore {
+	return &RedisRateLimitStore{client: client, prefix: "agora-token-service:ratelimit:"}
+}
+
+// Ping implements RateLimitStorePinger, when the client can ping the server.
+func (r *RedisRateLimitStore) Ping(ctx context.Context) error {
+	if pinger, ok := r.client.(interface {
+		Ping(ctx context.Context) *redis.StatusCmd
+	}); ok {
+		return pinger.Ping(ctx).Err()
+	}
+	return nil
+}
+
+// Take implements RateLimitStore.
+func (r *RedisRateLimitStore) Take(ctx context.Context, key string, limit RateLimit) (bool, time.Duration, error) {
+	result, err := redisTakeScript.Run(ctx, r.client, []string{r.prefix + key},
+		limit.Rate, limit.Burst, time.Now().UnixMilli()).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	return result[0] == 1, time.Duration(result[1]) * time.Millisecond, nil
+}
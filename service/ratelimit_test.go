@@ -0,0 +1,235 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	limit, err := ParseRateLimit("120/m")
+	if assert.NoError(t, err) {
+		assert.Equal(t, RateLimit{Rate: 2, Burst: 120}, limit)
+	}
+	for _, invalid := range []string{"", "10", "0/s", "-1/s", "ten/s", "10/d"} {
+		_, err := ParseRateLimit(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryRateLimitStore()
+	store.now = func() time.Time { return now }
+	limit := RateLimit{Rate: 0.5, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		allowed, _, _ := store.Take(context.Background(), "uid:1", limit)
+		assert.True(t, allowed, i)
+	}
+	allowed, retryAfter, _ := store.Take(context.Background(), "uid:1", limit)
+	assert.False(t, allowed)
+	assert.Equal(t, 2*time.Second, retryAfter)
+
+	// Buckets are independent
+	allowed, _, _ = store.Take(context.Background(), "uid:2", limit)
+	assert.True(t, allowed)
+
+	// The bucket refills over time
+	now = now.Add(2 * time.Second)
+	allowed, _, _ = store.Take(context.Background(), "uid:1", limit)
+	assert.True(t, allowed)
+
+	// A changed limit applies to the existing buckets
+	allowed, retryAfter, _ = store.Take(context.Background(), "uid:1", RateLimit{Rate: 1.0 / 60, Burst: 1})
+	assert.False(t, allowed)
+	assert.Equal(t, 60*time.Second, retryAfter)
+	allowed, _, _ = store.Take(context.Background(), "uid:2", RateLimit{Rate: 1.0 / 60, Burst: 1})
+	assert.True(t, allowed)
+}
+
+func TestMemoryRateLimitStoreEviction(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryRateLimitStore()
+	store.now = func() time.Time { return now }
+	store.maxBuckets = 3
+	limit := RateLimit{Rate: 1.0 / 60, Burst: 1}
+
+	allowed, _, _ := store.Take(context.Background(), "uid:limited", limit)
+	assert.True(t, allowed)
+	// Buckets that have refilled are evicted first, keeping the limited ones
+	for i := 0; i < 2; i++ {
+		now = now.Add(time.Second)
+		store.Take(context.Background(), "uid:other", RateLimit{Rate: 1, Burst: 1})
+		now = now.Add(time.Second)
+		store.Take(context.Background(), fmt.Sprintf("uid:new%d", i), RateLimit{Rate: 1, Burst: 1})
+	}
+	allowed, _, _ = store.Take(context.Background(), "uid:limited", limit)
+	assert.False(t, allowed)
+
+	// The store never grows beyond its size, whatever the number of keys
+	for i := 0; i < 100; i++ {
+		store.Take(context.Background(), fmt.Sprintf("uid:rotated%d", i), limit)
+	}
+	assert.Len(t, store.byFullAt, 3)
+	assert.Len(t, store.buckets, 3)
+}
+
+func TestRedisRateLimitStore(t *testing.T) {
+	server := miniredis.RunT(t)
+	store := NewRedisRateLimitStore(redis.NewClient(&redis.Options{Addr: server.Addr()}))
+	limit := RateLimit{Rate: 1.0 / 60, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		allowed, _, err := store.Take(context.Background(), "channel:room", limit)
+		assert.NoError(t, err)
+		assert.True(t, allowed, i)
+	}
+	allowed, retryAfter, err := store.Take(context.Background(), "channel:room", limit)
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+	assert.InDelta(t, time.Minute.Seconds(), retryAfter.Seconds(), 1)
+	assert.True(t, server.Exists("agora-token-service:ratelimit:channel:room"))
+
+	server.Close()
+	_, _, err = store.Take(context.Background(), "channel:room", limit)
+	assert.Error(t, err)
+}
+
+func TestRateLimitEndpoints(t *testing.T) {
+	server := miniredis.RunT(t)
+	t.Setenv("RATE_LIMIT_UID", "2/m")
+	t.Setenv("RATE_LIMIT_CHANNEL", "3/m")
+	t.Setenv("RATE_LIMIT_REDIS_URL", "redis://"+server.Addr())
+	service := NewService()
+
+	tests := []struct {
+		method string
+		url    string
+		body   []byte
+		code   int
+	}{
+		{http.MethodPost, "/getToken", []byte(`{"tokenType": "rtc", "channel": "room", "uid": "1"}`), http.StatusOK},
+		{http.MethodGet, "/rtc/room/publisher/uid/1/", nil, http.StatusOK},
+		{http.MethodPost, "/getToken", []byte(`{"tokenType": "rtm", "uid": "1"}`), http.StatusTooManyRequests},
+		{http.MethodGet, "/rtc/room/publisher/uid/2/", nil, http.StatusOK},
+		{http.MethodGet, "/rtc/room/publisher/uid/3/", nil, http.StatusTooManyRequests},
+		{http.MethodGet, "/projects/default/rtc/room/publisher/uid/1/", nil, http.StatusNotFound},
+		{http.MethodPost, "/getToken", []byte(`{"tokenType": "rtm", "uid": "4"}`), http.StatusOK},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest(test.method, test.url, bytes.NewBuffer(test.body))
+		resp := httptest.NewRecorder()
+		service.Server.Handler.ServeHTTP(resp, req)
+		assert.Equal(t, test.code, resp.Code, test.url, resp.Body)
+		if test.code == http.StatusTooManyRequests {
+			assert.NotEmpty(t, resp.Header().Get("Retry-After"))
+		}
+	}
+
+	// Batch items are limited individually
+	req, _ := http.NewRequest(http.MethodPost, "/getTokens", bytes.NewBufferString(`[
+		{"tokenType": "rtm", "uid": "5"},
+		{"tokenType": "rtm", "uid": "1"}
+	]`))
+	resp := httptest.NewRecorder()
+	service.Server.Handler.ServeHTTP(resp, req)
+	var results []TokenResult
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, http.StatusOK, results[0].Status)
+		assert.Equal(t, http.StatusTooManyRequests, results[1].Status)
+		assert.Greater(t, results[1].RetryAfter, 0)
+	}
+}
+
+func TestRateLimitForwardedFor(t *testing.T) {
+	t.Setenv("RATE_LIMIT_CLIENT", "1/h")
+	request := func(service *Service, forwardedFor string) int {
+		req, _ := http.NewRequest(http.MethodGet, "/rtm/username/", nil)
+		req.RemoteAddr = "203.0.113.1:4321"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		resp := httptest.NewRecorder()
+		service.Server.Handler.ServeHTTP(resp, req)
+		return resp.Code
+	}
+
+	// X-Forwarded-For can not be used to get a new bucket
+	service := NewService()
+	assert.Equal(t, http.StatusOK, request(service, "198.51.100.1"))
+	assert.Equal(t, http.StatusTooManyRequests, request(service, "198.51.100.2"))
+
+	// Unless it is set by a trusted proxy
+	t.Setenv("TRUSTED_PROXIES", "203.0.113.0/24")
+	service = NewService()
+	assert.Equal(t, http.StatusOK, request(service, "198.51.100.1"))
+	assert.Equal(t, http.StatusOK, request(service, "198.51.100.2"))
+	assert.Equal(t, http.StatusTooManyRequests, request(service, "198.51.100.2"))
+}
+
+func TestRateLimitReload(t *testing.T) {
+	first, second := miniredis.RunT(t), miniredis.RunT(t)
+	configFile := writeConfig(t, "config.yaml", "rateLimits:\n  uid: 1/m\n  redisUrl: redis://"+first.Addr())
+	t.Setenv("CONFIG_FILE", configFile)
+	service := NewService()
+	previous := service.rateLimits.redisClient
+
+	// The store is kept while the Redis server is unchanged
+	assert.NoError(t, service.Reload())
+	assert.Same(t, previous, service.rateLimits.redisClient)
+	assert.NoError(t, previous.Ping(context.Background()).Err())
+
+	// The previous client is closed when the Redis server changes
+	if err := os.WriteFile(configFile, []byte("rateLimits:\n  uid: 1/m\n  redisUrl: redis://"+second.Addr()), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, service.Reload())
+	assert.ErrorIs(t, previous.Ping(context.Background()).Err(), redis.ErrClosed)
+	previous = service.rateLimits.redisClient
+
+	// Or when the limits are disabled
+	if err := os.WriteFile(configFile, []byte("corsAllowOrigin: '*'"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, service.Reload())
+	assert.Nil(t, service.rateLimits)
+	assert.ErrorIs(t, previous.Ping(context.Background()).Err(), redis.ErrClosed)
+}
+
+func TestRateLimitClients(t *testing.T) {
+	t.Setenv("AUTH_API_KEYS", "frontend:frontend-key,backend:backend-key")
+	t.Setenv("RATE_LIMIT_CLIENT", "1/h")
+	service := NewService()
+
+	tests := []struct {
+		apiKey string
+		code   int
+	}{
+		{"frontend-key", http.StatusOK},
+		{"frontend-key", http.StatusTooManyRequests},
+		{"backend-key", http.StatusOK},
+		{"invalid-key", http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest(http.MethodGet, "/rtm/username/", nil)
+		req.Header.Set("X-API-Key", test.apiKey)
+		resp := httptest.NewRecorder()
+		service.Server.Handler.ServeHTTP(resp, req)
+		assert.Equal(t, test.code, resp.Code, test.apiKey)
+		if test.code == http.StatusTooManyRequests {
+			assert.Equal(t, "3600", resp.Header().Get("Retry-After"))
+		}
+	}
+}
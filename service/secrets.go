@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// SecretProvider loads a secret, such as an app certificate, from outside of the configuration.
+// Implementations are queried again on every refresh, and must be safe for concurrent use.
+type SecretProvider interface {
+	// GetSecret returns the current value of the secret.
+	GetSecret(ctx context.Context) (string, error)
+}
+
+// FileSecretProvider reads a secret from a file, such as a Docker or Kubernetes secret mounted in the container.
+// Leading and trailing whitespace is ignored.
+type FileSecretProvider struct {
+	Path string
+}
+
+// GetSecret implements SecretProvider.
+func (f *FileSecretProvider) GetSecret(_ context.Context) (string, error) {
+	content, err := os.ReadFile(f.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %s", err)
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// VaultSecretProvider reads a secret from a HashiCorp Vault compatible key/value secrets engine, version 1 or 2,
+// over its HTTP API.
+type VaultSecretProvider struct {
+	Address string // The address of the server, such as https://vault.example.com:8200
+	Token   string // The token sent in the X-Vault-Token header
+	Path    string // The path of the secret, such as secret/data/agora for a KV version 2 engine mounted at secret/
+	Field   string // The key of the secret within the secret data
+
+	// Client sends the requests. http.DefaultClient is used when nil.
+	Client *http.Client
+}
+
+// GetSecret implements SecretProvider.
+func (v *VaultSecretProvider) GetSecret(ctx context.Context) (string, error) {
+	url := strings.TrimSuffix(v.Address, "/") + "/v1/" + strings.TrimPrefix(v.Path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", v.Token)
+	client := v.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to read vault secret %s: %s", v.Path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to read vault secret %s: status %d", v.Path, resp.StatusCode)
+	}
+
+	var secret struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return "", fmt.Errorf("failed to parse vault secret %s: %s", v.Path, err)
+	}
+	data := secret.Data
+	// KV version 2 nests the secret data along with its metadata
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		data = nested
+	}
+	value, ok := data[v.Field].(string)
+	if !ok || value == "" {
+		return "", fmt.Errorf("vault secret %s has no %q field", v.Path, v.Field)
+	}
+	return value, nil
+}
+
+// secretProviders returns the providers of the app certificates loaded from outside of the configuration,
+// indexed by project name, empty for the default project.
+func (c *Config) secretProviders() (map[string]SecretProvider, error) {
+	providers := make(map[string]SecretProvider)
+	var errs []error
+
+	if c.AppCertificateFile != "" && c.Vault.Path != "" {
+		errs = append(errs, errors.New("appCertificateFile: can not be combined with vault.path"))
+	}
+	switch {
+	case c.AppCertificateFile != "":
+		providers[""] = &FileSecretProvider{Path: c.AppCertificateFile}
+	case c.Vault.Path != "":
+		if c.Vault.Address == "" {
+			errs = append(errs, errors.New("vault.address: is required to read vault.path"))
+		}
+		token := c.Vault.Token
+		if c.Vault.TokenFile != "" {
+			fileToken, err := (&FileSecretProvider{Path: c.Vault.TokenFile}).GetSecret(context.Background())
+			if err != nil {
+				errs = append(errs, fmt.Errorf("vault.tokenFile: %s", err))
+			}
+			token = fileToken
+		}
+		field := c.Vault.Field
+		if field == "" {
+			field = "appCertificate"
+		}
+		providers[""] = &VaultSecretProvider{
+			Address: c.Vault.Address,
+			Token:   token,
+			Path:    c.Vault.Path,
+			Field:   field,
+			Client:  &http.Client{Timeout: 10 * time.Second},
+		}
+	}
+
+	for _, name := range sortedKeys(c.Projects) {
+		if project := c.Projects[name]; project != nil && project.AppCertificateFile != "" {
+			providers[name] = &FileSecretProvider{Path: project.AppCertificateFile}
+		}
+	}
+	return providers, errors.Join(errs...)
+}
+
+// resolveSecrets loads the app certificates of the secret providers into the configuration.
+func (c *Config) resolveSecrets(ctx context.Context) error {
+	providers, err := c.secretProviders()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, name := range sortedKeys(providers) {
+		certificate, err := providers[name].GetSecret(ctx)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if name == "" {
+			c.AppCertificate = certificate
+		} else {
+			c.Projects[name].AppCertificate = certificate
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// refreshSecrets loads the app certificates of the secret providers again, and switches to the ones that
+// changed. The certificates they replace are kept as previous certificates, so tokens signed with them
+// can still be verified. The secrets are fetched without holding mu, so a slow secret store delays neither
+// the requests nor the reloads.
+func (s *Service) refreshSecrets(ctx context.Context) {
+	s.mu.RLock()
+	providers := s.secretProviders
+	logger := s.log(ctx)
+	s.mu.RUnlock()
+
+	certificates := make(map[string]string, len(providers))
+	for _, name := range sortedKeys(providers) {
+		certificate, err := providers[name].GetSecret(ctx)
+		if err != nil {
+			logger.Error("secret refresh failed", "project", name, "error", err)
+			continue
+		}
+		certificates[name] = certificate
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, name := range sortedKeys(certificates) {
+		if s.secretProviders[name] != providers[name] {
+			// A reload replaced the provider while the secret was fetched
+			continue
+		}
+		certificate := certificates[name]
+		project, err := s.lookupProject(name)
+		if err != nil || project.AppCertificate == certificate {
+			continue
+		}
+		if _, err := s.switchCertificate(name, certificate, "secret refresh"); err != nil {
+			s.log(ctx).Error("secret refresh failed", "project", name, "error", err)
+		}
+	}
+}
+
+// refreshSecretsPeriodically refreshes the secrets at the configured interval, until the context is done.
+func (s *Service) refreshSecretsPeriodically(ctx context.Context) {
+	for {
+		s.mu.RLock()
+		interval := s.secretsRefreshInterval
+		s.mu.RUnlock()
+		enabled := interval > 0
+		if !enabled {
+			// Refresh is disabled, check again later in case a reload enables it
+			interval = time.Minute
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+		if enabled {
+			s.refreshSecrets(ctx)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// vaultStub serves the secret data at /v1/<path> to the requests with the token, nesting it in KV version 2 style when kv2 is set.
+func vaultStub(t *testing.T, token, path string, kv2 bool, data map[string]interface{}) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != token {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Path != "/v1/"+path {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if kv2 {
+			data = map[string]interface{}{"data": data, "metadata": map[string]interface{}{"version": 1}}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFileSecretProvider(t *testing.T) {
+	secretFile := writeConfig(t, "app_certificate", stagingCert+"\n")
+
+	secret, err := (&FileSecretProvider{Path: secretFile}).GetSecret(context.Background())
+	if assert.NoError(t, err) {
+		assert.Equal(t, stagingCert, secret)
+	}
+
+	_, err = (&FileSecretProvider{Path: filepath.Join(t.TempDir(), "missing")}).GetSecret(context.Background())
+	assert.Error(t, err)
+}
+
+func TestVaultSecretProvider(t *testing.T) {
+	for _, kv2 := range []bool{true, false} {
+		server := vaultStub(t, "vault-token", "secret/data/agora", kv2, map[string]interface{}{"appCertificate": stagingCert})
+
+		provider := &VaultSecretProvider{Address: server.URL + "/", Token: "vault-token", Path: "secret/data/agora", Field: "appCertificate"}
+		secret, err := provider.GetSecret(context.Background())
+		if assert.NoError(t, err, "kv2: %v", kv2) {
+			assert.Equal(t, stagingCert, secret)
+		}
+
+		for _, invalid := range []*VaultSecretProvider{
+			{Address: server.URL, Token: "wrong-token", Path: "secret/data/agora", Field: "appCertificate"},
+			{Address: server.URL, Token: "vault-token", Path: "secret/data/missing", Field: "appCertificate"},
+			{Address: server.URL, Token: "vault-token", Path: "secret/data/agora", Field: "certificate"},
+		} {
+			_, err := invalid.GetSecret(context.Background())
+			assert.Error(t, err, "kv2: %v, provider: %+v", kv2, invalid)
+		}
+	}
+}
+
+func TestLoadConfigSecrets(t *testing.T) {
+	t.Setenv("APP_CERTIFICATE", "")
+	t.Setenv("APP_CERTIFICATE_FILE", writeConfig(t, "app_certificate", stagingCert))
+	t.Setenv("PROJECT_ACME_APP_ID", acmeAppID)
+	t.Setenv("PROJECT_ACME_APP_CERTIFICATE_FILE", writeConfig(t, "acme_certificate", acmeCert))
+
+	config, err := LoadConfig("")
+	if assert.NoError(t, err) {
+		assert.Equal(t, stagingCert, config.AppCertificate)
+		assert.Equal(t, acmeCert, config.Projects["acme"].AppCertificate)
+	}
+
+	// A certificate can not be read both from a file and from Vault
+	t.Setenv("VAULT_ADDR", "http://127.0.0.1:8200")
+	t.Setenv("VAULT_SECRET_PATH", "secret/data/agora")
+	_, err = LoadConfig("")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "appCertificateFile:")
+	}
+
+	t.Setenv("APP_CERTIFICATE_FILE", filepath.Join(t.TempDir(), "missing"))
+	t.Setenv("VAULT_SECRET_PATH", "")
+	_, err = LoadConfig("")
+	assert.Error(t, err)
+}
+
+func TestRefreshSecrets(t *testing.T) {
+	originalCert := os.Getenv("APP_CERTIFICATE")
+	server := vaultStub(t, "vault-token", "secret/data/agora", true, map[string]interface{}{"appCertificate": originalCert})
+	t.Setenv("APP_CERTIFICATE", "")
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_TOKEN_FILE", writeConfig(t, "vault_token", "vault-token\n"))
+	t.Setenv("VAULT_SECRET_PATH", "secret/data/agora")
+	t.Setenv("SECRETS_REFRESH_INTERVAL", "1m")
+	secretFile := writeConfig(t, "acme_certificate", acmeCert)
+	t.Setenv("PROJECT_ACME_APP_ID", acmeAppID)
+	t.Setenv("PROJECT_ACME_APP_CERTIFICATE_FILE", secretFile)
+	service := NewService()
+	assert.Equal(t, originalCert, service.appCertificate)
+
+	before, err := service.GenRtcToken(TokenRequest{TokenType: "rtc", Channel: "room", Uid: "1", Project: "acme"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Unchanged secrets are not switched
+	service.refreshSecrets(context.Background())
+	assert.Empty(t, service.CertificateStatuses()[1].Previous)
+
+	if err := os.WriteFile(secretFile, []byte(rotatedCert), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	service.refreshSecrets(context.Background())
+
+	project, err := service.lookupProject("acme")
+	if assert.NoError(t, err) {
+		assert.Equal(t, rotatedCert, project.AppCertificate)
+		assert.Equal(t, []string{acmeCert}, project.PreviousCertificates)
+	}
+	info, err := service.InspectToken(before)
+	if assert.NoError(t, err) {
+		assert.True(t, info.SignatureValid)
+		assert.Equal(t, "previous", info.SignedWith)
+	}
+	assert.Equal(t, originalCert, service.appCertificate)
+}
+
+// blockingSecretProvider returns its secret once released, like a slow secret store.
+type blockingSecretProvider struct {
+	called   chan struct{}
+	released chan struct{}
+	secret   string
+}
+
+func (b *blockingSecretProvider) GetSecret(ctx context.Context) (string, error) {
+	close(b.called)
+	<-b.released
+	return b.secret, nil
+}
+
+func TestRefreshSecretsDoesNotBlockReloads(t *testing.T) {
+	s, err := New(Options{AppID: testAppID, AppCertificate: testAppCertificate})
+	if !assert.NoError(t, err) {
+		return
+	}
+	provider := &blockingSecretProvider{called: make(chan struct{}), released: make(chan struct{}), secret: rotatedCert}
+	s.mu.Lock()
+	s.secretProviders = map[string]SecretProvider{"": provider}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.refreshSecrets(context.Background())
+		close(done)
+	}()
+	<-provider.called
+	// The settings can be replaced while the secret is fetched
+	if assert.True(t, s.mu.TryLock()) {
+		s.mu.Unlock()
+	}
+	close(provider.released)
+	<-done
+
+	project, err := s.lookupProject("")
+	if assert.NoError(t, err) {
+		assert.Equal(t, rotatedCert, project.AppCertificate)
+	}
+}
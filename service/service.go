@@ -2,15 +2,22 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"google.golang.org/grpc"
 )
 
 // Service represents the main application service.
@@ -18,7 +25,17 @@ type Service struct {
 	// Server is the HTTP server for the application.
 	Server *http.Server
 
-	// Sigint is a channel to handle OS signals, such as Ctrl+C.
+	// GRPCServer serves the gRPC TokenService on its own port. gRPC is disabled when nil.
+	GRPCServer *grpc.Server
+
+	// grpcAddr is the address the GRPCServer listens to.
+	grpcAddr string
+
+	// trustedProxies are the proxies whose X-Forwarded-For header identifies the client.
+	trustedProxies []string
+
+	// Sigint is a channel to handle OS signals, such as Ctrl+C. Run and Stop shut the service down on
+	// SIGINT and SIGTERM.
 	Sigint chan os.Signal
 
 	// appID is the identifier for the application.
@@ -29,83 +46,424 @@ type Service struct {
 
 	// allowOrigin specifies the allowed origin for Cross-Origin Resource Sharing (CORS).
 	allowOrigin string
-}
 
-// Stop service safely, closing additional connections if needed.
-func (s *Service) Stop() {
-	// Will continue once an interrupt has occurred
-	signal.Notify(s.Sigint, os.Interrupt)
-	<-s.Sigint
-
-	// cancel would be useful if we had to close third party connection first
-	// Like connections to a db or cache
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-	cancel()
-	err := s.Server.Shutdown(ctx)
-	if err != nil {
-		log.Println(err)
-	}
-}
+	// projects holds additional Agora projects, selected by name through the "project" request field
+	// or the /projects/:project route prefix.
+	projects map[string]*Project
 
-// Start runs the service by listening to the specified port
-func (s *Service) Start() {
-	log.Println("Listening to port " + s.Server.Addr)
-	if err := s.Server.ListenAndServe(); err != nil {
-		panic(err)
-	}
+	// authenticators verify the callers of the token endpoints. Authentication is disabled when empty.
+	authenticators []Authenticator
+
+	// policy restricts the tokens each principal may request. Every request is allowed when nil.
+	policy *Policy
+
+	// rateLimits restricts the rate of token requests per client, uid and channel. Requests are not limited when nil.
+	rateLimits *rateLimits
+
+	// batchMaxSize is the maximum number of token requests accepted by POST /getTokens.
+	batchMaxSize int
+
+	// minExpire and maxExpire bound the requested token and privilege expirations, in seconds.
+	minExpire int
+	maxExpire int
+
+	// openAPI is the specification served at /openapi.json, which requests are validated against when
+	// openAPIValidation is set. openAPIDocs enables Swagger UI at /docs.
+	openAPI           *openapi3.T
+	openAPIValidation bool
+	openAPIDocs       bool
+
+	// batchWorkers is the number of tokens of a batch generated concurrently.
+	batchWorkers int
+
+	// metrics collects the Prometheus metrics exposed at /metrics. Nothing is recorded when nil.
+	metrics *metrics
+
+	// auditSinks record each issued token. Tokens are not audited when empty. ownedAuditSinks are the ones
+	// opened from the configuration, closed when replaced on reload and on shutdown.
+	auditSinks      []AuditSink
+	ownedAuditSinks []AuditSink
+
+	// logger writes the structured logs of the service. The default slog logger is used when nil.
+	logger *slog.Logger
+
+	// logRedaction controls how uids and channels appear in the logs: "none", "hash" or "redact".
+	logRedaction string
+	// logRedactKey is the HMAC key of the "hash" redaction.
+	logRedactKey []byte
+
+	// previousCertificates are the certificates the default project was rotated away from.
+	previousCertificates []string
+
+	// certificatesRotatedAt records when the active certificate of each project, indexed by name, was last switched.
+	certificatesRotatedAt map[string]time.Time
+
+	// credentialsMu guards the credentials of the projects, which can be rotated while requests are served.
+	credentialsMu sync.RWMutex
+
+	// secretProviders load the certificates of the projects, indexed by name, that are kept outside of the configuration.
+	secretProviders map[string]SecretProvider
+
+	// secretsRefreshInterval is how often the certificates of the secret providers are refreshed. Disabled when zero.
+	secretsRefreshInterval time.Duration
+
+	// adminSubjects are the principals allowed to call the admin endpoints.
+	adminSubjects []string
+
+	// tls serves the TLS certificate and client CAs, reloaded when their files change. Plain HTTP is served when nil.
+	tls *certificateReloader
+
+	// configFile is the configuration file loaded on start and reload, if any.
+	configFile string
+
+	// loadConfig returns the configuration applied on reload. The configuration file and environment are
+	// loaded again when nil.
+	loadConfig func() (*Config, error)
+
+	// shutdownTimeout bounds how long Run and Stop wait for in-flight requests and shutdown hooks.
+	shutdownTimeout time.Duration
+
+	// shutdownDelay is how long the service keeps serving once it reports not ready, so load balancers stop
+	// routing new requests to it before the listeners close.
+	shutdownDelay time.Duration
+
+	// ready reports whether the service accepts traffic: from the time Start listens until Shutdown begins.
+	ready atomic.Bool
+
+	// draining is set once Shutdown begins, failing the readiness checks of GET /readyz.
+	draining atomic.Bool
+
+	// background is done once the service shuts down, stopping the configuration reloads and secret refreshes.
+	background     context.Context
+	stopBackground context.CancelFunc
+
+	// shutdownHooks run on shutdown, in reverse registration order, once the servers are drained.
+	shutdownHooks []ShutdownHook
+	hooksMu       sync.Mutex
+
+	// shutdownOnce runs the shutdown a single time. shutdownDone is closed once it completes, with shutdownErr.
+	shutdownOnce sync.Once
+	shutdownDone chan struct{}
+	shutdownErr  error
+
+	// mu guards the settings replaced on reload. Requests hold the read lock while they are served.
+	mu sync.RWMutex
 }
 
 // NewService returns a Service pointer with all configurations set
 func NewService() *Service {
 
-	err := godotenv.Load()
+	envErr := godotenv.Load()
+	// fatal logs the configuration error and exits
+	fatal := func(msg string, err error) {
+		slog.New(slog.NewJSONHandler(os.Stdout, nil)).Error(msg, "error", err)
+		os.Exit(1)
+	}
+	configFile := os.Getenv("CONFIG_FILE")
+	config, err := LoadConfig(configFile)
 	if err != nil {
-		log.Println("Error loading .env file")
-	}
-	appIDEnv, appIDExists := os.LookupEnv("APP_ID")
-	appCertEnv, appCertExists := os.LookupEnv("APP_CERTIFICATE")
-	serverPort, serverPortExists := os.LookupEnv("SERVER_PORT")
-	corsAllowOrigin, _ := os.LookupEnv("CORS_ALLOW_ORIGIN")
-
-	if !appIDExists || !appCertExists || len(appIDEnv) == 0 || len(appCertEnv) == 0 {
-		log.Fatal("FATAL ERROR: ENV not properly configured, check .env file or APP_ID and APP_CERTIFICATE")
-	}
-	if !serverPortExists || len(serverPort) == 0 {
-		// Check $PORT, this is used by Railway.
-		port, portExists := os.LookupEnv("PORT")
-		if portExists && len(port) > 0 {
-			serverPort = port
-		} else {
-			serverPort = "8080"
-		}
+		fatal("configuration not valid", err)
+	}
+	s, err := NewServiceFromConfig(config)
+	if err != nil {
+		fatal("configuration not valid", err)
 	}
+	s.configFile = configFile
+	if envErr != nil {
+		s.logger.Info("no .env file loaded", "error", envErr)
+	}
+	return s
+}
 
+// NewServiceFromConfig returns a Service with the settings of the configuration, such as one returned by
+// LoadConfig. Unlike NewService, it neither loads the .env file nor exits on errors.
+//
+// Parameters:
+//   - config: *Config - The configuration of the service.
+//
+// Returns:
+//   - *Service: The service, ready to Start or to generate tokens with GenRtcToken, GenRtmToken and GenChatToken.
+//   - error: An error if the projects, authentication, policy, TLS or other settings can not be loaded.
+//
+// Notes:
+//   - SIGHUP reloads the environment only, as the service has no configuration file.
+//
+// Example usage:
+//
+//	config, err := service.LoadConfig("config.yaml")
+//	if err != nil {
+//	    return err
+//	}
+//	s, err := service.NewServiceFromConfig(config)
+func NewServiceFromConfig(config *Config) (*Service, error) {
 	s := &Service{
 		Sigint: make(chan os.Signal, 1),
 		Server: &http.Server{
-			Addr: fmt.Sprintf(":%s", serverPort),
+			Addr: fmt.Sprintf(":%s", config.ServerPort),
 		},
-		appID:          appIDEnv,
-		appCertificate: appCertEnv,
-		allowOrigin:    corsAllowOrigin,
+		metrics:      newMetrics(),
+		shutdownDone: make(chan struct{}),
+	}
+	s.background, s.stopBackground = context.WithCancel(context.Background())
+	if err := s.applyConfig(config); err != nil {
+		return nil, err
+	}
+	if config.TLS.CertFile != "" {
+		var err error
+		if s.tls, err = newCertificateReloader(config.TLS); err != nil {
+			return nil, fmt.Errorf("TLS not properly configured: %w", err)
+		}
+		s.tls.onReload = s.tlsReloaded
+		s.Server.TLSConfig = s.tls.tlsConfig()
+	}
+	if config.GRPCPort != "" {
+		s.grpcAddr = fmt.Sprintf(":%s", config.GRPCPort)
+		s.GRPCServer = s.newGRPCServer()
 	}
 
-	api := gin.Default()
+	api := gin.New()
+	// Without trusted proxies, X-Forwarded-For is ignored, so callers can not pick their IP address
+	s.trustedProxies = config.TrustedProxies
+	if err := api.SetTrustedProxies(s.trustedProxies); err != nil {
+		return nil, fmt.Errorf("trusted proxies not properly configured: %w", err)
+	}
 
+	api.Use(s.settingsMiddleware(), s.loggingMiddleware(), s.recoveryMiddleware())
+	api.Use(s.metrics.middleware())
 	api.Use(s.nocache())
+	// Probes and metrics scrapes send no Origin header, so they are registered before the CORS checks apply
+	api.GET("/healthz", s.getHealthz)
+	api.GET("/readyz", s.getReadyz)
+	api.GET("/version", s.getVersion)
+	api.GET("/metrics", s.metrics.handler())
 	api.Use(s.CORSMiddleware())
-	api.GET("rtc/:channelName/:role/:tokenType/:rtcuid/", s.getRtcToken)
-	api.GET("rtm/:rtmuid/", s.getRtmToken)
-	api.GET("rte/:channelName/:role/:tokenType/:rtcuid/", s.getRtcRtmToken)
-	api.GET("rte/:channelName/:role/:tokenType/:rtcuid/:rtmuid/", s.getRtcRtmToken)
-	api.GET("chat/app/", s.getChatToken)             // Chat token for API calls
-	api.GET("chat/account/:chatid/", s.getChatToken) // Chat token for SDK calls
+	s.registerTokenRoutes(api.Group("", s.AuthMiddleware(), s.openAPIValidationMiddleware(), s.rateLimitMiddleware()))
+	s.registerTokenRoutes(api.Group("projects/:project", s.AuthMiddleware(), s.projectMiddleware(),
+		s.openAPIValidationMiddleware(), s.rateLimitMiddleware()))
 	api.GET("/ping", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"message": "pong",
 		})
 	})
-	api.POST("/getToken", s.getToken)
+	api.POST("/inspectToken", s.AuthMiddleware(), s.openAPIValidationMiddleware(), s.rateLimitMiddleware(), s.inspectToken)
+	s.registerAdminRoutes(api.Group("admin", s.AuthMiddleware(), s.adminMiddleware(), s.openAPIValidationMiddleware()))
+	api.GET("/openapi.json", s.getOpenAPI)
+	api.GET("/docs", s.getDocs)
+	api.NoRoute(func(c *gin.Context) {
+		abortWithError(c, &APIError{Status: http.StatusNotFound, Code: CodeNotFound, Message: "not found: " + c.Request.URL.Path})
+	})
 	s.Server.Handler = api
-	return s
+	return s, nil
+}
+
+// applyConfig builds the projects, authenticators, policy, rate limits and logger of the configuration,
+// and replaces the current settings once they are all valid. The listener is left untouched.
+func (s *Service) applyConfig(config *Config) error {
+	projects, err := loadProjects(config)
+	if err != nil {
+		return fmt.Errorf("projects not properly configured: %w", err)
+	}
+	if config.AppID == "" && len(projects) == 0 {
+		return errors.New("ENV not properly configured, check .env file or APP_ID and APP_CERTIFICATE")
+	}
+	authenticators, err := loadAuthenticators(config.Auth)
+	if err != nil {
+		return fmt.Errorf("authentication not properly configured: %w", err)
+	}
+	if config.TLS.ClientCAFile != "" {
+		// Client certificates are verified during the TLS handshake, so they take precedence
+		authenticators = append([]Authenticator{NewClientCertAuthenticator()}, authenticators...)
+	}
+	var policy *Policy
+	if config.PolicyFile != "" {
+		if policy, err = LoadPolicy(config.PolicyFile); err != nil {
+			return fmt.Errorf("policy not properly configured: %w", err)
+		}
+	}
+	logger, err := newLogger(os.Stdout, config.Log)
+	if err != nil {
+		return fmt.Errorf("logging not properly configured: %w", err)
+	}
+	if config.logger != nil {
+		logger = config.logger
+	}
+	secretProviders, err := config.secretProviders()
+	if err != nil {
+		return fmt.Errorf("secrets not properly configured: %w", err)
+	}
+	var secretsRefreshInterval time.Duration
+	if config.SecretsRefreshInterval != "" {
+		if secretsRefreshInterval, err = time.ParseDuration(config.SecretsRefreshInterval); err != nil {
+			return fmt.Errorf("secrets not properly configured: %w", err)
+		}
+	}
+	shutdownTimeout, shutdownDelay, err := config.Shutdown.durations()
+	if err != nil {
+		return fmt.Errorf("shutdown not properly configured: %w", err)
+	}
+	openAPI, err := newOpenAPISpec(config)
+	if err != nil {
+		return fmt.Errorf("OpenAPI specification not properly built: %w", err)
+	}
+	// Opened last, as they must be closed if the configuration is rejected
+	ownedAuditSinks, err := loadAuditSinks(config.Audit)
+	if err != nil {
+		return fmt.Errorf("audit not properly configured: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rateLimits, err := loadRateLimits(config.RateLimits, s.rateLimits)
+	if err != nil {
+		closeAuditSinks(ownedAuditSinks)
+		return fmt.Errorf("rate limits not properly configured: %w", err)
+	}
+	// No request holds the previous sinks anymore
+	if err := closeAuditSinks(s.ownedAuditSinks); err != nil {
+		logger.Warn("previous audit sinks not properly closed", "error", err)
+	}
+	// Nor the previous Redis client
+	if err := s.rateLimits.close(rateLimits); err != nil {
+		logger.Warn("previous rate limit store not properly closed", "error", err)
+	}
+	s.ownedAuditSinks = ownedAuditSinks
+	s.auditSinks = append(append([]AuditSink(nil), ownedAuditSinks...), config.auditSinks...)
+	s.logger = logger
+	s.applyCredentials(config, projects)
+	s.allowOrigin = config.CORSAllowOrigin
+	s.authenticators = authenticators
+	s.policy = policy
+	s.rateLimits = rateLimits
+	s.batchMaxSize = config.Batch.MaxSize
+	s.minExpire, s.maxExpire = config.Expire.Min, config.Expire.Max
+	s.openAPI = openAPI
+	s.openAPIValidation = config.OpenAPI.ValidateRequests
+	s.openAPIDocs = config.OpenAPI.Docs
+	s.batchWorkers = config.Batch.Workers
+	s.adminSubjects = config.Admin.Subjects
+	s.secretProviders = secretProviders
+	s.secretsRefreshInterval = secretsRefreshInterval
+	s.shutdownTimeout = shutdownTimeout
+	s.shutdownDelay = shutdownDelay
+	s.logRedaction = logRedaction(config.Log)
+	s.logRedactKey = []byte(config.Log.RedactKey)
+	return nil
+}
+
+// applyCredentials replaces the credentials of the projects, and records the projects whose active certificate
+// changed. The caller must hold mu for writing.
+func (s *Service) applyCredentials(config *Config, projects map[string]*Project) {
+	s.credentialsMu.Lock()
+	defer s.credentialsMu.Unlock()
+
+	previousDefault := s.appCertificate
+	previousProjects := s.projects
+	s.appID = config.AppID
+	s.appCertificate = config.AppCertificate
+	s.previousCertificates = config.AppPreviousCertificates
+	s.projects = projects
+
+	if previousDefault != "" && config.AppCertificate != "" && previousDefault != config.AppCertificate {
+		s.certificateRotated("", s.defaultProject(), "reload")
+	}
+	for name, project := range projects {
+		if previous, exists := previousProjects[name]; exists && previous.AppCertificate != project.AppCertificate {
+			s.certificateRotated(name, project, "reload")
+		}
+	}
+}
+
+// Reload loads the configuration file and environment variables again, and applies every setting but the
+// listening port. In-flight requests complete with the previous settings. The current settings are kept
+// if the new configuration is not valid. Services built with New apply their options again instead.
+func (s *Service) Reload() error {
+	loadConfig := s.loadConfig
+	if loadConfig == nil {
+		loadConfig = func() (*Config, error) { return LoadConfig(s.configFile) }
+	}
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if err := s.applyConfig(config); err != nil {
+		return err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if addr := fmt.Sprintf(":%s", config.ServerPort); addr != s.Server.Addr {
+		s.log(context.Background()).Warn("server port changes require a restart", "addr", s.Server.Addr, "configured", addr)
+	}
+	var grpcAddr string
+	if config.GRPCPort != "" {
+		grpcAddr = fmt.Sprintf(":%s", config.GRPCPort)
+	}
+	if grpcAddr != s.grpcAddr {
+		s.log(context.Background()).Warn("gRPC port changes require a restart", "addr", s.grpcAddr, "configured", grpcAddr)
+	}
+	if !slices.Equal(config.TrustedProxies, s.trustedProxies) {
+		s.log(context.Background()).Warn("trusted proxy changes require a restart")
+	}
+	var tlsConfig TLSConfig
+	if s.tls != nil {
+		tlsConfig = s.tls.config
+	}
+	if config.TLS != tlsConfig {
+		s.log(context.Background()).Warn("TLS setting changes require a restart")
+	}
+	s.log(context.Background()).Info("configuration reloaded", "config_file", s.configFile)
+	return nil
+}
+
+// tlsReloaded logs the outcome of reading the changed TLS files.
+func (s *Service) tlsReloaded(err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if err != nil {
+		s.log(context.Background()).Error("TLS certificate reload failed, serving the previous certificate", "error", err)
+		return
+	}
+	s.log(context.Background()).Info("TLS certificate reloaded", "cert_file", s.tls.config.CertFile)
+}
+
+// reloadOnSignal reloads the configuration on every SIGHUP, until the context is done.
+func (s *Service) reloadOnSignal(ctx context.Context) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+		}
+		if err := s.Reload(); err != nil {
+			s.mu.RLock()
+			s.log(context.Background()).Error("configuration reload failed", "error", err)
+			s.mu.RUnlock()
+		}
+	}
+}
+
+// settingsMiddleware holds the settings for the duration of the request, so a reload never
+// changes them halfway through.
+func (s *Service) settingsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		c.Next()
+	}
+}
+
+// registerTokenRoutes adds the token generation endpoints to the router.
+// They are registered both at the root, for the default project, and under the /projects/:project prefix,
+// behind the AuthMiddleware.
+func (s *Service) registerTokenRoutes(router gin.IRoutes) {
+	router.GET("rtc/:channelName/:role/:tokenType/:rtcuid/", s.getRtcToken)
+	router.GET("rtm/:rtmuid/", s.getRtmToken)
+	router.GET("rte/:channelName/:role/:tokenType/:rtcuid/", s.getRtcRtmToken)
+	router.GET("rte/:channelName/:role/:tokenType/:rtcuid/:rtmuid/", s.getRtcRtmToken)
+	router.GET("chat/app/", s.getChatToken)             // Chat token for API calls
+	router.GET("chat/account/:chatid/", s.getChatToken) // Chat token for SDK calls
+	router.POST("/getToken", s.getToken)
+	router.POST("/getTokens", s.getTokens)
 }
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// clientAuthRequire rejects TLS connections without a client certificate signed by the client CA.
+	clientAuthRequire = "require"
+
+	// clientAuthOptional verifies the client certificates that are presented, letting the other
+	// authentication methods handle the connections without one.
+	clientAuthOptional = "optional"
+)
+
+// certificateReloader serves the TLS certificate and client CAs read from files, and reads them again
+// whenever the files change, so renewed certificates are picked up without a restart.
+type certificateReloader struct {
+	config TLSConfig
+
+	// onReload is called after every attempt to read the changed files, with the error if it failed.
+	onReload func(err error)
+
+	mu          sync.Mutex
+	fileStates  map[string]fileState
+	certificate *tls.Certificate
+	clientCAs   *x509.CertPool
+}
+
+// fileState identifies a version of a file.
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// newCertificateReloader reads the certificate, key and client CA files of the configuration.
+func newCertificateReloader(config TLSConfig) (*certificateReloader, error) {
+	reloader := &certificateReloader{config: config, onReload: func(error) {}}
+	reloader.fileStates = reloader.currentFileStates()
+	if err := reloader.load(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// files returns the files the TLS settings are read from.
+func (r *certificateReloader) files() []string {
+	files := []string{r.config.CertFile, r.config.KeyFile}
+	if r.config.ClientCAFile != "" {
+		files = append(files, r.config.ClientCAFile)
+	}
+	return files
+}
+
+// currentFileStates returns the state of every file. Missing files have a zero state.
+func (r *certificateReloader) currentFileStates() map[string]fileState {
+	states := make(map[string]fileState)
+	for _, file := range r.files() {
+		if info, err := os.Stat(file); err == nil {
+			states[file] = fileState{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return states
+}
+
+// load reads the certificate, key and client CA files. The caller must hold mu, unless the reloader is not shared yet.
+func (r *certificateReloader) load() error {
+	certificate, err := tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %s", err)
+	}
+	var clientCAs *x509.CertPool
+	if r.config.ClientCAFile != "" {
+		content, err := os.ReadFile(r.config.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read TLS client CA file: %s", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(content) {
+			return fmt.Errorf("TLS client CA file %s holds no PEM certificate", r.config.ClientCAFile)
+		}
+	}
+	r.certificate, r.clientCAs = &certificate, clientCAs
+	return nil
+}
+
+// reloadIfChanged reads the files again if any of them changed since they were last read.
+// The current certificate is kept when the files can not be read, such as when they are being replaced.
+func (r *certificateReloader) reloadIfChanged() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	states := r.currentFileStates()
+	changed := len(states) != len(r.fileStates)
+	for file, state := range states {
+		if previous, exists := r.fileStates[file]; !exists || previous != state {
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+	// A failed read is not retried until the files change again
+	r.fileStates = states
+	r.onReload(r.load())
+}
+
+// tlsConfig returns the TLS configuration of the server, which checks the files for changes on every handshake.
+func (r *certificateReloader) tlsConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			return r.certificate, nil
+		},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.reloadIfChanged()
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			config := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*r.certificate},
+				NextProtos:   []string{"h2", "http/1.1"},
+			}
+			if r.clientCAs != nil {
+				config.ClientCAs = r.clientCAs
+				config.ClientAuth = tls.RequireAndVerifyClientCert
+				if r.config.ClientAuth == clientAuthOptional {
+					config.ClientAuth = tls.VerifyClientCertIfGiven
+				}
+			}
+			return config, nil
+		},
+	}
+}
+
+// ClientCertAuthenticator authenticates requests made over mutual TLS with their verified client certificate.
+// The principal subject is the certificate common name, and its claims hold the other subject attributes.
+type ClientCertAuthenticator struct{}
+
+// NewClientCertAuthenticator returns a ClientCertAuthenticator.
+func NewClientCertAuthenticator() *ClientCertAuthenticator {
+	return &ClientCertAuthenticator{}
+}
+
+// Authenticate implements Authenticator.
+func (a *ClientCertAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, errNoCredentials
+	}
+	certificate := r.TLS.VerifiedChains[0][0]
+	if certificate.Subject.CommonName == "" {
+		return nil, errors.New("client certificate has no common name")
+	}
+	claims := map[string]interface{}{
+		"name": certificate.Subject.CommonName,
+		"dn":   certificate.Subject.String(),
+	}
+	for claim, values := range map[string][]string{
+		"organization":       certificate.Subject.Organization,
+		"organizationalUnit": certificate.Subject.OrganizationalUnit,
+		"dnsNames":           certificate.DNSNames,
+		"emailAddresses":     certificate.EmailAddresses,
+	} {
+		if len(values) == 0 {
+			continue
+		}
+		list := make([]interface{}, len(values))
+		for i, value := range values {
+			list[i] = value
+		}
+		claims[claim] = list
+	}
+	return &Principal{Subject: certificate.Subject.CommonName, Method: "mtls", Claims: claims}, nil
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testCertificate is a certificate and its key, PEM encoded.
+type testCertificate struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	certPEM     []byte
+	keyPEM      []byte
+}
+
+// newTestCertificate issues a certificate from the template, signed by the parent, or self-signed when the parent is nil.
+func newTestCertificate(t *testing.T, template *x509.Certificate, parent *testCertificate) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.certificate, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, _ := x509.ParseCertificate(der)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCertificate{
+		certificate: certificate,
+		key:         key,
+		certPEM:     pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:      pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// newTestPKI returns a CA, a server certificate for 127.0.0.1 and a client certificate for the "backend" subject.
+func newTestPKI(t *testing.T) (ca, server, client *testCertificate) {
+	ca = newTestCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+	server = newTestCertificate(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "token-service"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca)
+	client = newTestCertificate(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "backend", Organization: []string{"Acme"}},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca)
+	return ca, server, client
+}
+
+// writeFile writes the content to the path, moving its modification time forward so the change is always detected.
+func writeFile(t *testing.T, path string, content []byte, modTime time.Time) {
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// startTLSServer serves the handler of the service with its TLS configuration.
+func startTLSServer(t *testing.T, service *Service) *httptest.Server {
+	server := httptest.NewUnstartedServer(service.Server.Handler)
+	server.TLS = service.Server.TLSConfig
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// tlsClient returns a client trusting the CA and presenting the client certificate, if any.
+func tlsClient(ca, client *testCertificate) *http.Client {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.certificate)
+	config := &tls.Config{RootCAs: roots}
+	if client != nil {
+		keyPair, _ := tls.X509KeyPair(client.certPEM, client.keyPEM)
+		config.Certificates = []tls.Certificate{keyPair}
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca, serverCert, clientCert := newTestPKI(t)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "ca.pem"), ca.certPEM, time.Now())
+	writeFile(t, filepath.Join(dir, "server.pem"), serverCert.certPEM, time.Now())
+	writeFile(t, filepath.Join(dir, "server.key"), serverCert.keyPEM, time.Now())
+	policyFile := filepath.Join(dir, "policy.yaml")
+	writeFile(t, policyFile, []byte(`
+rules:
+  - name: backends
+    when: {methods: [mtls]}
+    require: {claims: {organization: Acme}, channelPrefix: "{name}-"}
+`), time.Now())
+
+	t.Setenv("TLS_CERT_FILE", filepath.Join(dir, "server.pem"))
+	t.Setenv("TLS_KEY_FILE", filepath.Join(dir, "server.key"))
+	t.Setenv("TLS_CLIENT_CA_FILE", filepath.Join(dir, "ca.pem"))
+	t.Setenv("AUTH_API_KEYS", "ops:ops-key")
+	t.Setenv("POLICY_FILE", policyFile)
+
+	for _, clientAuth := range []string{clientAuthRequire, clientAuthOptional} {
+		t.Setenv("TLS_CLIENT_AUTH", clientAuth)
+		server := startTLSServer(t, NewService())
+
+		tests := []struct {
+			client  *testCertificate
+			apiKey  string
+			channel string
+			code    int
+		}{
+			{clientCert, "", "backend-room", http.StatusOK},
+			{clientCert, "", "room", http.StatusForbidden},
+			{clientCert, "ops-key", "backend-room", http.StatusOK},
+			{nil, "ops-key", "room", http.StatusOK},
+			{nil, "", "room", http.StatusUnauthorized},
+		}
+		for _, test := range tests {
+			body := []byte(`{"tokenType": "rtc", "channel": "` + test.channel + `", "uid": "1"}`)
+			req, _ := http.NewRequest(http.MethodPost, server.URL+"/getToken", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			if test.apiKey != "" {
+				req.Header.Set("X-API-Key", test.apiKey)
+			}
+			resp, err := tlsClient(ca, test.client).Do(req)
+			if clientAuth == clientAuthRequire && test.client == nil {
+				// The handshake fails without a client certificate
+				assert.Error(t, err, "%+v", test)
+				continue
+			}
+			if assert.NoError(t, err, "%+v", test) {
+				resp.Body.Close()
+				assert.Equal(t, test.code, resp.StatusCode, "clientAuth: %s, %+v", clientAuth, test)
+			}
+		}
+	}
+}
+
+func TestClientCertAuthenticator(t *testing.T) {
+	_, _, clientCert := newTestPKI(t)
+	authenticator := NewClientCertAuthenticator()
+
+	_, err := authenticator.Authenticate(httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.ErrorIs(t, err, errNoCredentials)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{clientCert.certificate}}}
+	principal, err := authenticator.Authenticate(req)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "backend", principal.Subject)
+		assert.Equal(t, "mtls", principal.Method)
+		assert.Equal(t, "CN=backend,O=Acme", principal.Claims["dn"])
+		assert.True(t, principal.hasClaim("organization", "Acme"))
+	}
+}
+
+func TestCertificateReloader(t *testing.T) {
+	ca, serverCert, _ := newTestPKI(t)
+	dir := t.TempDir()
+	config := TLSConfig{CertFile: filepath.Join(dir, "server.pem"), KeyFile: filepath.Join(dir, "server.key")}
+	writeFile(t, config.CertFile, serverCert.certPEM, time.Now())
+	writeFile(t, config.KeyFile, serverCert.keyPEM, time.Now())
+
+	reloader, err := newCertificateReloader(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reloadErrs []error
+	reloader.onReload = func(err error) { reloadErrs = append(reloadErrs, err) }
+	servedCertificate := func() *x509.Certificate {
+		tlsConfig, err := reloader.tlsConfig().GetConfigForClient(&tls.ClientHelloInfo{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		served, err := x509.ParseCertificate(tlsConfig.Certificates[0].Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return served
+	}
+	assert.Equal(t, serverCert.certificate.SerialNumber, servedCertificate().SerialNumber)
+	assert.Empty(t, reloadErrs)
+
+	// A renewed certificate is served once both files are replaced
+	renewed := newTestCertificate(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "token-service"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+	}, ca)
+	writeFile(t, config.CertFile, renewed.certPEM, time.Now().Add(time.Minute))
+	assert.Equal(t, serverCert.certificate.SerialNumber, servedCertificate().SerialNumber)
+	writeFile(t, config.KeyFile, renewed.keyPEM, time.Now().Add(time.Minute))
+	assert.Equal(t, renewed.certificate.SerialNumber, servedCertificate().SerialNumber)
+	if assert.Len(t, reloadErrs, 2) {
+		assert.Error(t, reloadErrs[0])
+		assert.NoError(t, reloadErrs[1])
+	}
+
+	// Unchanged files are not read again
+	servedCertificate()
+	assert.Len(t, reloadErrs, 2)
+
+	_, err = newCertificateReloader(TLSConfig{CertFile: config.CertFile, KeyFile: config.CertFile})
+	assert.Error(t, err)
+}
@@ -1,10 +1,9 @@
 package service
 
 import (
-	"fmt"
-	"log"
 	"strconv"
 
+	"github.com/AgoraIO-Community/go-tokenbuilder/accesstoken"
 	"github.com/AgoraIO-Community/go-tokenbuilder/chatTokenBuilder"
 	rtctokenbuilder2 "github.com/AgoraIO-Community/go-tokenbuilder/rtctokenbuilder"
 )
@@ -12,6 +11,7 @@ import (
 // generateRtcToken generates an RTC token for the video conferencing application based on the provided parameters.
 //
 // Parameters:
+//   - project: *Project - The Agora project whose credentials sign the token.
 //   - channelName: string - The name of the video conferencing channel.
 //   - uidStr: string - The user ID for the RTC token, represented as a string.
 //   - tokenType: string - The type of RTC token. Can be "userAccount" or "uid".
@@ -35,45 +35,74 @@ import (
 //
 // Example usage:
 //
-//	rtcToken, err := generateRtcToken("channel123", "user123", "userAccount", rtctokenbuilder2.RolePublisher, 3600)
-func (s *Service) generateRtcToken(channelName, uidStr, tokenType string, role rtctokenbuilder2.Role, expireDelta uint32) (rtcToken string, err error) {
+//	rtcToken, err := generateRtcToken(project, "channel123", "user123", "userAccount", rtctokenbuilder2.RolePublisher, 3600)
+func (s *Service) generateRtcToken(project *Project, channelName, uidStr, tokenType string, role rtctokenbuilder2.Role, expireDelta uint32) (rtcToken string, err error) {
 
 	if tokenType == "userAccount" {
-		log.Printf("Building Token for userAccount: %s\n", uidStr)
-		rtcToken, err = rtctokenbuilder2.BuildTokenWithAccount(s.appID, s.appCertificate, channelName, uidStr, role, expireDelta)
+		rtcToken, err = rtctokenbuilder2.BuildTokenWithAccount(project.AppID, project.AppCertificate, channelName, uidStr, role, expireDelta)
 		return rtcToken, err
 	} else if tokenType == "uid" {
-		uid64, parseErr := strconv.ParseUint(uidStr, 10, 64)
+		uid64, parseErr := strconv.ParseUint(uidStr, 10, 32)
 		// check if conversion fails
 		if parseErr != nil {
-			err = fmt.Errorf("failed to parse uidStr: %s, to uint causing error: %s", uidStr, parseErr)
+			err = badRequest(CodeInvalidUid, "rtcuid", "failed to parse uidStr: %s, to uint causing error: %s", uidStr, parseErr)
 			return "", err
 		}
 
-		uid := uint32(uid64) // convert uid from uint64 to uint 32
-		log.Printf("Building Token for uid: %d\n", uid)
-		rtcToken, err = rtctokenbuilder2.BuildTokenWithUid(s.appID, s.appCertificate, channelName, uid, role, expireDelta)
+		uid := uint32(uid64) // parsed with a bit size of 32, so the conversion is lossless
+		rtcToken, err = rtctokenbuilder2.BuildTokenWithUid(project.AppID, project.AppCertificate, channelName, uid, role, expireDelta)
 		return rtcToken, err
 	} else {
-		err = fmt.Errorf("failed to generate RTC token for Unknown Tokentype: %s", tokenType)
-		log.Println(err)
+		err = badRequest(CodeUnsupportedTokenType, "tokenType", "failed to generate RTC token for Unknown Tokentype: %s", tokenType)
 		return "", err
 	}
 }
 
-func (s *Service) generateChatToken(uidStr string, tokenType string, expireTimestamp uint32) (chatToken string, err error) {
+func (s *Service) generateChatToken(project *Project, uidStr string, tokenType string, expireTimestamp uint32) (chatToken string, err error) {
 
 	if tokenType == "userAccount" {
-		log.Printf("Building Token with userAccount: %s\n", uidStr)
-		chatToken, err = chatTokenBuilder.BuildChatUserToken(s.appID, s.appCertificate, uidStr, expireTimestamp)
+		chatToken, err = chatTokenBuilder.BuildChatUserToken(project.AppID, project.AppCertificate, uidStr, expireTimestamp)
 		return chatToken, err
 
 	} else if tokenType == "app" {
-		chatToken, err = chatTokenBuilder.BuildChatAppToken(s.appID, s.appCertificate, expireTimestamp)
+		chatToken, err = chatTokenBuilder.BuildChatAppToken(project.AppID, project.AppCertificate, expireTimestamp)
 		return chatToken, err
 	} else {
-		err = fmt.Errorf("failed to generate Chat token for Unknown token type: %s", tokenType)
-		log.Println(err)
+		err = badRequest(CodeUnsupportedTokenType, "tokenType", "failed to generate Chat token for Unknown token type: %s", tokenType)
 		return "", err
 	}
 }
+
+// rtcPrivilegeExpires holds the expiration, in seconds, of each RTC privilege.
+// A zero expiration leaves the privilege out of the token.
+type rtcPrivilegeExpires struct {
+	joinChannel       uint32
+	publishAudio      uint32
+	publishVideo      uint32
+	publishDataStream uint32
+}
+
+// buildRtcTokenWithPrivileges builds an RTC token for the given account where every privilege has its own expiration.
+// It mirrors rtctokenbuilder2.BuildTokenWithAccount, which uses a single expiration for the token and all privileges.
+func buildRtcTokenWithPrivileges(appID, appCertificate, channelName, account string, tokenExpire uint32, privileges rtcPrivilegeExpires) (string, error) {
+	token := accesstoken.NewAccessToken(appID, appCertificate, tokenExpire)
+	token.AddService(newRtcService(channelName, account, privileges))
+
+	return token.Build()
+}
+
+// newRtcService returns the RTC service of a token, granting the privileges with a non-zero expiration.
+func newRtcService(channelName, account string, privileges rtcPrivilegeExpires) *accesstoken.ServiceRtc {
+	serviceRtc := accesstoken.NewServiceRtc(channelName, account)
+	serviceRtc.AddPrivilege(accesstoken.PrivilegeJoinChannel, privileges.joinChannel)
+	if privileges.publishAudio > 0 {
+		serviceRtc.AddPrivilege(accesstoken.PrivilegePublishAudioStream, privileges.publishAudio)
+	}
+	if privileges.publishVideo > 0 {
+		serviceRtc.AddPrivilege(accesstoken.PrivilegePublishVideoStream, privileges.publishVideo)
+	}
+	if privileges.publishDataStream > 0 {
+		serviceRtc.AddPrivilege(accesstoken.PrivilegePublishDataStream, privileges.publishDataStream)
+	}
+	return serviceRtc
+}
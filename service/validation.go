@@ -0,0 +1,151 @@
+package service
+
+import (
+	"regexp"
+	"strconv"
+
+	rtctokenbuilder2 "github.com/AgoraIO-Community/go-tokenbuilder/rtctokenbuilder"
+)
+
+const (
+	// maxChannelLength is the maximum length of a channel name, in bytes.
+	maxChannelLength = 64
+
+	// maxAccountLength is the maximum length of a user account, in bytes.
+	maxAccountLength = 255
+
+	// defaultTokenExpire is the expiration of tokens requested without one, in seconds, unless it is out of the
+	// configured range.
+	defaultTokenExpire = 3600
+
+	// defaultMinExpire and defaultMaxExpire are the default bounds of the requested expirations, in seconds.
+	defaultMinExpire = 60
+	defaultMaxExpire = 86400
+)
+
+var (
+	// channelPattern matches the characters Agora supports in channel names.
+	channelPattern = regexp.MustCompile(`^[a-zA-Z0-9 !#$%&()+\-:;<=.>?@\[\]^_{}|~,]*$`)
+
+	// numericPattern matches the uids that are numeric user IDs rather than user accounts.
+	numericPattern = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// validateTokenRequest checks the fields of a token request that are set against the limits of Agora and the
+// configured expiration range. The fields required by each token type are checked when generating the token.
+//
+// Parameters:
+//   - tokenRequest: TokenRequest - The token request to check.
+//
+// Returns:
+//   - error: An APIError about the first invalid field, or nil if the request is valid.
+//
+// Behavior:
+//  1. Checks that the channel name is at most 64 bytes of the characters supported by Agora.
+//  2. Checks that numeric uids of RTC tokens fit in 32 bits, and that user accounts are at most 255 bytes.
+//  3. Checks that the role is "publisher" or "subscriber", or empty for subscribers.
+//  4. Checks that the token and privilege expirations are within the configured range, 0 standing for the default.
+//
+// Example usage:
+//
+//	if err := generator.validateTokenRequest(tokenReq); err != nil {
+//	    return "", err
+//	}
+func (g *TokenGenerator) validateTokenRequest(tokenRequest TokenRequest) error {
+	if tokenRequest.Channel != "" {
+		if err := validateChannel("channel", tokenRequest.Channel); err != nil {
+			return err
+		}
+	}
+	if tokenRequest.Uid != "" {
+		rtc := tokenRequest.TokenType == "rtc" || contains(tokenRequest.Services, "rtc")
+		if err := validateUid("uid", tokenRequest.Uid, rtc); err != nil {
+			return err
+		}
+	}
+	if _, err := parseRole("role", tokenRequest.RtcRole); err != nil {
+		return err
+	}
+	for _, expire := range []struct {
+		field string
+		value int
+	}{
+		{"expire", tokenRequest.ExpirationSeconds}, {"joinChannelExpire", tokenRequest.JoinChannelExpire},
+		{"pubAudioExpire", tokenRequest.PubAudioExpire}, {"pubVideoExpire", tokenRequest.PubVideoExpire},
+		{"pubDataStreamExpire", tokenRequest.PubDataStreamExpire},
+	} {
+		if err := g.validateExpire(expire.field, expire.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateChannel checks that the channel name is at most 64 bytes of the characters supported by Agora.
+func validateChannel(field, channel string) error {
+	if len(channel) > maxChannelLength {
+		return badRequest(CodeInvalidChannel, field, "invalid: channel name exceeds %d bytes", maxChannelLength)
+	}
+	if !channelPattern.MatchString(channel) {
+		return badRequest(CodeInvalidChannel, field, "invalid: channel name contains unsupported characters")
+	}
+	return nil
+}
+
+// validateUid checks that a user account is at most 255 bytes. With rtc set, numeric uids are RTC user IDs,
+// which must fit in 32 bits.
+func validateUid(field, uid string, rtc bool) error {
+	if rtc && numericPattern.MatchString(uid) {
+		if _, err := strconv.ParseUint(uid, 10, 32); err != nil {
+			return badRequest(CodeInvalidUid, field, "invalid: uid must be between 0 and 4294967295")
+		}
+		return nil
+	}
+	if len(uid) > maxAccountLength {
+		return badRequest(CodeInvalidUid, field, "invalid: user account exceeds %d bytes", maxAccountLength)
+	}
+	return nil
+}
+
+// parseRole returns the RTC role of the role name: "publisher", or "subscriber", which is the default.
+func parseRole(field, role string) (rtctokenbuilder2.Role, error) {
+	switch role {
+	case "publisher":
+		return rtctokenbuilder2.RolePublisher, nil
+	case "subscriber", "":
+		return rtctokenbuilder2.RoleSubscriber, nil
+	default:
+		return 0, badRequest(CodeInvalidRole, field, "invalid: role must be publisher or subscriber, got %q", role)
+	}
+}
+
+// validateExpire checks that the expiration, in seconds, is within the configured range. 0 stands for the default.
+func (g *TokenGenerator) validateExpire(field string, expire int) error {
+	minExpire, maxExpire := g.expireRange()
+	switch {
+	case expire < 0:
+		return badRequest(CodeInvalidExpiry, field, "invalid: expiration can not be negative")
+	case expire == 0:
+		return nil
+	case expire < minExpire:
+		return unprocessable(CodeExpiryTooShort, field, "invalid: expiration must be at least %d seconds", minExpire)
+	case expire > maxExpire:
+		return unprocessable(CodeExpiryTooLong, field, "invalid: expiration must be at most %d seconds", maxExpire)
+	}
+	return nil
+}
+
+// defaultExpire returns the expiration of tokens requested without one: one hour, within the configured range.
+func (g *TokenGenerator) defaultExpire() int {
+	minExpire, maxExpire := g.expireRange()
+	return min(max(defaultTokenExpire, minExpire), maxExpire)
+}
+
+// expireRange returns the configured bounds of the requested expirations, or the default ones for generators
+// and services created without a configuration.
+func (g *TokenGenerator) expireRange() (minExpire, maxExpire int) {
+	if g.maxExpire == 0 {
+		return defaultMinExpire, defaultMaxExpire
+	}
+	return g.minExpire, g.maxExpire
+}
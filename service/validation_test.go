@@ -0,0 +1,94 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTokenRequests(t *testing.T) {
+	tests := []struct {
+		method string
+		url    string
+		body   string
+		status int
+		code   ErrorCode
+		field  string
+	}{
+		{http.MethodPost, "/getToken", `{"tokenType": "rtc", "channel": "room #1 (test)", "uid": "4294967295"}`, http.StatusOK, "", ""},
+		{http.MethodPost, "/getToken", `{"tokenType": "rtc", "channel": "` + strings.Repeat("a", 64) + `", "uid": "1"}`, http.StatusOK, "", ""},
+		{http.MethodPost, "/getToken", `{"tokenType": "rtc", "channel": "` + strings.Repeat("a", 65) + `", "uid": "1"}`, http.StatusBadRequest, CodeInvalidChannel, "channel"},
+		{http.MethodPost, "/getToken", `{"tokenType": "rtc", "channel": "room/1", "uid": "1"}`, http.StatusBadRequest, CodeInvalidChannel, "channel"},
+		{http.MethodPost, "/getToken", `{"tokenType": "rtc", "channel": "salle-à-manger", "uid": "1"}`, http.StatusBadRequest, CodeInvalidChannel, "channel"},
+		{http.MethodPost, "/getToken", `{"tokenType": "rtc", "channel": "room", "uid": "4294967296"}`, http.StatusBadRequest, CodeInvalidUid, "uid"},
+		{http.MethodPost, "/getToken", `{"tokenType": "rtm", "uid": "4294967296"}`, http.StatusOK, "", ""},
+		{http.MethodPost, "/getToken", `{"tokenType": "rtm", "uid": "` + strings.Repeat("u", 256) + `"}`, http.StatusBadRequest, CodeInvalidUid, "uid"},
+		{http.MethodPost, "/getToken", `{"tokenType": "rtc", "channel": "room", "uid": "1", "role": "host"}`, http.StatusBadRequest, CodeInvalidRole, "role"},
+		{http.MethodPost, "/getToken", `{"services": ["rtc", "rtm"], "channel": "room", "uid": "1", "role": "Publisher"}`, http.StatusBadRequest, CodeInvalidRole, "role"},
+		{http.MethodPost, "/getToken", `{"tokenType": "rtm", "uid": "1", "expire": 30}`, http.StatusUnprocessableEntity, CodeExpiryTooShort, "expire"},
+		{http.MethodPost, "/getToken", `{"tokenType": "chat", "expire": 86401}`, http.StatusUnprocessableEntity, CodeExpiryTooLong, "expire"},
+		{http.MethodPost, "/getToken", `{"tokenType": "rtc", "channel": "room", "uid": "1", "role": "publisher", "pubAudioExpire": 10}`, http.StatusUnprocessableEntity, CodeExpiryTooShort, "pubAudioExpire"},
+		{http.MethodGet, "/rtc/room/publisher/uid/4294967295/", "", http.StatusOK, "", ""},
+		{http.MethodGet, "/rtc/room/publisher/uid/4294967296/", "", http.StatusBadRequest, CodeInvalidUid, "rtcuid"},
+		{http.MethodGet, "/rtc/room/publisher/userAccount/" + strings.Repeat("u", 256) + "/", "", http.StatusBadRequest, CodeInvalidUid, "rtcuid"},
+		{http.MethodGet, "/rtc/room/host/uid/1/", "", http.StatusBadRequest, CodeInvalidRole, "role"},
+		{http.MethodGet, "/rtc/room*1/publisher/uid/1/", "", http.StatusBadRequest, CodeInvalidChannel, "channelName"},
+		{http.MethodGet, "/rtc/room/publisher/uid/1/?expiry=4294967296", "", http.StatusBadRequest, CodeInvalidExpiry, "expiry"},
+		{http.MethodGet, "/rtc/room/publisher/uid/1/?expiry=90000", "", http.StatusUnprocessableEntity, CodeExpiryTooLong, "expiry"},
+		{http.MethodGet, "/rte/room/publisher/uid/1/" + strings.Repeat("u", 256) + "/", "", http.StatusBadRequest, CodeInvalidUid, "rtmuid"},
+		{http.MethodGet, "/rtm/1/?expiry=10", "", http.StatusUnprocessableEntity, CodeExpiryTooShort, "expiry"},
+		{http.MethodGet, "/chat/account/" + strings.Repeat("u", 256) + "/", "", http.StatusBadRequest, CodeInvalidUid, "chatid"},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest(test.method, test.url, bytes.NewBufferString(test.body))
+		resp := httptest.NewRecorder()
+		testService.Server.Handler.ServeHTTP(resp, req)
+
+		assert.Equal(t, test.status, resp.Code, test.url, test.body, resp.Body.String())
+		if test.status == http.StatusOK {
+			continue
+		}
+		var response ErrorResponse
+		if assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response), test.url) {
+			assert.Equal(t, test.code, response.Code, test.url, test.body)
+			assert.Equal(t, test.field, response.Field, test.url, test.body)
+		}
+	}
+}
+
+func TestConfiguredExpireRange(t *testing.T) {
+	t.Setenv("EXPIRE_MIN", "300")
+	t.Setenv("EXPIRE_MAX", "600")
+	service := NewService()
+
+	_, err := service.GenRtmToken(TokenRequest{TokenType: "rtm", Uid: "1", ExpirationSeconds: 900})
+	assert.Equal(t, CodeExpiryTooLong, NewErrorResponse(err).Code)
+	_, err = service.GenRtmToken(TokenRequest{TokenType: "rtm", Uid: "1", ExpirationSeconds: 120})
+	assert.Equal(t, CodeExpiryTooShort, NewErrorResponse(err).Code)
+
+	// Tokens requested without an expiration get the longest one allowed below one hour
+	token, err := service.GenRtcToken(TokenRequest{TokenType: "rtc", Channel: "room", Uid: "1"})
+	if assert.NoError(t, err) {
+		info, err := service.InspectToken(token)
+		if assert.NoError(t, err) {
+			assert.Equal(t, uint32(600), info.Expire)
+		}
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/rtm/1/", nil)
+	resp := httptest.NewRecorder()
+	service.Server.Handler.ServeHTTP(resp, req)
+	if assert.Equal(t, http.StatusOK, resp.Code) {
+		var body map[string]string
+		json.Unmarshal(resp.Body.Bytes(), &body)
+		info, err := service.InspectToken(body["rtmToken"])
+		if assert.NoError(t, err) {
+			assert.Equal(t, uint32(600), info.Expire)
+		}
+	}
+}